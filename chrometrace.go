@@ -0,0 +1,179 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/apstndb/execspansql/rowwriter"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// chromeTraceExporter collects ended spans and writes them to a file in the
+// Chrome Trace Event Format on shutdown, for Perfetto and chrome://tracing.
+type chromeTraceExporter struct {
+	name string
+
+	mu    sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (e *chromeTraceExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *chromeTraceExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	b, err := json.MarshalIndent(chromeTrace(e.spans), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(e.name, append(b, '\n'), 0o644)
+}
+
+// chromeTraceFile is the JSON object form of the Trace Event Format.
+type chromeTraceFile struct {
+	TraceEvents     []chromeTraceEvent `json:"traceEvents"`
+	DisplayTimeUnit string             `json:"displayTimeUnit"`
+}
+
+type chromeTraceEvent struct {
+	Name string `json:"name"`
+	Cat  string `json:"cat,omitempty"`
+	Ph   string `json:"ph"`
+	// Ts and Dur are in microseconds.
+	Ts   float64        `json:"ts"`
+	Dur  float64        `json:"dur,omitempty"`
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	S    string         `json:"s,omitempty"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// chromeTrace converts spans into complete ("X") events with the span
+// attributes as args, and span events into instant ("i") events. Timestamps
+// are relative to the earliest span. Complete events on a thread must nest,
+// so spans are laid out on the first thread where they nest, which puts
+// children under their parents and concurrent spans side by side.
+func chromeTrace(spans []sdktrace.ReadOnlySpan) chromeTraceFile {
+	spans = slices.Clone(spans)
+	slices.SortStableFunc(spans, func(a, b sdktrace.ReadOnlySpan) int {
+		return cmp.Or(a.StartTime().Compare(b.StartTime()), b.EndTime().Compare(a.EndTime()))
+	})
+	file := chromeTraceFile{TraceEvents: []chromeTraceEvent{}, DisplayTimeUnit: "ms"}
+	if len(spans) == 0 {
+		return file
+	}
+	origin := spans[0].StartTime()
+	micros := func(t time.Time) float64 { return float64(t.Sub(origin).Nanoseconds()) / 1e3 }
+	file.TraceEvents = append(file.TraceEvents, chromeTraceEvent{
+		Name: "process_name", Ph: "M", Pid: 1,
+		Args: map[string]any{"name": traceServiceName},
+	})
+
+	// lanes[i] holds the end times of the open spans on thread i+1.
+	var lanes [][]time.Time
+	for _, span := range spans {
+		tid := 0
+		for i, open := range lanes {
+			for len(open) > 0 && !open[len(open)-1].After(span.StartTime()) {
+				open = open[:len(open)-1]
+			}
+			lanes[i] = open
+			if len(open) == 0 || !open[len(open)-1].Before(span.EndTime()) {
+				tid = i + 1
+				break
+			}
+		}
+		if tid == 0 {
+			lanes = append(lanes, nil)
+			tid = len(lanes)
+		}
+		lanes[tid-1] = append(lanes[tid-1], span.EndTime())
+
+		args := map[string]any{
+			"trace_id": span.SpanContext().TraceID().String(),
+			"span_id":  span.SpanContext().SpanID().String(),
+		}
+		if parent := span.Parent(); parent.IsValid() {
+			args["parent_span_id"] = parent.SpanID().String()
+		}
+		for _, kv := range span.Attributes() {
+			args[string(kv.Key)] = kv.Value.AsInterface()
+		}
+		if span.Status().Code == codes.Error {
+			args["error"] = span.Status().Description
+		}
+		file.TraceEvents = append(file.TraceEvents, chromeTraceEvent{
+			Name: span.Name(),
+			Cat:  span.InstrumentationScope().Name,
+			Ph:   "X",
+			Ts:   micros(span.StartTime()),
+			Dur:  float64(span.EndTime().Sub(span.StartTime()).Nanoseconds()) / 1e3,
+			Pid:  1,
+			Tid:  tid,
+			Args: args,
+		})
+		for _, ev := range span.Events() {
+			var evArgs map[string]any
+			for _, kv := range ev.Attributes {
+				if evArgs == nil {
+					evArgs = make(map[string]any)
+				}
+				evArgs[string(kv.Key)] = kv.Value.AsInterface()
+			}
+			file.TraceEvents = append(file.TraceEvents, chromeTraceEvent{
+				Name: ev.Name, Ph: "i", S: "t", Ts: micros(ev.Time), Pid: 1, Tid: tid, Args: evArgs,
+			})
+		}
+	}
+	return file
+}
+
+// firstRowEvent is the span event that [firstRowWriter] adds.
+const firstRowEvent = "first row"
+
+// firstRowWriter adds a "first row" event to span when the first row of the
+// result is written, so that traces show the latency to the first row.
+type firstRowWriter struct {
+	rowwriter.Writer
+	span trace.Span
+	seen bool
+}
+
+func (w *firstRowWriter) WriteRow(values []*structpb.Value) error {
+	if !w.seen {
+		w.seen = true
+		w.span.AddEvent(firstRowEvent)
+	}
+	return w.Writer.WriteRow(values)
+}
+
+// firstOutputWriter adds the [firstRowEvent] to span on the first write to
+// the output of json, yaml and experimental_csv, whose rows are not written
+// by a [rowwriter.Writer].
+type firstOutputWriter struct {
+	io.Writer
+	span trace.Span
+	seen bool
+}
+
+func (w *firstOutputWriter) Write(p []byte) (int, error) {
+	if !w.seen {
+		w.seen = true
+		w.span.AddEvent(firstRowEvent)
+	}
+	return w.Writer.Write(p)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestChromeTrace(t *testing.T) {
+	t.Parallel()
+
+	origin := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	at := func(ms int) time.Time { return origin.Add(time.Duration(ms) * time.Millisecond) }
+	traceID := trace.TraceID{1}
+	sc := func(id byte) trace.SpanContext {
+		return trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{id}})
+	}
+	spans := tracetest.SpanStubs{
+		// Children overlap each other, so the second one gets its own thread.
+		{Name: "child b", SpanContext: sc(3), Parent: sc(1), StartTime: at(2), EndTime: at(8)},
+		{Name: "root", SpanContext: sc(1), StartTime: at(0), EndTime: at(10),
+			Attributes: []attribute.KeyValue{attribute.String("db.statement", "SELECT 1")},
+			Events:     []sdktrace.Event{{Name: firstRowEvent, Time: at(9)}},
+		},
+		{Name: "child a", SpanContext: sc(2), Parent: sc(1), StartTime: at(1), EndTime: at(5)},
+		{Name: "later", SpanContext: sc(4), StartTime: at(11), EndTime: at(12)},
+	}.Snapshots()
+
+	type event struct {
+		Name, Ph string
+		Ts, Dur  float64
+		Tid      int
+	}
+	var got []event
+	for _, ev := range chromeTrace(spans).TraceEvents {
+		got = append(got, event{ev.Name, ev.Ph, ev.Ts, ev.Dur, ev.Tid})
+	}
+	want := []event{
+		{"process_name", "M", 0, 0, 0},
+		{"root", "X", 0, 10000, 1},
+		{firstRowEvent, "i", 9000, 0, 1},
+		{"child a", "X", 1000, 4000, 1},
+		{"child b", "X", 2000, 6000, 2},
+		{"later", "X", 11000, 1000, 1},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("chromeTrace() mismatch (-want +got):\n%s", diff)
+	}
+
+	root := chromeTrace(spans).TraceEvents[1]
+	if got, want := root.Args["db.statement"], "SELECT 1"; got != want {
+		t.Errorf("root args[db.statement] = %v, want %v", got, want)
+	}
+	if _, ok := root.Args["parent_span_id"]; ok {
+		t.Errorf("root has a parent_span_id: %v", root.Args)
+	}
+}
+
+func TestEnableTracingFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "trace.json")
+	oldTP := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(oldTP) })
+
+	ctx, tp, traceCancel, err := enableTracing(context.Background(), opts{TraceFile: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer traceCancel()
+
+	_, span := otel.Tracer("test").Start(ctx, "test-query")
+	span.End()
+	if err := shutdownTracing(context.Background(), tp); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file chromeTraceFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		t.Fatalf("trace file is not JSON: %v", err)
+	}
+	if len(file.TraceEvents) != 2 || file.TraceEvents[1].Name != "test-query" {
+		t.Fatalf("trace events = %+v, want process_name and test-query", file.TraceEvents)
+	}
+}
+
+func TestFirstOutputWriter(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	_, span := tp.Tracer("test").Start(context.Background(), "execspansql")
+
+	var buf bytes.Buffer
+	w := &firstOutputWriter{Writer: &buf, span: span}
+	for _, s := range []string{"[\n", "]\n"} {
+		if _, err := io.WriteString(w, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	span.End()
+
+	if buf.String() != "[\n]\n" {
+		t.Errorf("output = %q", buf.String())
+	}
+	ended := recorder.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("%d spans ended, want 1", len(ended))
+	}
+	var names []string
+	for _, ev := range ended[0].Events() {
+		names = append(names, ev.Name)
+	}
+	if diff := cmp.Diff([]string{firstRowEvent}, names); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,205 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/jqresult"
+	"github.com/apstndb/execspansql/valuefmt"
+)
+
+// Language selects the output of [Generate].
+type Language string
+
+const (
+	Go         Language = "go"
+	TypeScript Language = "typescript"
+	Proto      Language = "proto"
+)
+
+func ParseLanguage(s string) (Language, error) {
+	switch Language(s) {
+	case Go, TypeScript, Proto:
+		return Language(s), nil
+	default:
+		return "", fmt.Errorf("codegen language must be go, typescript or proto")
+	}
+}
+
+// Options configures [Generate].
+type Options struct {
+	Language Language
+	// Package is the Go or protobuf package name.
+	Package string
+	// Name is the row type name. The parameter type is Name+"Params", and
+	// STRUCT columns get types named after the enclosing type and the column.
+	Name string
+	// Render is the JSON rendering that TypeScript interfaces describe.
+	Render jqresult.RenderOptions
+}
+
+// Generate returns source declaring the row type of rowType and, when params
+// is not empty, a parameter type with one field per parameter. Every column
+// and STRUCT field must have a unique name, since the Spanner client and JSON
+// objects address them by name.
+func Generate(opts Options, rowType *sppb.StructType, params []*sppb.StructType_Field) ([]byte, error) {
+	c := collector{used: make(map[string]bool)}
+	if _, err := c.collect(opts.Name, "a row of the query result", rowType.GetFields()); err != nil {
+		return nil, err
+	}
+	if len(params) > 0 {
+		if _, err := c.collect(opts.Name+"Params", "the parameters of the query", params); err != nil {
+			return nil, err
+		}
+	}
+	switch opts.Language {
+	case Go:
+		return generateGo(opts, c.decls)
+	case TypeScript:
+		return generateTypeScript(opts, c.decls), nil
+	case Proto:
+		return generateProto(opts, c.decls), nil
+	default:
+		return nil, fmt.Errorf("unknown codegen language %q", opts.Language)
+	}
+}
+
+// decl is a named type with one field per STRUCT field.
+type decl struct {
+	name   string
+	doc    string // what the type is, completing "<name> is"
+	fields []field
+}
+
+type field struct {
+	name string
+	typ  *sppb.Type
+	// structName is the declared type of a STRUCT or ARRAY<STRUCT> field.
+	structName string
+}
+
+// collector flattens nested STRUCT types into declarations in depth-first
+// order, the outermost first.
+type collector struct {
+	decls []decl
+	used  map[string]bool
+}
+
+// collect declares fields as a type named name, made unique, and returns the name.
+func (c *collector) collect(name, doc string, fields []*sppb.StructType_Field) (string, error) {
+	name = c.uniqueName(name)
+	d := decl{name: name, doc: doc}
+	seen := make(map[string]bool, len(fields))
+	for i, f := range fields {
+		if f.GetName() == "" {
+			return "", fmt.Errorf("codegen: field %d of %s has no name; alias it with AS", i+1, name)
+		}
+		if seen[f.GetName()] {
+			return "", fmt.Errorf("codegen: %s has more than one field named %s", name, f.GetName())
+		}
+		seen[f.GetName()] = true
+		d.fields = append(d.fields, field{name: f.GetName(), typ: f.GetType()})
+	}
+	i := len(c.decls)
+	c.decls = append(c.decls, d)
+	for j, f := range d.fields {
+		st := f.typ
+		if st.GetCode() == sppb.TypeCode_ARRAY {
+			st = st.GetArrayElementType()
+		}
+		if st.GetCode() != sppb.TypeCode_STRUCT {
+			continue
+		}
+		structName, err := c.collect(name+exportedName(f.name), "a "+valuefmt.TypeString(st)+" value", st.GetStructType().GetFields())
+		if err != nil {
+			return "", err
+		}
+		c.decls[i].fields[j].structName = structName
+	}
+	return name, nil
+}
+
+func (c *collector) uniqueName(name string) string {
+	unique := name
+	for i := 2; c.used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	c.used[unique] = true
+	return unique
+}
+
+// words splits an identifier such as first_name, FirstName or HTTPStatus2 into words.
+func words(s string) []string {
+	var out []string
+	var cur []rune
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(cur) > 0 {
+				out = append(out, string(cur))
+				cur = nil
+			}
+			continue
+		}
+		if len(cur) > 0 && unicode.IsUpper(r) {
+			prev := cur[len(cur)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				out = append(out, string(cur))
+				cur = nil
+			}
+		}
+		cur = append(cur, r)
+	}
+	if len(cur) > 0 {
+		out = append(out, string(cur))
+	}
+	return out
+}
+
+// exportedName converts a column name to an exported identifier: FirstName for first_name.
+func exportedName(s string) string {
+	var sb strings.Builder
+	for _, w := range words(s) {
+		r := []rune(w)
+		sb.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+	return identifier(sb.String(), "X")
+}
+
+// snakeName converts a column name to snake_case: first_name for FirstName.
+func snakeName(s string) string {
+	ws := words(s)
+	for i, w := range ws {
+		ws[i] = strings.ToLower(w)
+	}
+	return identifier(strings.Join(ws, "_"), "x_")
+}
+
+// identifier prefixes s so that it does not start with a digit and is not empty.
+func identifier(s, prefix string) string {
+	if s == "" || unicode.IsDigit([]rune(s)[0]) {
+		return prefix + s
+	}
+	return s
+}
+
+// uniqueNames returns the names produced by convert for fields, with a
+// numeric suffix on later duplicates.
+func uniqueNames(fields []field, convert func(string) string) []string {
+	used := make(map[string]bool, len(fields))
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		name := convert(f.name)
+		unique := name
+		for j := 2; used[unique]; j++ {
+			unique = name + strconv.Itoa(j)
+		}
+		used[unique] = true
+		names[i] = unique
+	}
+	return names
+}
@@ -0,0 +1,114 @@
+package codegen
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/jqresult"
+	"github.com/google/go-cmp/cmp"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "rewrite testdata/*.golden from current output")
+
+func typ(code sppb.TypeCode) *sppb.Type { return &sppb.Type{Code: code} }
+
+func rowTypeFixture() *sppb.StructType {
+	return &sppb.StructType{Fields: []*sppb.StructType_Field{
+		{Name: "SingerId", Type: typ(sppb.TypeCode_INT64)},
+		{Name: "first_name", Type: typ(sppb.TypeCode_STRING)},
+		{Name: "BirthDate", Type: typ(sppb.TypeCode_DATE)},
+		{Name: "UpdatedAt", Type: typ(sppb.TypeCode_TIMESTAMP)},
+		{Name: "Score", Type: typ(sppb.TypeCode_FLOAT64)},
+		{Name: "Budget", Type: typ(sppb.TypeCode_NUMERIC)},
+		{Name: "Photo", Type: typ(sppb.TypeCode_BYTES)},
+		{Name: "Info", Type: typ(sppb.TypeCode_JSON)},
+		{Name: "Tags", Type: &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: typ(sppb.TypeCode_STRING)}},
+		{Name: "albums", Type: &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{
+			Code: sppb.TypeCode_STRUCT,
+			StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+				{Name: "AlbumId", Type: typ(sppb.TypeCode_INT64)},
+				{Name: "title", Type: typ(sppb.TypeCode_STRING)},
+			}},
+		}}},
+		{Name: "first name", Type: typ(sppb.TypeCode_BOOL)},
+	}}
+}
+
+func paramsFixture() []*sppb.StructType_Field {
+	return []*sppb.StructType_Field{
+		{Name: "id", Type: typ(sppb.TypeCode_INT64)},
+		{Name: "since", Type: typ(sppb.TypeCode_TIMESTAMP)},
+	}
+}
+
+func TestGenerateGolden(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]Options{
+		"go":         {Language: Go, Package: "model", Name: "Singer"},
+		"typescript": {Language: TypeScript, Name: "Singer"},
+		"typed.ts":   {Language: TypeScript, Name: "Singer", Render: jqresult.RenderOptions{TypedNumbers: true, ExpandJSON: true}},
+		"proto":      {Language: Proto, Package: "example.v1", Name: "Singer"},
+	}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Generate(opts, rowTypeFixture(), paramsFixture())
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			path := filepath.Join("testdata", name+".golden")
+			if *updateGolden {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile(%q) error = %v (run: go test -update-golden ./codegen)", path, err)
+			}
+			if diff := cmp.Diff(string(want), string(got)); diff != "" {
+				t.Errorf("output mismatch for %s (-want +got):\n%s", path, diff)
+			}
+		})
+	}
+}
+
+func TestGenerateRequiresNames(t *testing.T) {
+	t.Parallel()
+
+	for _, fields := range [][]*sppb.StructType_Field{
+		{{Type: typ(sppb.TypeCode_INT64)}},
+		{{Name: "a", Type: typ(sppb.TypeCode_INT64)}, {Name: "a", Type: typ(sppb.TypeCode_STRING)}},
+	} {
+		if _, err := Generate(Options{Language: Go, Package: "p", Name: "Row"}, &sppb.StructType{Fields: fields}, nil); err == nil {
+			t.Errorf("Generate(%v) expected an error", fields)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct{ in, exported, snake string }{
+		{"SingerId", "SingerId", "singer_id"},
+		{"first_name", "FirstName", "first_name"},
+		{"HTTPStatus2", "HTTPStatus2", "http_status2"},
+		{"first name", "FirstName", "first_name"},
+		{"1st", "X1st", "x_1st"},
+		{"", "X", "x_"},
+	}
+	for _, tc := range tests {
+		if got := exportedName(tc.in); got != tc.exported {
+			t.Errorf("exportedName(%q) = %q, want %q", tc.in, got, tc.exported)
+		}
+		if got := snakeName(tc.in); got != tc.snake {
+			t.Errorf("snakeName(%q) = %q, want %q", tc.in, got, tc.snake)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// Package codegen generates typed declarations of query rows and parameters.
+//
+// The declarations are derived from the row type and parameter types that a
+// query returns in PLAN mode, so they can be regenerated whenever the SQL
+// changes instead of being maintained by hand. [Generate] supports Go structs
+// for the Cloud Spanner client, TypeScript interfaces for the JSON output of
+// execspansql, and protobuf messages.
+package codegen
@@ -0,0 +1,87 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+// generateGo declares a struct per decl for Row.ToStruct and
+// spanner.Statement. The row type does not record NOT NULL, so scalar fields
+// use the spanner.Null* types; BYTES and STRUCT are nil when NULL.
+func generateGo(opts Options, decls []decl) ([]byte, error) {
+	var body bytes.Buffer
+	usesSpanner := false
+	for i, d := range decls {
+		if i > 0 {
+			body.WriteByte('\n')
+		}
+		fmt.Fprintf(&body, "// %s is %s.\n", d.name, d.doc)
+		fmt.Fprintf(&body, "type %s struct {\n", d.name)
+		for j, name := range uniqueNames(d.fields, exportedName) {
+			f := d.fields[j]
+			typ := goType(f.typ, f.structName)
+			if strings.Contains(typ, "spanner.") {
+				usesSpanner = true
+			}
+			fmt.Fprintf(&body, "\t%s %s `spanner:%s`\n", name, typ, strconv.Quote(f.name))
+		}
+		body.WriteString("}\n")
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by execspansql. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", opts.Package)
+	if usesSpanner {
+		src.WriteString("import \"cloud.google.com/go/spanner\"\n\n")
+	}
+	src.Write(body.Bytes())
+	return format.Source(src.Bytes())
+}
+
+// goType returns the Go type that the Spanner client decodes a nullable typ into.
+func goType(typ *sppb.Type, structName string) string {
+	switch typ.GetCode() {
+	case sppb.TypeCode_BOOL:
+		return "spanner.NullBool"
+	case sppb.TypeCode_INT64, sppb.TypeCode_ENUM:
+		return "spanner.NullInt64"
+	case sppb.TypeCode_FLOAT64:
+		return "spanner.NullFloat64"
+	case sppb.TypeCode_FLOAT32:
+		return "spanner.NullFloat32"
+	case sppb.TypeCode_NUMERIC:
+		if typ.GetTypeAnnotation() == sppb.TypeAnnotationCode_PG_NUMERIC {
+			return "spanner.PGNumeric"
+		}
+		return "spanner.NullNumeric"
+	case sppb.TypeCode_STRING:
+		return "spanner.NullString"
+	case sppb.TypeCode_BYTES, sppb.TypeCode_PROTO:
+		return "[]byte"
+	case sppb.TypeCode_DATE:
+		return "spanner.NullDate"
+	case sppb.TypeCode_TIMESTAMP:
+		return "spanner.NullTime"
+	case sppb.TypeCode_JSON:
+		if typ.GetTypeAnnotation() == sppb.TypeAnnotationCode_PG_JSONB {
+			return "spanner.PGJsonB"
+		}
+		return "spanner.NullJSON"
+	case sppb.TypeCode_UUID:
+		return "spanner.NullUUID"
+	case sppb.TypeCode_INTERVAL:
+		return "spanner.NullInterval"
+	case sppb.TypeCode_ARRAY:
+		return "[]" + goType(typ.GetArrayElementType(), structName)
+	case sppb.TypeCode_STRUCT:
+		return "*" + structName
+	default:
+		// Unknown types are decoded as they are sent.
+		return "spanner.GenericColumnValue"
+	}
+}
@@ -0,0 +1,76 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+const timestampProto = "google/protobuf/timestamp.proto"
+
+// generateProto declares a proto3 message per decl. Scalar fields are
+// optional so that NULL is distinguishable; repeated fields cannot hold NULL
+// elements. Field names are snake_case and numbered in column order.
+func generateProto(opts Options, decls []decl) []byte {
+	var body bytes.Buffer
+	usesTimestamp := false
+	for _, d := range decls {
+		fmt.Fprintf(&body, "\n// %s is %s.\n", d.name, d.doc)
+		fmt.Fprintf(&body, "message %s {\n", d.name)
+		for i, name := range uniqueNames(d.fields, snakeName) {
+			f := d.fields[i]
+			label, typ := protoType(f.typ, f.structName)
+			if typ == "google.protobuf.Timestamp" {
+				usesTimestamp = true
+			}
+			if label != "" {
+				label += " "
+			}
+			fmt.Fprintf(&body, "  %s%s %s = %d;\n", label, typ, name, i+1)
+		}
+		body.WriteString("}\n")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by execspansql. DO NOT EDIT.\n\n")
+	buf.WriteString("syntax = \"proto3\";\n")
+	if opts.Package != "" {
+		fmt.Fprintf(&buf, "\npackage %s;\n", opts.Package)
+	}
+	if usesTimestamp {
+		fmt.Fprintf(&buf, "\nimport %q;\n", timestampProto)
+	}
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// protoType returns the field label and type of typ. NUMERIC, DATE, JSON,
+// UUID and INTERVAL are strings in their Spanner text form.
+func protoType(typ *sppb.Type, structName string) (label, t string) {
+	switch typ.GetCode() {
+	case sppb.TypeCode_ARRAY:
+		_, elem := protoType(typ.GetArrayElementType(), structName)
+		return "repeated", elem
+	case sppb.TypeCode_STRUCT:
+		return "", structName
+	case sppb.TypeCode_TIMESTAMP:
+		return "", "google.protobuf.Timestamp"
+	}
+
+	switch typ.GetCode() {
+	case sppb.TypeCode_BOOL:
+		t = "bool"
+	case sppb.TypeCode_INT64, sppb.TypeCode_ENUM:
+		t = "int64"
+	case sppb.TypeCode_FLOAT64:
+		t = "double"
+	case sppb.TypeCode_FLOAT32:
+		t = "float"
+	case sppb.TypeCode_BYTES, sppb.TypeCode_PROTO:
+		t = "bytes"
+	default:
+		t = "string"
+	}
+	return "optional", t
+}
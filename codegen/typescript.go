@@ -0,0 +1,73 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/jqresult"
+)
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// generateTypeScript declares an interface per decl describing the rows of
+// --jq-row-shape=object and jsonl-objects output rendered with opts.Render.
+// Property names are the column names, so the interfaces match the JSON as is.
+func generateTypeScript(opts Options, decls []decl) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by execspansql. DO NOT EDIT.\n")
+	for _, d := range decls {
+		fmt.Fprintf(&buf, "\n/** %s is %s. */\n", d.name, d.doc)
+		fmt.Fprintf(&buf, "export interface %s {\n", d.name)
+		for _, f := range d.fields {
+			name := f.name
+			if !tsIdentifier.MatchString(name) {
+				name = strconv.Quote(name)
+			}
+			fmt.Fprintf(&buf, "  %s: %s;\n", name, tsType(opts.Render, f.typ, f.structName))
+		}
+		buf.WriteString("}\n")
+	}
+	return buf.Bytes()
+}
+
+// tsType returns the nullable TypeScript type of a rendered value of typ.
+func tsType(render jqresult.RenderOptions, typ *sppb.Type, structName string) string {
+	var t string
+	switch typ.GetCode() {
+	case sppb.TypeCode_BOOL:
+		t = "boolean"
+	case sppb.TypeCode_INT64, sppb.TypeCode_NUMERIC:
+		t = "string"
+		if render.TypedNumbers {
+			t = "number"
+			if typ.GetTypeAnnotation() == sppb.TypeAnnotationCode_PG_NUMERIC {
+				t = `number | "NaN"`
+			}
+		}
+	case sppb.TypeCode_FLOAT32, sppb.TypeCode_FLOAT64:
+		t = `number | "NaN" | "Infinity" | "-Infinity"`
+	case sppb.TypeCode_JSON:
+		t = "string"
+		if render.ExpandJSON {
+			// Includes null, which a JSON null value renders as.
+			return "unknown"
+		}
+	case sppb.TypeCode_INTERVAL:
+		t = "string"
+		if render.Interval == jqresult.IntervalObject {
+			t = "{ months: number; days: number; nanos: number }"
+		}
+	case sppb.TypeCode_ARRAY:
+		elem := tsType(render, typ.GetArrayElementType(), structName)
+		t = "(" + elem + ")[]"
+	case sppb.TypeCode_STRUCT:
+		t = structName
+	default:
+		// STRING, BYTES, DATE, TIMESTAMP, UUID, ENUM, PROTO and unknown types are strings.
+		t = "string"
+	}
+	return t + " | null"
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/apstndb/execspansql/rowwriter"
 )
 
 var updateGolden = flag.Bool("update-golden", false, "rewrite testdata/experimental_csv/*.golden, testdata/yaml_output/*.golden, and profile YAML goldens")
@@ -42,3 +44,24 @@ func TestExperimentalCsvGolden(t *testing.T) {
 		})
 	}
 }
+
+// TestCsvMatchesExperimentalCsvGolden pins the default --format=csv dialect to
+// the experimental_csv output it replaces.
+func TestCsvMatchesExperimentalCsvGolden(t *testing.T) {
+	for name, rs := range csvGoldenFixtures() {
+		t.Run(name, func(t *testing.T) {
+			goldenPath := filepath.Join("testdata", "experimental_csv", name+".golden")
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("ReadFile(%q) error = %v", goldenPath, err)
+			}
+			var buf bytes.Buffer
+			if err := rowwriter.WriteResultSet(rowwriter.NewCSV(&buf, rowwriter.DefaultCSVOptions()), rs); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Fatalf("csv output mismatch for %s\n\ngot:\n%s\n\nwant:\n%s", name, got, want)
+			}
+		})
+	}
+}
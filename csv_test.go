@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
 	"flag"
 	"io"
 	"os"
@@ -9,6 +10,7 @@ import (
 
 	"cloud.google.com/go/spanner"
 	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/valuefmt"
 	svwriter "github.com/apstndb/spanvalue/writer"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/types/known/structpb"
@@ -79,6 +81,82 @@ func TestExperimentalCsvRowIterPathMatchesResultSet(t *testing.T) {
 	}
 }
 
+// TestSimpleFormatSpanvalueContract pins [valuefmt.Simple], which renders the
+// table format and text composites of the csv format, to the value rendering
+// of spanvalue's DelimitedWriter for every type code. The markdown and html
+// formats use the DelimitedWriter itself.
+func TestSimpleFormatSpanvalueContract(t *testing.T) {
+	t.Parallel()
+
+	typ := func(code sppb.TypeCode) *sppb.Type { return &sppb.Type{Code: code} }
+	structType := &sppb.Type{Code: sppb.TypeCode_STRUCT, StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+		{Name: "i", Type: typ(sppb.TypeCode_INT64)},
+		{Type: typ(sppb.TypeCode_STRING)},
+	}}}
+	columns := []struct {
+		name  string
+		typ   *sppb.Type
+		value *structpb.Value
+	}{
+		{"bool", typ(sppb.TypeCode_BOOL), structpb.NewBoolValue(false)},
+		{"int64", typ(sppb.TypeCode_INT64), structpb.NewStringValue("-42")},
+		{"float32", typ(sppb.TypeCode_FLOAT32), structpb.NewNumberValue(0.1)},
+		{"float64", typ(sppb.TypeCode_FLOAT64), structpb.NewNumberValue(1e21)},
+		{"float64_nan", typ(sppb.TypeCode_FLOAT64), structpb.NewStringValue("NaN")},
+		{"float64_inf", typ(sppb.TypeCode_FLOAT64), structpb.NewStringValue("-Infinity")},
+		{"numeric", typ(sppb.TypeCode_NUMERIC), structpb.NewStringValue("-0.000000001")},
+		{"string", typ(sppb.TypeCode_STRING), structpb.NewStringValue("a\nb")},
+		{"bytes", typ(sppb.TypeCode_BYTES), structpb.NewStringValue("aGk=")},
+		{"json", typ(sppb.TypeCode_JSON), structpb.NewStringValue(`{"a":[1,null]}`)},
+		{"date", typ(sppb.TypeCode_DATE), structpb.NewStringValue("2024-06-01")},
+		{"timestamp", typ(sppb.TypeCode_TIMESTAMP), structpb.NewStringValue("2024-06-01T12:34:56.123456789Z")},
+		{"uuid", typ(sppb.TypeCode_UUID), structpb.NewStringValue("550e8400-e29b-41d4-a716-446655440000")},
+		{"interval", typ(sppb.TypeCode_INTERVAL), structpb.NewStringValue("P1Y2M3DT4H5M6.789S")},
+		{"enum", &sppb.Type{Code: sppb.TypeCode_ENUM, ProtoTypeFqn: "examples.Genre"}, structpb.NewStringValue("2")},
+		{"proto", &sppb.Type{Code: sppb.TypeCode_PROTO, ProtoTypeFqn: "examples.Info"}, structpb.NewStringValue("CgNhYmM=")},
+		{"array", &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: typ(sppb.TypeCode_INT64)}, structpb.NewListValue(&structpb.ListValue{
+			Values: []*structpb.Value{structpb.NewStringValue("1"), structpb.NewNullValue()},
+		})},
+		{"struct", structType, structpb.NewListValue(&structpb.ListValue{
+			Values: []*structpb.Value{structpb.NewStringValue("7"), structpb.NewNullValue()},
+		})},
+		{"array_struct", &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: structType}, structpb.NewListValue(&structpb.ListValue{
+			Values: []*structpb.Value{structpb.NewListValue(&structpb.ListValue{
+				Values: []*structpb.Value{structpb.NewStringValue("1"), structpb.NewStringValue("x")},
+			})},
+		})},
+	}
+	names := make([]string, len(columns))
+	types := make([]*sppb.Type, len(columns))
+	values, nulls := make([]*structpb.Value, len(columns)), make([]*structpb.Value, len(columns))
+	for i, c := range columns {
+		names[i], types[i], values[i], nulls[i] = c.name, c.typ, c.value, structpb.NewNullValue()
+	}
+	rs := resultSet(names, types, [][]*structpb.Value{values, nulls})
+
+	var buf bytes.Buffer
+	if err := experimentalCsvViaSpanvalueWriter(&buf, rs); err != nil {
+		t.Fatalf("experimentalCsvViaSpanvalueWriter() error = %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("csv.ReadAll() error = %v", err)
+	}
+	want := records[1:]
+
+	var got [][]string
+	for _, row := range rs.GetRows() {
+		formatted, err := valuefmt.Simple.FormatRow(rs.GetMetadata().GetRowType().GetFields(), row.GetValues())
+		if err != nil {
+			t.Fatalf("FormatRow() error = %v", err)
+		}
+		got = append(got, formatted)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("valuefmt.Simple mismatch (-spanvalue writer +valuefmt.Simple):\n%s", diff)
+	}
+}
+
 // TestExperimentalCsvBehavior covers execspansql-specific CSV behavior.
 func TestExperimentalCsvBehavior(t *testing.T) {
 	t.Parallel()
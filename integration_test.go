@@ -22,6 +22,7 @@ import (
 	"github.com/apstndb/execspansql/jqresult"
 	"github.com/apstndb/execspansql/params"
 	"github.com/apstndb/execspansql/resultset"
+	"github.com/apstndb/execspansql/rowwriter"
 )
 
 //go:embed testdata/ddl.sql
@@ -645,4 +646,22 @@ func TestWithCloudSpannerEmulator(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("table WriteRowIterator", func(t *testing.T) {
+		var buf bytes.Buffer
+		iter := client.Single().Query(ctx, spanner.Statement{SQL: "SELECT SingerId, FirstName FROM Singers ORDER BY SingerId LIMIT 2"})
+		if err := rowwriter.WriteRowIterator(rowwriter.NewTable(&buf, rowwriter.TableStyleASCII), iter, false); err != nil {
+			t.Fatalf("WriteRowIterator(): %v", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(lines) != 8 {
+			t.Fatalf("got %d lines, want 8:\n%s", len(lines), buf.String())
+		}
+		if !strings.Contains(lines[1], "SingerId") || !strings.Contains(lines[2], "INT64") {
+			t.Fatalf("header does not show row type:\n%s", buf.String())
+		}
+		if !strings.HasPrefix(lines[7], "2 rows in set") {
+			t.Fatalf("footer: got %q", lines[7])
+		}
+	})
 }
@@ -287,3 +287,21 @@ func TestPrintTopLevelIterJSONL(t *testing.T) {
 		t.Fatalf("got %d encodes want 3", len(e.vals))
 	}
 }
+
+func TestValidateFormat(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []string{"json", "yaml"} {
+		if err := InputLazy.ValidateFormat(format); err != nil {
+			t.Fatalf("InputLazy.ValidateFormat(%q) error = %v", format, err)
+		}
+	}
+	for _, format := range []string{"csv", "experimental_csv", "table", "markdown", "html", "jsonl-objects", "sql-insert", "mutations-json", "parquet", "arrow", "avro", "sqlite", "xlsx", "gcloud", "template", "json-schema", "codegen", "plan", "plan-dot", "plan-mermaid", "plan-html", "folded", "pprof", "plan-rows", "plan-diff", "plan-check"} {
+		if err := InputLazy.ValidateFormat(format); err == nil {
+			t.Fatalf("InputLazy.ValidateFormat(%q) expected error", format)
+		}
+		if err := InputEager.ValidateFormat(format); err != nil {
+			t.Fatalf("InputEager.ValidateFormat(%q) error = %v", format, err)
+		}
+	}
+}
@@ -31,8 +31,12 @@ type Lazy struct {
 
 // NewLazy builds a lazy jq input. rowIter must not have been read yet; Lazy takes ownership and Stop()s it.
 func NewLazy(rowIter *spanner.RowIterator, redact bool) *Lazy {
+	return newLazy(rowIter, redact, RowToJSON)
+}
+
+func newLazy(rowIter *spanner.RowIterator, redact bool, rowToJSON func(*spanner.Row) (any, error)) *Lazy {
 	l := &Lazy{redact: redact}
-	l.rows = NewRowIter(rowIter, redact, RowToJSON)
+	l.rows = NewRowIter(rowIter, redact, rowToJSON)
 	l.rows.ioMu = &l.ioMu
 	return l
 }
@@ -19,6 +19,25 @@ func ParseInputMode(s string) (InputMode, error) {
 	}
 }
 
+// RowShape selects how each row is represented in jq input.
+type RowShape string
+
+const (
+	// RowShapeArray passes rows as positional arrays, matching protojson ResultSet rows.
+	RowShapeArray RowShape = "array"
+	// RowShapeObject passes rows as objects keyed by column name (see ObjectKeys).
+	RowShapeObject RowShape = "object"
+)
+
+func ParseRowShape(s string) (RowShape, error) {
+	switch RowShape(s) {
+	case RowShapeArray, RowShapeObject:
+		return RowShape(s), nil
+	default:
+		return "", fmt.Errorf("jq-row-shape must be array or object")
+	}
+}
+
 // DefaultFilter returns the filter used when the user passes an empty --filter.
 func DefaultFilter(InputMode) string {
 	return "."
@@ -26,7 +45,7 @@ func DefaultFilter(InputMode) string {
 
 // ValidateFormat returns an error when mode is incompatible with the output format.
 func (m InputMode) ValidateFormat(format string) error {
-	if format == "experimental_csv" && m != InputEager {
+	if format != "json" && format != "yaml" && m != InputEager {
 		return fmt.Errorf("--jq-input-mode=%s is only supported with --format=json or yaml", m)
 	}
 	return nil
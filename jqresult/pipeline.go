@@ -8,17 +8,44 @@ import (
 	"github.com/wader/gojq"
 )
 
+// ExecuteOption configures Execute.
+type ExecuteOption func(*executeConfig)
+
+type executeConfig struct {
+	rowShape RowShape
+	render   RenderOptions
+}
+
+// WithRowShape selects how rows are represented in jq input. The default is RowShapeArray.
+func WithRowShape(shape RowShape) ExecuteOption {
+	return func(c *executeConfig) {
+		c.rowShape = shape
+	}
+}
+
+// WithRender selects how typed row values are rendered. The default keeps protojson.
+func WithRender(render RenderOptions) ExecuteOption {
+	return func(c *executeConfig) {
+		c.render = render
+	}
+}
+
 // Execute runs jq. For eager mode, rs must be set and rowIter is ignored.
 // For lazy mode, rowIter must be unread; cleanup releases the iterator state.
 // Lazy mode is intended for read-only queries; read-write callers should
 // materialize first and use eager mode.
-func Execute(code *gojq.Code, mode InputMode, rowIter *spanner.RowIterator, rs *sppb.ResultSet, redactRows bool) (gojq.Iter, func(), error) {
+func Execute(code *gojq.Code, mode InputMode, rowIter *spanner.RowIterator, rs *sppb.ResultSet, redactRows bool, opts ...ExecuteOption) (gojq.Iter, func(), error) {
+	cfg := executeConfig{rowShape: RowShapeArray}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	switch mode {
 	case InputEager:
 		if rs == nil {
 			return nil, func() {}, fmt.Errorf("eager mode requires a materialized ResultSet")
 		}
-		m, err := ResultSetMap(rs)
+		m, err := cfg.render.ResultSetMap(rs, cfg.rowShape)
 		if err != nil {
 			return nil, func() {}, err
 		}
@@ -27,7 +54,11 @@ func Execute(code *gojq.Code, mode InputMode, rowIter *spanner.RowIterator, rs *
 		if rowIter == nil {
 			return nil, func() {}, fmt.Errorf("lazy mode requires an unread RowIterator")
 		}
-		lazy := NewLazy(rowIter, redactRows)
+		rowToJSON := cfg.render.RowToJSON
+		if cfg.rowShape == RowShapeObject {
+			rowToJSON = cfg.render.RowToObject
+		}
+		lazy := newLazy(rowIter, redactRows, rowToJSON)
 		return code.Run(lazy), lazy.Stop, nil
 	default:
 		return nil, func() {}, fmt.Errorf("unknown jq input mode: %s", mode)
@@ -0,0 +1,286 @@
+package jqresult
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// BytesEncoding selects how BYTES values are rendered.
+type BytesEncoding string
+
+const (
+	// BytesBase64 keeps the protojson representation.
+	BytesBase64 BytesEncoding = "base64"
+	BytesHex    BytesEncoding = "hex"
+	// BytesUTF8 renders the raw bytes as a string; invalid UTF-8 is replaced by U+FFFD when encoded.
+	BytesUTF8 BytesEncoding = "utf8"
+)
+
+func ParseBytesEncoding(s string) (BytesEncoding, error) {
+	switch BytesEncoding(s) {
+	case BytesBase64, BytesHex, BytesUTF8:
+		return BytesEncoding(s), nil
+	default:
+		return "", fmt.Errorf("bytes-encoding must be base64, hex or utf8")
+	}
+}
+
+// IntervalFormat selects how INTERVAL values are rendered.
+type IntervalFormat string
+
+const (
+	// IntervalISO8601 keeps the ISO 8601 duration string sent by Spanner.
+	IntervalISO8601 IntervalFormat = "iso8601"
+	// IntervalObject renders {"months": N, "days": N, "nanos": N}, the fields Spanner normalizes intervals to.
+	IntervalObject IntervalFormat = "object"
+)
+
+func ParseIntervalFormat(s string) (IntervalFormat, error) {
+	switch IntervalFormat(s) {
+	case IntervalISO8601, IntervalObject:
+		return IntervalFormat(s), nil
+	default:
+		return "", fmt.Errorf("interval-format must be iso8601 or object")
+	}
+}
+
+// RenderOptions selects how typed row values are represented in jq input.
+// The zero value keeps the protojson representation: INT64 and NUMERIC as strings,
+// BYTES as base64, TIMESTAMP in UTC, and JSON as its string encoding.
+type RenderOptions struct {
+	// TypedNumbers renders INT64 and NUMERIC as JSON numbers (json.Number, so no precision is lost).
+	TypedNumbers bool
+	// Bytes is the BYTES encoding; empty means base64.
+	Bytes BytesEncoding
+	// Location renders TIMESTAMP as RFC 3339 in this zone; nil keeps UTC.
+	Location *time.Location
+	// DateLayout is a Go time layout for DATE; empty keeps YYYY-MM-DD.
+	DateLayout string
+	// Interval is the INTERVAL format; empty means ISO 8601.
+	Interval IntervalFormat
+	// ExpandJSON decodes JSON columns into values instead of passing the encoded string.
+	ExpandJSON bool
+}
+
+// IsZero reports whether o keeps the protojson representation.
+func (o RenderOptions) IsZero() bool {
+	return o == RenderOptions{}
+}
+
+// RendersRows reports whether rows in shape are rendered by o rather than
+// passed through protojson. Rendered rows hold json.Number only for values
+// that are JSON numbers: FLOAT64, expanded JSON, INTERVAL objects and, with
+// TypedNumbers, INT64 and NUMERIC.
+func (o RenderOptions) RendersRows(shape RowShape) bool {
+	return !o.IsZero() || shape == RowShapeObject
+}
+
+// RowToJSON encodes one row as a positional array. With zero options it is [RowToJSON].
+func (o RenderOptions) RowToJSON(r *spanner.Row) (any, error) {
+	if o.IsZero() {
+		return RowToJSON(r)
+	}
+	fields, values := rowFieldsAndValues(r)
+	out := make([]any, len(values))
+	for i, v := range values {
+		rv, err := o.value(fields[i].GetType(), v, false)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %w", i, err)
+		}
+		out[i] = rv
+	}
+	return out, nil
+}
+
+// RowToObject encodes one row as a jq object keyed by column name (see [ObjectKeys]).
+func (o RenderOptions) RowToObject(r *spanner.Row) (any, error) {
+	fields, values := rowFieldsAndValues(r)
+	obj, err := o.StructValuesToObject(fields, values)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Map(), nil
+}
+
+// StructValuesToObject converts row or STRUCT values to an object keyed by field name.
+// Nested STRUCTs are objects too.
+func (o RenderOptions) StructValuesToObject(fields []*sppb.StructType_Field, values []*structpb.Value) (*Object, error) {
+	if len(fields) != len(values) {
+		return nil, fmt.Errorf("struct has %d fields but value has %d", len(fields), len(values))
+	}
+	obj := &Object{Keys: ObjectKeys(fields), Values: make([]any, len(values))}
+	for i, v := range values {
+		ov, err := o.value(fields[i].GetType(), v, true)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", obj.Keys[i], err)
+		}
+		obj.Values[i] = ov
+	}
+	return obj, nil
+}
+
+// ResultSetMap materializes rs as a jq input map with rows in the given shape.
+// Metadata and stats always follow protojson; only rows are rendered.
+func (o RenderOptions) ResultSetMap(rs *sppb.ResultSet, shape RowShape) (map[string]any, error) {
+	m, err := ProtoToMap(rs)
+	if err != nil {
+		return nil, err
+	}
+	if len(rs.GetRows()) == 0 || !o.RendersRows(shape) {
+		return m, nil
+	}
+
+	fields := rs.GetMetadata().GetRowType().GetFields()
+	rows := make([]any, 0, len(rs.GetRows()))
+	for _, row := range rs.GetRows() {
+		if shape == RowShapeObject {
+			obj, err := o.StructValuesToObject(fields, row.GetValues())
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, obj.Map())
+			continue
+		}
+		v, err := o.value(&sppb.Type{Code: sppb.TypeCode_STRUCT, StructType: &sppb.StructType{Fields: fields}}, structpb.NewListValue(row), false)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, v)
+	}
+	m["rows"] = rows
+	return m, nil
+}
+
+func rowFieldsAndValues(r *spanner.Row) ([]*sppb.StructType_Field, []*structpb.Value) {
+	fields := make([]*sppb.StructType_Field, r.Size())
+	values := make([]*structpb.Value, r.Size())
+	for i := range fields {
+		fields[i] = &sppb.StructType_Field{Name: r.ColumnName(i), Type: r.ColumnType(i)}
+		values[i] = r.ColumnValue(i)
+	}
+	return fields, values
+}
+
+// value renders v of type typ. STRUCTs are objects when objects is set and
+// positional arrays otherwise, as in protojson.
+func (o RenderOptions) value(typ *sppb.Type, v *structpb.Value, objects bool) (any, error) {
+	switch k := v.GetKind().(type) {
+	case *structpb.Value_NullValue:
+		return nil, nil
+	case *structpb.Value_BoolValue:
+		return k.BoolValue, nil
+	case *structpb.Value_NumberValue:
+		// encoding/json formats floats the same way as protojson.
+		b, err := json.Marshal(k.NumberValue)
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(b), nil
+	case *structpb.Value_StringValue:
+		return o.scalar(typ, k.StringValue)
+	case *structpb.Value_ListValue:
+		values := k.ListValue.GetValues()
+		if typ.GetCode() == sppb.TypeCode_STRUCT {
+			fields := typ.GetStructType().GetFields()
+			if objects {
+				return o.StructValuesToObject(fields, values)
+			}
+			if len(fields) != len(values) {
+				return nil, fmt.Errorf("struct has %d fields but value has %d", len(fields), len(values))
+			}
+		}
+		out := make([]any, len(values))
+		for i, e := range values {
+			elemType := typ.GetArrayElementType()
+			if typ.GetCode() == sppb.TypeCode_STRUCT {
+				elemType = typ.GetStructType().GetFields()[i].GetType()
+			}
+			ev, err := o.value(elemType, e, objects)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = ev
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unexpected %T", v.GetKind())
+	}
+}
+
+// scalar renders a string-encoded value.
+func (o RenderOptions) scalar(typ *sppb.Type, s string) (any, error) {
+	switch typ.GetCode() {
+	case sppb.TypeCode_INT64, sppb.TypeCode_NUMERIC:
+		// PG NUMERIC may be NaN, which has no JSON number form.
+		if o.TypedNumbers && isJSONNumber(s) {
+			return json.Number(s), nil
+		}
+	case sppb.TypeCode_BYTES:
+		switch o.Bytes {
+		case BytesHex, BytesUTF8:
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid BYTES value: %w", err)
+			}
+			if o.Bytes == BytesHex {
+				return hex.EncodeToString(b), nil
+			}
+			return string(b), nil
+		}
+	case sppb.TypeCode_TIMESTAMP:
+		if o.Location != nil {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TIMESTAMP value: %w", err)
+			}
+			return t.In(o.Location).Format(time.RFC3339Nano), nil
+		}
+	case sppb.TypeCode_DATE:
+		if o.DateLayout != "" {
+			d, err := civil.ParseDate(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DATE value: %w", err)
+			}
+			return d.In(time.UTC).Format(o.DateLayout), nil
+		}
+	case sppb.TypeCode_INTERVAL:
+		if o.Interval == IntervalObject {
+			iv, err := spanner.ParseInterval(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid INTERVAL value: %w", err)
+			}
+			return map[string]any{
+				"months": json.Number(fmt.Sprint(iv.Months)),
+				"days":   json.Number(fmt.Sprint(iv.Days)),
+				"nanos":  json.Number(iv.Nanos.String()),
+			}, nil
+		}
+	case sppb.TypeCode_JSON:
+		if o.ExpandJSON {
+			dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+			dec.UseNumber()
+			var v any
+			if err := dec.Decode(&v); err != nil {
+				return nil, fmt.Errorf("invalid JSON value: %w", err)
+			}
+			return v, nil
+		}
+	}
+	return s, nil
+}
+
+func isJSONNumber(s string) bool {
+	if s == "" || (s[0] != '-' && (s[0] < '0' || s[0] > '9')) {
+		return false
+	}
+	return json.Valid([]byte(s))
+}
@@ -0,0 +1,153 @@
+package jqresult
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func renderFixture() *sppb.ResultSet {
+	typ := func(code sppb.TypeCode) *sppb.Type { return &sppb.Type{Code: code} }
+	return &sppb.ResultSet{
+		Metadata: &sppb.ResultSetMetadata{RowType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+			{Name: "i", Type: typ(sppb.TypeCode_INT64)},
+			{Name: "n", Type: typ(sppb.TypeCode_NUMERIC)},
+			{Name: "b", Type: typ(sppb.TypeCode_BYTES)},
+			{Name: "ts", Type: typ(sppb.TypeCode_TIMESTAMP)},
+			{Name: "d", Type: typ(sppb.TypeCode_DATE)},
+			{Name: "iv", Type: typ(sppb.TypeCode_INTERVAL)},
+			{Name: "j", Type: typ(sppb.TypeCode_JSON)},
+			{Name: "a", Type: &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: typ(sppb.TypeCode_INT64)}},
+			{Name: "f", Type: typ(sppb.TypeCode_FLOAT64)},
+		}}},
+		Rows: []*structpb.ListValue{{Values: []*structpb.Value{
+			structpb.NewStringValue("9223372036854775807"),
+			structpb.NewStringValue("12345678901234567890.123456789"),
+			structpb.NewStringValue("aGk="),
+			structpb.NewStringValue("2024-01-02T03:04:05.123456Z"),
+			structpb.NewStringValue("2024-01-02"),
+			structpb.NewStringValue("P1Y2M3DT4H5M6.5S"),
+			structpb.NewStringValue(`{"k":[1,2.5]}`),
+			structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("1"), structpb.NewNullValue()}}),
+			structpb.NewStringValue("NaN"),
+		}}},
+	}
+}
+
+func TestRenderOptionsResultSetMap(t *testing.T) {
+	t.Parallel()
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	tests := []struct {
+		name   string
+		render RenderOptions
+		want   []any
+	}{
+		{
+			name: "zero keeps protojson",
+			want: []any{
+				"9223372036854775807", "12345678901234567890.123456789", "aGk=",
+				"2024-01-02T03:04:05.123456Z", "2024-01-02", "P1Y2M3DT4H5M6.5S",
+				`{"k":[1,2.5]}`, []any{"1", nil}, "NaN",
+			},
+		},
+		{
+			name: "all options",
+			render: RenderOptions{
+				TypedNumbers: true,
+				Bytes:        BytesHex,
+				Location:     tokyo,
+				DateLayout:   "2006/01/02",
+				Interval:     IntervalObject,
+				ExpandJSON:   true,
+			},
+			want: []any{
+				json.Number("9223372036854775807"), json.Number("12345678901234567890.123456789"), "6869",
+				"2024-01-02T12:04:05.123456+09:00", "2024/01/02",
+				map[string]any{"months": json.Number("14"), "days": json.Number("3"), "nanos": json.Number("14706500000000")},
+				map[string]any{"k": []any{json.Number("1"), json.Number("2.5")}},
+				[]any{json.Number("1"), nil}, "NaN",
+			},
+		},
+		{
+			name:   "utf8 bytes",
+			render: RenderOptions{Bytes: BytesUTF8},
+			want: []any{
+				"9223372036854775807", "12345678901234567890.123456789", "hi",
+				"2024-01-02T03:04:05.123456Z", "2024-01-02", "P1Y2M3DT4H5M6.5S",
+				`{"k":[1,2.5]}`, []any{"1", nil}, "NaN",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := tc.render.ResultSetMap(renderFixture(), RowShapeArray)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]any{tc.want}, m["rows"]); diff != "" {
+				t.Fatalf("rows (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderOptionsObjectShape(t *testing.T) {
+	t.Parallel()
+
+	m, err := RenderOptions{TypedNumbers: true}.ResultSetMap(renderFixture(), RowShapeObject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := m["rows"].([]any)[0].(map[string]any)
+	if got, want := row["i"], json.Number("9223372036854775807"); got != want {
+		t.Fatalf(`row["i"] = %#v, want %#v`, got, want)
+	}
+	if got, want := row["b"], "aGk="; got != want {
+		t.Fatalf(`row["b"] = %#v, want %#v`, got, want)
+	}
+}
+
+func TestRenderOptionsInvalidValue(t *testing.T) {
+	t.Parallel()
+
+	rs := &sppb.ResultSet{
+		Metadata: &sppb.ResultSetMetadata{RowType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+			{Name: "j", Type: &sppb.Type{Code: sppb.TypeCode_JSON}},
+		}}},
+		Rows: []*structpb.ListValue{{Values: []*structpb.Value{structpb.NewStringValue("{")}}},
+	}
+	if _, err := (RenderOptions{ExpandJSON: true}).ResultSetMap(rs, RowShapeArray); err == nil {
+		t.Fatal("expected error for invalid JSON value")
+	}
+}
+
+func TestParseRenderEnums(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"base64", "hex", "utf8"} {
+		if _, err := ParseBytesEncoding(s); err != nil {
+			t.Errorf("ParseBytesEncoding(%q) error = %v", s, err)
+		}
+	}
+	if _, err := ParseBytesEncoding("base32"); err == nil {
+		t.Error("expected error for base32")
+	}
+	for _, s := range []string{"iso8601", "object"} {
+		if _, err := ParseIntervalFormat(s); err != nil {
+			t.Errorf("ParseIntervalFormat(%q) error = %v", s, err)
+		}
+	}
+	if _, err := ParseIntervalFormat("seconds"); err == nil {
+		t.Error("expected error for seconds")
+	}
+}
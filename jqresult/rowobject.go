@@ -0,0 +1,118 @@
+package jqresult
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Object is a JSON object whose keys keep row type order when marshaled.
+type Object struct {
+	Keys   []string
+	Values []any
+}
+
+// MarshalJSON encodes o with keys in order and without HTML escaping.
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	buf.WriteByte('{')
+	for i, k := range o.Keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := enc.Encode(k); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1) // drop the newline Encode appends
+		buf.WriteByte(':')
+		if err := enc.Encode(o.Values[i]); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Map converts o, including nested objects, to the map form used as jq input.
+func (o *Object) Map() map[string]any {
+	m := make(map[string]any, len(o.Keys))
+	for i, k := range o.Keys {
+		m[k] = objectValueToJQ(o.Values[i])
+	}
+	return m
+}
+
+func objectValueToJQ(v any) any {
+	switch v := v.(type) {
+	case *Object:
+		return v.Map()
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = objectValueToJQ(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// ObjectKeys returns unique object keys for fields in row type order.
+// Named fields keep their name on first use. Anonymous fields become _N, where N is
+// the 1-based field position, and later duplicates of a name become name_2, name_3, ...;
+// generated keys skip any name already taken, so the result is deterministic.
+func ObjectKeys(fields []*sppb.StructType_Field) []string {
+	used := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if f.GetName() != "" {
+			used[f.GetName()] = true
+		}
+	}
+	seen := make(map[string]bool, len(fields))
+	keys := make([]string, len(fields))
+	for i, f := range fields {
+		name := f.GetName()
+		if name != "" && !seen[name] {
+			seen[name] = true
+			keys[i] = name
+			continue
+		}
+
+		var key string
+		if name == "" {
+			key = "_" + strconv.Itoa(i+1)
+			for n := 2; used[key]; n++ {
+				key = "_" + strconv.Itoa(i+1) + "_" + strconv.Itoa(n)
+			}
+		} else {
+			for n := 2; ; n++ {
+				key = name + "_" + strconv.Itoa(n)
+				if !used[key] {
+					break
+				}
+			}
+		}
+		used[key] = true
+		keys[i] = key
+	}
+	return keys
+}
+
+// StructValuesToObject converts row or STRUCT values to an object keyed by field name.
+// Values follow protojson rules as in [RowToJSON], except that nested STRUCTs are objects too.
+func StructValuesToObject(fields []*sppb.StructType_Field, values []*structpb.Value) (*Object, error) {
+	return RenderOptions{}.StructValuesToObject(fields, values)
+}
+
+// RowToObject encodes one row as a jq object keyed by column name (see [ObjectKeys]).
+// It can be passed to [NewRowIter] in place of [RowToJSON].
+func RowToObject(r *spanner.Row) (any, error) {
+	return RenderOptions{}.RowToObject(r)
+}
@@ -0,0 +1,126 @@
+package jqresult
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestObjectKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		names []string
+		want  []string
+	}{
+		{[]string{"a", "b"}, []string{"a", "b"}},
+		{[]string{"a", "a", "a"}, []string{"a", "a_2", "a_3"}},
+		{[]string{"a", "a", "a_2"}, []string{"a", "a_3", "a_2"}},
+		{[]string{"", "x", ""}, []string{"_1", "x", "_3"}},
+		{[]string{"_1", ""}, []string{"_1", "_2"}},
+		{[]string{"", "_1"}, []string{"_1_2", "_1"}},
+	}
+	for _, tc := range tests {
+		fields := make([]*sppb.StructType_Field, len(tc.names))
+		for i, name := range tc.names {
+			fields[i] = &sppb.StructType_Field{Name: name, Type: &sppb.Type{Code: sppb.TypeCode_INT64}}
+		}
+		if diff := cmp.Diff(tc.want, ObjectKeys(fields)); diff != "" {
+			t.Errorf("ObjectKeys(%q) (-want +got)\n%s", tc.names, diff)
+		}
+	}
+}
+
+func TestStructValuesToObjectMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	structType := &sppb.Type{
+		Code: sppb.TypeCode_STRUCT,
+		StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+			{Name: "z", Type: &sppb.Type{Code: sppb.TypeCode_STRING}},
+			{Type: &sppb.Type{Code: sppb.TypeCode_FLOAT64}},
+		}},
+	}
+	fields := []*sppb.StructType_Field{
+		{Name: "id", Type: &sppb.Type{Code: sppb.TypeCode_INT64}},
+		{Name: "s", Type: structType},
+		{Name: "arr", Type: &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: structType}},
+		{Name: "b", Type: &sppb.Type{Code: sppb.TypeCode_BOOL}},
+		{Name: "id", Type: &sppb.Type{Code: sppb.TypeCode_STRING}},
+	}
+	structValue := structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{
+		structpb.NewStringValue("<x>"), structpb.NewNumberValue(1.5),
+	}})
+	values := []*structpb.Value{
+		structpb.NewStringValue("1"),
+		structValue,
+		structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structValue}}),
+		structpb.NewBoolValue(true),
+		structpb.NewNullValue(),
+	}
+
+	obj, err := StructValuesToObject(fields, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(obj); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":"1","s":{"z":"<x>","_2":1.5},"arr":[{"z":"<x>","_2":1.5}],"b":true,"id_2":null}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("Encode() = %s, want %s", got, want)
+	}
+
+	if _, err := StructValuesToObject(fields[:1], values); err == nil {
+		t.Fatal("expected error for field count mismatch")
+	}
+}
+
+func TestRowToObject(t *testing.T) {
+	t.Parallel()
+
+	row, err := spanner.NewRow([]string{"id", "name"}, []any{int64(1), "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := RowToObject(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"id": "1", "name": "alice"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("RowToObject() (-want +got)\n%s", diff)
+	}
+}
+
+func TestExecuteEagerRowShapeObject(t *testing.T) {
+	t.Parallel()
+
+	rs := &sppb.ResultSet{
+		Metadata: &sppb.ResultSetMetadata{RowType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+			{Name: "id", Type: &sppb.Type{Code: sppb.TypeCode_INT64}},
+		}}},
+		Rows: []*structpb.ListValue{{Values: []*structpb.Value{structpb.NewStringValue("42")}}},
+	}
+	code, err := Compile(".rows[0].id", InputEager)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iter, cleanup, err := Execute(code, InputEager, nil, rs, false, WithRowShape(RowShapeObject))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	v, ok := iter.Next()
+	if !ok || v != "42" {
+		t.Fatalf("got %v, %v want 42", v, ok)
+	}
+}
@@ -0,0 +1,183 @@
+package jqresult
+
+import (
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/valuefmt"
+)
+
+// JSONSchemaDialect is the JSON Schema draft of [RenderOptions.RowSchema].
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Patterns of the string-encoded integer and decimal types.
+const (
+	integerPattern = `^-?[0-9]+$`
+	decimalPattern = `^-?[0-9]+(\.[0-9]+)?$`
+)
+
+// schemaObject builds an object whose keys keep the given order.
+func schemaObject(kv ...any) *Object {
+	o := &Object{}
+	for i := 0; i < len(kv); i += 2 {
+		o.Keys = append(o.Keys, kv[i].(string))
+		o.Values = append(o.Values, kv[i+1])
+	}
+	return o
+}
+
+func (o *Object) set(key string, value any) {
+	for i, k := range o.Keys {
+		if k == key {
+			o.Values[i] = value
+			return
+		}
+	}
+	o.Keys = append(o.Keys, key)
+	o.Values = append(o.Values, value)
+}
+
+func (o *Object) get(key string) (any, bool) {
+	for i, k := range o.Keys {
+		if k == key {
+			return o.Values[i], true
+		}
+	}
+	return nil, false
+}
+
+// RowSchema returns a JSON Schema of the rows of rowType as they are rendered
+// with o in the given shape: an object keyed by [ObjectKeys] or a positional
+// array. The result marshals to JSON with keys in a stable order.
+//
+// The row type does not record NOT NULL constraints, so every column, array
+// element and STRUCT field allows null. Each schema carries the GoogleSQL type
+// as its description.
+func (o RenderOptions) RowSchema(rowType *sppb.StructType, shape RowShape) *Object {
+	s := o.structSchema(rowType.GetFields(), shape)
+	s.Keys = append([]string{"$schema"}, s.Keys...)
+	s.Values = append([]any{JSONSchemaDialect}, s.Values...)
+	return s
+}
+
+func (o RenderOptions) structSchema(fields []*sppb.StructType_Field, shape RowShape) *Object {
+	if shape == RowShapeObject {
+		keys := ObjectKeys(fields)
+		properties := &Object{Keys: keys, Values: make([]any, len(fields))}
+		for i, f := range fields {
+			properties.Values[i] = o.typeSchema(f.GetType(), shape)
+		}
+		return schemaObject(
+			"type", "object",
+			"properties", properties,
+			"required", keys,
+			"additionalProperties", false,
+		)
+	}
+	items := make([]any, len(fields))
+	for i, f := range fields {
+		s := o.typeSchema(f.GetType(), shape)
+		if f.GetName() != "" {
+			s.set("title", f.GetName())
+		}
+		items[i] = s
+	}
+	return schemaObject(
+		"type", "array",
+		"prefixItems", items,
+		"minItems", len(fields),
+		"maxItems", len(fields),
+	)
+}
+
+// typeSchema returns the nullable schema of a value of typ.
+func (o RenderOptions) typeSchema(typ *sppb.Type, shape RowShape) *Object {
+	s := o.valueSchema(typ, shape)
+	if t, ok := s.get("type"); ok {
+		s.set("type", []any{t, "null"})
+	} else if anyOf, ok := s.get("anyOf"); ok {
+		s.set("anyOf", append(anyOf.([]any), schemaObject("type", "null")))
+	}
+	s.set("description", valuefmt.TypeString(typ))
+	return s
+}
+
+// valueSchema mirrors the rendering of [RenderOptions.scalar] for non-null values.
+func (o RenderOptions) valueSchema(typ *sppb.Type, shape RowShape) *Object {
+	switch typ.GetCode() {
+	case sppb.TypeCode_BOOL:
+		return schemaObject("type", "boolean")
+	case sppb.TypeCode_INT64:
+		if o.TypedNumbers {
+			return schemaObject("type", "integer")
+		}
+		return schemaObject("type", "string", "pattern", integerPattern)
+	case sppb.TypeCode_NUMERIC:
+		if typ.GetTypeAnnotation() == sppb.TypeAnnotationCode_PG_NUMERIC {
+			// PostgreSQL NUMERIC may be NaN, which stays a string.
+			if o.TypedNumbers {
+				return schemaObject("anyOf", []any{schemaObject("type", "number"), schemaObject("const", "NaN")})
+			}
+			return schemaObject("type", "string")
+		}
+		if o.TypedNumbers {
+			return schemaObject("type", "number")
+		}
+		return schemaObject("type", "string", "pattern", decimalPattern)
+	case sppb.TypeCode_FLOAT32, sppb.TypeCode_FLOAT64:
+		// protojson encodes non-finite floats as strings.
+		return schemaObject("anyOf", []any{
+			schemaObject("type", "number"),
+			schemaObject("enum", []any{"NaN", "Infinity", "-Infinity"}),
+		})
+	case sppb.TypeCode_STRING:
+		return schemaObject("type", "string")
+	case sppb.TypeCode_BYTES:
+		switch o.Bytes {
+		case BytesHex:
+			return schemaObject("type", "string", "pattern", "^([0-9a-f]{2})*$")
+		case BytesUTF8:
+			return schemaObject("type", "string")
+		default:
+			return schemaObject("type", "string", "contentEncoding", "base64")
+		}
+	case sppb.TypeCode_PROTO:
+		return schemaObject("type", "string", "contentEncoding", "base64")
+	case sppb.TypeCode_ENUM:
+		return schemaObject("type", "string", "pattern", integerPattern)
+	case sppb.TypeCode_DATE:
+		if o.DateLayout != "" {
+			return schemaObject("type", "string")
+		}
+		return schemaObject("type", "string", "format", "date")
+	case sppb.TypeCode_TIMESTAMP:
+		return schemaObject("type", "string", "format", "date-time")
+	case sppb.TypeCode_UUID:
+		return schemaObject("type", "string", "format", "uuid")
+	case sppb.TypeCode_INTERVAL:
+		if o.Interval == IntervalObject {
+			return schemaObject(
+				"type", "object",
+				"properties", schemaObject(
+					"months", schemaObject("type", "integer"),
+					"days", schemaObject("type", "integer"),
+					"nanos", schemaObject("type", "integer"),
+				),
+				"required", []string{"months", "days", "nanos"},
+				"additionalProperties", false,
+			)
+		}
+		return schemaObject("type", "string", "format", "duration")
+	case sppb.TypeCode_JSON:
+		if o.ExpandJSON {
+			// Any JSON value; a JSON null column is indistinguishable from SQL NULL.
+			return schemaObject()
+		}
+		return schemaObject("type", "string", "contentMediaType", "application/json")
+	case sppb.TypeCode_ARRAY:
+		return schemaObject("type", "array", "items", o.typeSchema(typ.GetArrayElementType(), shape))
+	case sppb.TypeCode_STRUCT:
+		return o.structSchema(typ.GetStructType().GetFields(), shape)
+	default:
+		// Unknown types keep their wire form, which is a string for scalars.
+		return schemaObject()
+	}
+}
@@ -0,0 +1,86 @@
+package jqresult
+
+import (
+	"encoding/json"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/google/go-cmp/cmp"
+)
+
+// schemaProperty decodes the schema of the named column from an object-shaped row schema.
+func schemaProperty(t *testing.T, s *Object, name string) map[string]any {
+	t.Helper()
+	b, err := s.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m["properties"].(map[string]any)[name].(map[string]any)
+}
+
+func TestRowSchema(t *testing.T) {
+	t.Parallel()
+
+	rowType := &sppb.StructType{Fields: []*sppb.StructType_Field{
+		{Name: "id", Type: &sppb.Type{Code: sppb.TypeCode_INT64}},
+		{Name: "st", Type: &sppb.Type{Code: sppb.TypeCode_STRUCT, StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+			{Name: "b", Type: &sppb.Type{Code: sppb.TypeCode_BOOL}},
+		}}}},
+	}}
+	tests := map[RowShape]string{
+		RowShapeObject: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","properties":{` +
+			`"id":{"type":["string","null"],"pattern":"^-?[0-9]+$","description":"INT64"},` +
+			`"st":{"type":["object","null"],"properties":{"b":{"type":["boolean","null"],"description":"BOOL"}},"required":["b"],"additionalProperties":false,"description":"STRUCT<b BOOL>"}},` +
+			`"required":["id","st"],"additionalProperties":false}`,
+		RowShapeArray: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"array","prefixItems":[` +
+			`{"type":["string","null"],"pattern":"^-?[0-9]+$","description":"INT64","title":"id"},` +
+			`{"type":["array","null"],"prefixItems":[{"type":["boolean","null"],"description":"BOOL","title":"b"}],"minItems":1,"maxItems":1,"description":"STRUCT<b BOOL>","title":"st"}],` +
+			`"minItems":2,"maxItems":2}`,
+	}
+	for shape, want := range tests {
+		b, err := RenderOptions{}.RowSchema(rowType, shape).MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("RowSchema(%s) =\n%s\nwant\n%s", shape, b, want)
+		}
+	}
+}
+
+func TestRowSchemaRenderOptions(t *testing.T) {
+	t.Parallel()
+
+	rowType := renderFixture().GetMetadata().GetRowType()
+	tests := []struct {
+		render RenderOptions
+		column string
+		want   map[string]any
+	}{
+		{RenderOptions{}, "f", map[string]any{
+			"anyOf":       []any{map[string]any{"type": "number"}, map[string]any{"enum": []any{"NaN", "Infinity", "-Infinity"}}, map[string]any{"type": "null"}},
+			"description": "FLOAT64",
+		}},
+		{RenderOptions{TypedNumbers: true}, "n", map[string]any{"type": []any{"number", "null"}, "description": "NUMERIC"}},
+		{RenderOptions{}, "b", map[string]any{"type": []any{"string", "null"}, "contentEncoding": "base64", "description": "BYTES"}},
+		{RenderOptions{Bytes: BytesHex}, "b", map[string]any{"type": []any{"string", "null"}, "pattern": "^([0-9a-f]{2})*$", "description": "BYTES"}},
+		{RenderOptions{}, "ts", map[string]any{"type": []any{"string", "null"}, "format": "date-time", "description": "TIMESTAMP"}},
+		{RenderOptions{DateLayout: "2006/01/02"}, "d", map[string]any{"type": []any{"string", "null"}, "description": "DATE"}},
+		{RenderOptions{ExpandJSON: true}, "j", map[string]any{"description": "JSON"}},
+		{RenderOptions{TypedNumbers: true}, "a", map[string]any{
+			"type":        []any{"array", "null"},
+			"items":       map[string]any{"type": []any{"integer", "null"}, "description": "INT64"},
+			"description": "ARRAY<INT64>",
+		}},
+	}
+	for _, tc := range tests {
+		got := schemaProperty(t, tc.render.RowSchema(rowType, RowShapeObject), tc.column)
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("%+v schema of %s mismatch (-want +got):\n%s", tc.render, tc.column, diff)
+		}
+	}
+}
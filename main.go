@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"encoding/json"
 
@@ -18,6 +20,7 @@ import (
 
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 
 	grpczap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
 	"go.uber.org/zap"
@@ -25,17 +28,28 @@ import (
 	"cloud.google.com/go/spanner"
 	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
 	"github.com/alecthomas/kong"
+	"github.com/apstndb/execspansql/codegen"
 	"github.com/apstndb/execspansql/jqresult"
+	"github.com/apstndb/execspansql/plancheck"
+	"github.com/apstndb/execspansql/queryplan"
 	"github.com/apstndb/execspansql/resultset"
+	"github.com/apstndb/execspansql/rowwriter"
 	"github.com/apstndb/gsqlutils/stmtkind"
 	"github.com/apstndb/spaniter"
 	"github.com/apstndb/spannerotel/interceptor"
 	svwriter "github.com/apstndb/spanvalue/writer"
 	"github.com/wader/gojq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func main() {
 	if err := _main(); err != nil {
+		if errors.Is(err, plancheck.ErrViolations) {
+			// The findings are written; the exit status tells them from other errors.
+			log.Println(err)
+			os.Exit(2)
+		}
 		log.Fatalln(err)
 	}
 }
@@ -53,26 +67,66 @@ func init() {
 }
 
 type opts struct {
-	Database             string        `arg:"" required:"" help:"ID of the database."`
-	Sql                  string        `name:"sql" xor:"sql" required:"" help:"SQL query text; exclusive with --sql-file."`
-	SqlFile              string        `name:"sql-file" xor:"sql" required:"" help:"File name contains SQL query; exclusive with --sql"`
-	Project              string        `name:"project" short:"p" env:"CLOUDSDK_CORE_PROJECT" required:"" help:"ID of the project."`
-	Instance             string        `name:"instance" short:"i" env:"CLOUDSDK_SPANNER_INSTANCE" required:"" help:"ID of the instance."`
-	QueryMode            string        `name:"query-mode" enum:"NORMAL,PLAN,PROFILE" default:"NORMAL" help:"Query mode."`
-	Format               string        `name:"format" enum:"json,yaml,experimental_csv" default:"json" help:"Output format."`
+	Database             string        `arg:"" optional:"" help:"ID of the database; required unless --from-resultset is given."`
+	Sql                  string        `name:"sql" xor:"sql" help:"SQL query text; exclusive with --sql-file."`
+	SqlFile              string        `name:"sql-file" xor:"sql" help:"File name contains SQL query; exclusive with --sql"`
+	Project              string        `name:"project" short:"p" env:"CLOUDSDK_CORE_PROJECT" help:"ID of the project."`
+	Instance             string        `name:"instance" short:"i" env:"CLOUDSDK_SPANNER_INSTANCE" help:"ID of the instance."`
+	SaveResultset        string        `name:"save-resultset" help:"Also save the materialized ResultSet with the SQL, parameters and read timestamp to this file."`
+	SaveResultsetFormat  string        `name:"save-resultset-format" enum:"binary,json" default:"binary" help:"Encoding of --save-resultset: binary protobuf or protojson."`
+	FromResultset        string        `name:"from-resultset" help:"Render a file saved by --save-resultset (or a ResultSet in JSON) instead of querying Spanner."`
+	QueryMode            string        `name:"query-mode" enum:"NORMAL,PLAN,PROFILE" default:"NORMAL" help:"Query mode. --format=json-schema and --format=codegen always use PLAN."`
+	Format               string        `name:"format" enum:"json,yaml,csv,experimental_csv,table,markdown,html,jsonl-objects,sql-insert,mutations-json,parquet,arrow,avro,sqlite,xlsx,gcloud,template,json-schema,codegen,plan,plan-dot,plan-mermaid,plan-html,folded,pprof,plan-rows,plan-diff,plan-check" default:"json" help:"Output format. experimental_csv is the former name of csv and ignores the --csv-* flags."`
+	CSVDelimiter         string        `name:"csv-delimiter" default:"," help:"Field delimiter of --format=csv; use tab for TSV."`
+	CSVNoHeader          bool          `name:"csv-no-header" help:"Omit the header row of --format=csv."`
+	CSVNullString        string        `name:"csv-null-string" default:"<null>" help:"Text written for NULL in --format=csv."`
+	CSVBOM               bool          `name:"csv-bom" help:"Start --format=csv with a UTF-8 byte order mark (for Excel)."`
+	CSVTypeHeader        bool          `name:"csv-type-header" help:"Write column types as a second header row in --format=csv."`
+	CSVComposite         string        `name:"csv-composite" enum:"text,json,sql" default:"text" help:"Render ARRAY and STRUCT in --format=csv as text ([a, b]), JSON or GoogleSQL literals."`
+	Table                string        `name:"table" help:"Target table of --format=sql-insert, mutations-json and sqlite."`
+	BatchSize            int           `name:"batch-size" default:"100" help:"Rows per INSERT statement or mutation in --format=sql-insert and mutations-json, per record batch in --format=arrow, per block in --format=avro and per transaction in --format=sqlite."`
+	RowGroupSize         int           `name:"row-group-size" default:"100000" help:"Rows per row group in --format=parquet; one row group is buffered in memory."`
+	Output               string        `name:"output" short:"o" help:"Write output to this file instead of stdout; required for --format=parquet, sqlite and xlsx. The file is replaced only if the command succeeds."`
+	XLSXStats            bool          `name:"xlsx-stats" help:"Add a Stats sheet with query stats and the plan nodes to --format=xlsx."`
+	SQLiteAppend         bool          `name:"sqlite-append" help:"Add rows to an existing --format=sqlite database instead of replacing it; the table is created if missing."`
+	Template             string        `name:"template" xor:"template" help:"Go text/template executed for each row of --format=template; columns are accessible by name (e.g. {{.SingerId}})."`
+	TemplateFile         string        `name:"template-file" xor:"template" help:"File containing the --format=template row template."`
+	TemplateHeader       string        `name:"template-header" help:"Template executed before the first row of --format=template with the columns and metadata."`
+	TemplateFooter       string        `name:"template-footer" help:"Template executed after the last row of --format=template with the row count and stats."`
+	CodegenLang          string        `name:"codegen-lang" enum:"go,typescript,proto" default:"go" help:"Language of --format=codegen: Go structs, TypeScript interfaces or protobuf messages."`
+	CodegenPackage       string        `name:"codegen-package" default:"model" help:"Go or protobuf package of --format=codegen (ignored for typescript)."`
+	CodegenName          string        `name:"codegen-name" default:"Row" help:"Name of the --format=codegen row type; the parameter type is <name>Params."`
+	PlanRowsFormat       string        `name:"plan-rows-format" enum:"csv,table,markdown,html,jsonl-objects,sql-insert,mutations-json,parquet,arrow,avro,sqlite,xlsx" default:"csv" help:"Format of the plan node rows of --format=plan-rows."`
+	PlanDiffBase         string        `name:"plan-diff-base" help:"File saved by --save-resultset in PLAN or PROFILE mode whose plan --format=plan-diff compares against."`
+	PlanCheck            string        `name:"plan-check" help:"YAML file of the rules of --format=plan-check; all rules with their defaults, only missing_index an error, if not given."`
+	FoldedValue          string        `name:"folded-value" enum:"latency,cpu" default:"latency" help:"Execution stat of --format=folded stacks: latency or cpu time."`
+	TableStyle           string        `name:"table-style" enum:"ascii,unicode,vertical" default:"ascii" help:"Layout of --format=table: ascii or unicode borders, or vertical (one record per row)."`
+	HTMLIncludePlan      bool          `name:"html-include-plan" help:"Append the query plan (PLAN/PROFILE) to --format=html in a collapsible section."`
 	RedactRows           bool          `name:"redact-rows" help:"Redact result rows from output"`
 	CompactOutput        bool          `name:"compact-output" short:"c" help:"Compact JSON output (--compact-output of jq)"`
 	JqFilter             string        `name:"filter" xor:"filter" help:"jq filter"`
 	JqRawOutput          bool          `name:"raw-output" short:"r" help:"(--raw-output of jq)"`
 	JqFromFile           string        `name:"filter-file" xor:"filter" help:"(--from-file of jq)"`
 	JqInputMode          string        `name:"jq-input-mode" enum:"eager,lazy" default:"eager" help:"How query rows are passed to jq (json/yaml only): eager (full ResultSet), lazy (JQValue root)."`
+	JqRowShape           string        `name:"jq-row-shape" enum:"array,object" default:"array" help:"How each row is passed to jq: array (positional values) or object (keyed by column name)."`
+	TypedNumbers         bool          `name:"typed-numbers" help:"Render INT64 and NUMERIC as JSON/YAML numbers with exact precision instead of strings."`
+	BytesEncoding        string        `name:"bytes-encoding" enum:"base64,hex,utf8" default:"base64" help:"Render BYTES in json, yaml, jsonl-objects and template as base64, hex or utf8 text."`
+	Timezone             string        `name:"timezone" help:"Render TIMESTAMP in json, yaml, jsonl-objects and template in this IANA time zone (e.g. Asia/Tokyo) instead of UTC."`
+	DateFormat           string        `name:"date-format" help:"Go time layout for DATE in json, yaml, jsonl-objects and template (e.g. 2006/01/02); default is YYYY-MM-DD."`
+	IntervalFormat       string        `name:"interval-format" enum:"iso8601,object" default:"iso8601" help:"Render INTERVAL in json, yaml, jsonl-objects and template as an ISO 8601 duration or a {months, days, nanos} object."`
+	ExpandJSON           bool          `name:"expand-json" help:"Render JSON columns as JSON values instead of encoded strings."`
 	ParamFlags           []string      `name:"param" help:"[name]=[type or literal]; legacy [name]:[...] also accepted"`
 	ParamFile            string        `name:"param-file" help:"YAML or JSON file of query parameters (name to type/literal string)"`
 	LogGrpc              bool          `name:"log-grpc" help:"Show gRPC logs"`
 	TraceProject         string        `name:"experimental-trace-project" xor:"trace" help:"Export traces to Cloud Trace in the given project."`
 	TraceStdout          bool          `name:"experimental-trace-stdout" xor:"trace" help:"Export spans to stderr as pretty JSON (local debugging)."`
 	TraceOTLP            bool          `name:"experimental-trace-otlp" xor:"trace" help:"Export spans via OTLP/gRPC to a local OpenTelemetry collector."`
+	TraceFile            string        `name:"experimental-trace-file" xor:"trace" help:"Write spans to this file as Chrome Trace Event Format JSON for Perfetto or chrome://tracing."`
 	TraceOTLPEndpoint    string        `name:"experimental-trace-otlp-endpoint" default:"localhost:4317" help:"OTLP/gRPC endpoint used with --experimental-trace-otlp."`
+	OptimizerVersion     string        `name:"optimizer-version" help:"Optimizer version to run the query with, such as 7 or latest; the database default if empty."`
+	OptimizerStatsPkg    string        `name:"optimizer-statistics-package" help:"Optimizer statistics package to run the query with; the database default if empty."`
+	ProfileRuns          int           `name:"profile-runs" default:"1" help:"Run the query this many times in PROFILE mode and aggregate the execution stats of the plan nodes and the query stats into min, median, p95 and max."`
+	ProfileWarmup        int           `name:"profile-warmup" help:"Runs before --profile-runs whose stats are discarded."`
 	EnablePartitionedDML bool          `name:"enable-partitioned-dml" help:"Execute DML statement using Partitioned DML"`
 	Timeout              time.Duration `name:"timeout" default:"10m" help:"Maximum time to wait for the SQL query to complete"`
 	TryPartitionQuery    bool          `name:"try-partition-query" help:"(Experimental) Check whether the query can be executed as partition query or not"`
@@ -97,6 +151,54 @@ func (o opts) mergedParams() (map[string]string, error) {
 	return params.MergeParams(fileParams, cliParams), nil
 }
 
+// paramFields returns the types of the given query parameters, which may be
+// bare types, as struct fields.
+func (o opts) paramFields() ([]*sppb.StructType_Field, error) {
+	m, err := o.mergedParams()
+	if err != nil {
+		return nil, err
+	}
+	values, err := params.GenerateParams(m, true)
+	if err != nil {
+		return nil, err
+	}
+	var fields []*sppb.StructType_Field
+	for name, v := range values {
+		if gcv, ok := v.(spanner.GenericColumnValue); ok {
+			fields = append(fields, &sppb.StructType_Field{Name: name, Type: gcv.Type})
+		}
+	}
+	return fields, nil
+}
+
+// queryOptions returns the options to run the query with in mode.
+func (o opts) queryOptions(mode sppb.ExecuteSqlRequest_QueryMode) spanner.QueryOptions {
+	qo := spanner.QueryOptions{Mode: &mode}
+	if o.OptimizerVersion != "" || o.OptimizerStatsPkg != "" {
+		qo.Options = &sppb.ExecuteSqlRequest_QueryOptions{
+			OptimizerVersion:           o.OptimizerVersion,
+			OptimizerStatisticsPackage: o.OptimizerStatsPkg,
+		}
+	}
+	return qo
+}
+
+// repeatsProfile reports whether the query runs more than once by
+// --profile-runs or --profile-warmup.
+func (o opts) repeatsProfile() bool {
+	return o.ProfileRuns > 1 || o.ProfileWarmup > 0
+}
+
+// rowFormat returns the flag selecting the format that rows are written in
+// and its value: --plan-rows-format for --format=plan-rows, which writes plan
+// nodes as rows, and --format otherwise.
+func (o opts) rowFormat() (flag, format string) {
+	if o.Format == "plan-rows" {
+		return "--plan-rows-format", o.PlanRowsFormat
+	}
+	return "--format", o.Format
+}
+
 func processFlags() (o opts, err error) {
 	parser, err := kong.New(&o,
 		kong.Name("execspansql"),
@@ -128,6 +230,10 @@ func processFlags() (o opts, err error) {
 		return o, err
 	}
 
+	if err := o.validateSource(); err != nil {
+		return o, err
+	}
+
 	if o.TimestampBound.ReadTimestamp != "" {
 		if _, err := time.Parse(time.RFC3339Nano, o.TimestampBound.ReadTimestamp); err != nil {
 			return o, fmt.Errorf("--read-timestamp is supplied but wrong: %w", err)
@@ -137,9 +243,287 @@ func processFlags() (o opts, err error) {
 	if _, err := jqresult.ParseInputMode(o.JqInputMode); err != nil {
 		return o, err
 	}
+	if _, err := jqresult.ParseRowShape(o.JqRowShape); err != nil {
+		return o, err
+	}
+	if _, err := rowwriter.ParseTableStyle(o.TableStyle); err != nil {
+		return o, err
+	}
+	if _, err := o.renderOptions(); err != nil {
+		return o, err
+	}
+	if _, err := o.csvOptions(); err != nil {
+		return o, err
+	}
+	flag, format := o.rowFormat()
+	if (format == "sql-insert" || format == "mutations-json" || format == "sqlite") && o.Table == "" {
+		return o, fmt.Errorf("%s=%s requires --table", flag, format)
+	}
+	if o.BatchSize < 1 {
+		return o, fmt.Errorf("--batch-size must be positive")
+	}
+	if (format == "parquet" || format == "sqlite" || format == "xlsx") && o.Output == "" {
+		return o, fmt.Errorf("%s=%s requires --output", flag, format)
+	}
+	if o.RowGroupSize < 1 {
+		return o, fmt.Errorf("--row-group-size must be positive")
+	}
+	if (o.Format == "plan-diff") != (o.PlanDiffBase != "") {
+		return o, fmt.Errorf("--format=plan-diff and --plan-diff-base must be given together")
+	}
+	if o.ProfileRuns < 1 || o.ProfileWarmup < 0 {
+		return o, fmt.Errorf("--profile-runs must be positive and --profile-warmup must not be negative")
+	}
+	if o.repeatsProfile() {
+		if o.QueryMode != "PROFILE" {
+			return o, fmt.Errorf("--profile-runs and --profile-warmup require --query-mode=PROFILE")
+		}
+		if o.FromResultset != "" || o.EnablePartitionedDML || o.TryPartitionQuery {
+			return o, fmt.Errorf("--profile-runs and --profile-warmup cannot be combined with --from-resultset, --enable-partitioned-dml or --try-partition-query")
+		}
+	}
+	if (o.Format == "json-schema" || o.Format == "codegen") && o.QueryMode == "PROFILE" {
+		// Only the metadata is read, and PLAN mode returns it without running the query.
+		return o, fmt.Errorf("--format=%s runs the statement in PLAN mode and cannot be combined with --query-mode=PROFILE", o.Format)
+	}
+	if o.PlanCheck != "" && o.Format != "plan-check" {
+		return o, fmt.Errorf("--plan-check requires --format=plan-check")
+	}
+	hasTemplate := o.Template != "" || o.TemplateFile != ""
+	if o.Format == "template" && !hasTemplate {
+		return o, fmt.Errorf("--format=template requires --template or --template-file")
+	}
+	if o.Format != "template" && (hasTemplate || o.TemplateHeader != "" || o.TemplateFooter != "") {
+		return o, fmt.Errorf("--template, --template-file, --template-header and --template-footer require --format=template")
+	}
 	return o, nil
 }
 
+// planCheckConfig returns the rules of --plan-check, or the defaults.
+func (o opts) planCheckConfig() (plancheck.Config, error) {
+	if o.PlanCheck == "" {
+		return plancheck.DefaultConfig(), nil
+	}
+	return plancheck.LoadConfig(o.PlanCheck)
+}
+
+func planCheckWriter(config plancheck.Config) rowWriterFunc {
+	return func(w io.Writer, _ resultset.Header) rowwriter.Writer { return rowwriter.NewPlanCheck(w, config) }
+}
+
+// validateSource checks the flags that select where the result comes from:
+// a query, which needs the database and SQL, or a file given by --from-resultset.
+func (o opts) validateSource() error {
+	if o.FromResultset != "" {
+		if o.Sql != "" || o.SqlFile != "" || o.TryPartitionQuery {
+			return fmt.Errorf("--from-resultset cannot be combined with --sql, --sql-file or --try-partition-query")
+		}
+		return nil
+	}
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"database", o.Database},
+		{"--project", o.Project},
+		{"--instance", o.Instance},
+	} {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if o.Sql == "" && o.SqlFile == "" {
+		missing = append(missing, "--sql or --sql-file")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// csvOptions returns the --format=csv dialect selected by flags.
+func (o opts) csvOptions() (rowwriter.CSVOptions, error) {
+	delimiter, err := rowwriter.ParseCSVDelimiter(o.CSVDelimiter)
+	if err != nil {
+		return rowwriter.CSVOptions{}, err
+	}
+	composite, err := rowwriter.ParseCompositeFormat(o.CSVComposite)
+	if err != nil {
+		return rowwriter.CSVOptions{}, err
+	}
+	return rowwriter.CSVOptions{
+		Delimiter:  delimiter,
+		NoHeader:   o.CSVNoHeader,
+		NullString: o.CSVNullString,
+		BOM:        o.CSVBOM,
+		TypeHeader: o.CSVTypeHeader,
+		Composite:  composite,
+	}, nil
+}
+
+// rowWriterFunc creates a [rowwriter.Writer] on w for the result of the
+// statement described by header.
+type rowWriterFunc func(w io.Writer, header resultset.Header) rowwriter.Writer
+
+// rowWriter returns the constructor of the [rowwriter] format selected by --format,
+// or nil for json, yaml and experimental_csv.
+func (o opts) rowWriter(render jqresult.RenderOptions) (rowWriterFunc, error) {
+	switch o.Format {
+	case "csv":
+		csvOpts, err := o.csvOptions()
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer { return rowwriter.NewCSV(w, csvOpts) }, nil
+	case "table":
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer {
+			return rowwriter.NewTable(w, rowwriter.TableStyle(o.TableStyle))
+		}, nil
+	case "markdown":
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer { return rowwriter.NewMarkdown(w) }, nil
+	case "html":
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer { return rowwriter.NewHTML(w, o.HTMLIncludePlan) }, nil
+	case "jsonl-objects":
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer { return rowwriter.NewJSONLObjects(w, render) }, nil
+	case "sql-insert":
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer {
+			return rowwriter.NewSQLInsert(w, o.Table, o.BatchSize)
+		}, nil
+	case "mutations-json":
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer {
+			return rowwriter.NewMutationsJSON(w, o.Table, o.BatchSize)
+		}, nil
+	case "parquet":
+		return func(w io.Writer, header resultset.Header) rowwriter.Writer {
+			return rowwriter.NewParquet(w, header.SQL, o.RowGroupSize)
+		}, nil
+	case "arrow":
+		return func(w io.Writer, header resultset.Header) rowwriter.Writer {
+			return rowwriter.NewArrowStream(w, header.SQL, o.BatchSize)
+		}, nil
+	case "avro":
+		return func(w io.Writer, header resultset.Header) rowwriter.Writer {
+			return rowwriter.NewAvro(w, header.SQL, o.BatchSize)
+		}, nil
+	case "sqlite":
+		// --output is opened by the writer as a database.
+		return func(io.Writer, resultset.Header) rowwriter.Writer {
+			return rowwriter.NewSQLite(o.Output, o.Table, o.SQLiteAppend, o.BatchSize)
+		}, nil
+	case "xlsx":
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer { return rowwriter.NewXLSX(w, o.XLSXStats) }, nil
+	case "template":
+		text, err := readFileOrDefault(o.TemplateFile, o.Template)
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := rowwriter.ParseTemplate(text, o.TemplateHeader, o.TemplateFooter)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer { return rowwriter.NewTemplate(w, tmpl, render) }, nil
+	case "json-schema":
+		shape, err := jqresult.ParseRowShape(o.JqRowShape)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer {
+			return rowwriter.NewJSONSchema(w, render, shape, o.CompactOutput)
+		}, nil
+	case "codegen":
+		lang, err := codegen.ParseLanguage(o.CodegenLang)
+		if err != nil {
+			return nil, err
+		}
+		fields, err := o.paramFields()
+		if err != nil {
+			return nil, err
+		}
+		cgOpts := codegen.Options{Language: lang, Package: o.CodegenPackage, Name: o.CodegenName, Render: render}
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer { return rowwriter.NewCodegen(w, cgOpts, fields) }, nil
+	case "plan":
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer { return rowwriter.NewPlan(w) }, nil
+	case "plan-html":
+		return func(w io.Writer, header resultset.Header) rowwriter.Writer {
+			return rowwriter.NewPlanHTML(w, header.SQL, header.Params)
+		}, nil
+	case "folded":
+		value, err := rowwriter.ParseProfileValue(o.FoldedValue)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer { return rowwriter.NewFolded(w, value) }, nil
+	case "pprof":
+		return func(w io.Writer, header resultset.Header) rowwriter.Writer { return rowwriter.NewPprof(w, header.SQL) }, nil
+	case "plan-rows":
+		inner := o
+		inner.Format = o.PlanRowsFormat
+		newInner, err := inner.rowWriter(render)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, header resultset.Header) rowwriter.Writer {
+			return rowwriter.NewPlanRows(newInner(w, header), header.SQL)
+		}, nil
+	case "plan-diff":
+		_, base, err := loadResultSet("--plan-diff-base", o.PlanDiffBase)
+		if err != nil {
+			return nil, err
+		}
+		name := o.FromResultset
+		if name == "" {
+			name = "query"
+		}
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer {
+			return rowwriter.NewPlanDiff(w, base.GetStats(), o.PlanDiffBase, name)
+		}, nil
+	case "plan-check":
+		config, err := o.planCheckConfig()
+		if err != nil {
+			return nil, err
+		}
+		return planCheckWriter(config), nil
+	case "plan-dot", "plan-mermaid":
+		format := rowwriter.PlanGraphFormat(strings.TrimPrefix(o.Format, "plan-"))
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer { return rowwriter.NewPlanGraph(w, format) }, nil
+	case "gcloud":
+		// gcloud writes PROFILE rows and DML row counts to stderr.
+		return func(w io.Writer, _ resultset.Header) rowwriter.Writer { return rowwriter.NewGcloud(w, os.Stderr) }, nil
+	default:
+		return nil, nil
+	}
+}
+
+// renderOptions returns the typed value rendering selected by flags.
+func (o opts) renderOptions() (jqresult.RenderOptions, error) {
+	bytesEncoding, err := jqresult.ParseBytesEncoding(o.BytesEncoding)
+	if err != nil {
+		return jqresult.RenderOptions{}, err
+	}
+	intervalFormat, err := jqresult.ParseIntervalFormat(o.IntervalFormat)
+	if err != nil {
+		return jqresult.RenderOptions{}, err
+	}
+	render := jqresult.RenderOptions{
+		TypedNumbers: o.TypedNumbers,
+		DateLayout:   o.DateFormat,
+		ExpandJSON:   o.ExpandJSON,
+	}
+	// Defaults stay zero so that the protojson fast path is kept.
+	if bytesEncoding != jqresult.BytesBase64 {
+		render.Bytes = bytesEncoding
+	}
+	if intervalFormat != jqresult.IntervalISO8601 {
+		render.Interval = intervalFormat
+	}
+	if o.Timezone != "" {
+		loc, err := time.LoadLocation(o.Timezone)
+		if err != nil {
+			return jqresult.RenderOptions{}, fmt.Errorf("--timezone is supplied but wrong: %w", err)
+		}
+		render.Location = loc
+	}
+	return render, nil
+}
+
 // readFileOrDefault returns content of filename or s if filename is empty
 func readFileOrDefault(filename, s string) (string, error) {
 	if filename == "" {
@@ -216,20 +600,52 @@ func runInNewTransaction(ctx context.Context, client *spanner.Client, stmt spann
 		return resultset.Materialize(client.Single().WithTimestampBound(mode.TimestampBound).QueryWithOptions(ctx, stmt, opts), reductRows, statOpts...)
 	case partitionedDML:
 		count, err := client.PartitionedUpdateWithOptions(ctx, stmt, opts)
-		return &sppb.ResultSet{
-			Metadata: &sppb.ResultSetMetadata{
-				RowType: &sppb.StructType{},
-			},
-			Stats: &sppb.ResultSetStats{
-				RowCount: &sppb.ResultSetStats_RowCountLowerBound{RowCountLowerBound: count},
-			},
-		}, err
+		return partitionedDMLResultSet(count), err
 	default:
 		panic(fmt.Sprintf("unknown mode: %T", mode))
 	}
 }
 
-func _main() error {
+// runProfiles runs the query --profile-warmup times and then --profile-runs
+// times, and returns the result of the last run with the stats of the
+// --profile-runs runs aggregated by [queryplan.Aggregate].
+func runProfiles(ctx context.Context, client *spanner.Client, stmt spanner.Statement, opts spanner.QueryOptions, mode queryMode, o opts) (*sppb.ResultSet, error) {
+	var rs *sppb.ResultSet
+	var runs []*sppb.ResultSetStats
+	total := o.ProfileWarmup + o.ProfileRuns
+	for i := range total {
+		var err error
+		// Only the rows of the last run are kept.
+		rs, err = runInNewTransaction(ctx, client, stmt, opts, mode, o.RedactRows || i < total-1)
+		if err != nil {
+			return nil, fmt.Errorf("run %d of %d: %w", i+1, total, err)
+		}
+		if i >= o.ProfileWarmup {
+			runs = append(runs, rs.GetStats())
+		}
+	}
+	stats, err := queryplan.Aggregate(runs)
+	if err != nil {
+		return nil, err
+	}
+	rs.Stats = stats
+	return rs, nil
+}
+
+// partitionedDMLResultSet builds the result of a Partitioned DML statement, which has no rows
+// and only a lower bound of the modified row count.
+func partitionedDMLResultSet(count int64) *sppb.ResultSet {
+	return &sppb.ResultSet{
+		Metadata: &sppb.ResultSetMetadata{
+			RowType: &sppb.StructType{},
+		},
+		Stats: &sppb.ResultSetStats{
+			RowCount: &sppb.ResultSetStats_RowCountLowerBound{RowCountLowerBound: count},
+		},
+	}
+}
+
+func _main() (err error) {
 	o, err := processFlags()
 	if err != nil {
 		os.Exit(1)
@@ -259,13 +675,56 @@ func _main() error {
 		return err
 	}
 
+	render, err := o.renderOptions()
+	if err != nil {
+		return err
+	}
+
+	out := io.Writer(os.Stdout)
+	// --format=sqlite opens --output itself as a database.
+	if _, format := o.rowFormat(); o.Output != "" && format != "sqlite" {
+		f, oerr := createOutput(o.Output)
+		if oerr != nil {
+			return oerr
+		}
+		defer func() { err = f.commit(err) }()
+		out = f
+	}
+
+	newWriter, err := o.rowWriter(render)
+	if err != nil {
+		return err
+	}
+
+	if o.FromResultset != "" {
+		header, rs, err := loadResultSet("--from-resultset", o.FromResultset)
+		if err != nil {
+			return err
+		}
+		if o.SaveResultset != "" {
+			if err := o.saveResultSet(header, rs); err != nil {
+				return err
+			}
+		}
+		return writeResultSet(o, out, header, rs, newWriter, jqCode, render)
+	}
+
 	mode := sppb.ExecuteSqlRequest_QueryMode(sppb.ExecuteSqlRequest_QueryMode_value[o.QueryMode])
+	if o.Format == "json-schema" || o.Format == "codegen" {
+		// These need only the row and parameter types, which PLAN mode returns without reading rows.
+		mode = sppb.ExecuteSqlRequest_PLAN
+	}
+	if o.Format == "plan-check" && mode == sppb.ExecuteSqlRequest_NORMAL {
+		// Rules are checked on the plan; PROFILE adds the stats that some of them use.
+		mode = sppb.ExecuteSqlRequest_PLAN
+	}
 
 	query, err := readFileOrDefault(o.SqlFile, o.Sql)
 	if err != nil {
 		return err
 	}
 
+	var statementSpan trace.Span
 	ctx, tp, traceCancel, err := enableTracing(ctx, o)
 	if err != nil {
 		return err
@@ -279,6 +738,14 @@ func _main() error {
 				log.Printf("trace provider shutdown: %v", err)
 			}
 		}()
+
+		// In the trace file, the statement span parents the Spanner client
+		// spans and records when the first row is written. The other
+		// exporters keep the spans of the Spanner client as roots.
+		if o.TraceFile != "" {
+			ctx, statementSpan = tp.Tracer(traceServiceName).Start(ctx, "execspansql", trace.WithAttributes(attribute.String("db.statement", query)))
+			defer statementSpan.End()
+		}
 	}
 
 	logGrpc := o.LogGrpc
@@ -319,33 +786,226 @@ func _main() error {
 		m = single{tb}
 	}
 
+	if _, ok := m.(readWrite); ok && o.repeatsProfile() {
+		return fmt.Errorf("--profile-runs and --profile-warmup cannot repeat DML")
+	}
+
 	stmt := spanner.Statement{SQL: query, Params: paramMap}
 
 	if o.TryPartitionQuery {
-		bt, err := client.BatchReadOnlyTransaction(ctx, tb)
+		if err := partitionQuery(ctx, client, tb, stmt); err != nil {
+			return err
+		}
+
+		// Like the rows, the outcome goes to --output if given.
+		_, err := fmt.Fprintln(out, "success")
+		return err
+	}
+
+	if _, ok := m.(single); ok && o.Format == "plan-check" {
+		config, err := o.planCheckConfig()
 		if err != nil {
 			return err
 		}
-		defer bt.Close()
-		defer func() { bt.Cleanup(ctx) }()
+		if config.Has(plancheck.RootDistributedUnion) {
+			// Spanner decides whether the query can be partitioned; the
+			// root operator of the plan is only a heuristic for it.
+			perr := partitionQuery(ctx, client, tb, stmt)
+			if perr != nil && spanner.ErrCode(perr) != codes.InvalidArgument {
+				return perr
+			}
+			config.Partition = &plancheck.Partition{}
+			if perr != nil {
+				config.Partition.Err = errors.New(spanner.ErrDesc(perr))
+			}
+			newWriter = planCheckWriter(config)
+		}
+	}
 
-		_, err = bt.PartitionQuery(ctx, stmt, spanner.PartitionOptions{})
+	if statementSpan != nil {
+		if newWriter != nil {
+			newRowWriter := newWriter
+			newWriter = func(w io.Writer, header resultset.Header) rowwriter.Writer {
+				return &firstRowWriter{Writer: newRowWriter(w, header), span: statementSpan}
+			}
+		} else {
+			out = &firstOutputWriter{Writer: out, span: statementSpan}
+		}
+	}
+
+	header := resultset.Header{SQL: query, Params: paramStrMap, QueryMode: o.QueryMode}
+	// --format=sqlite writes the database itself rather than through the
+	// per-attempt buffer of runAndWriteRows, so DML results are written only
+	// after the transaction commits.
+	_, dml := m.(readWrite)
+	_, format := o.rowFormat()
+	if o.SaveResultset != "" || o.repeatsProfile() || (dml && format == "sqlite") {
+		var rs *sppb.ResultSet
+		if o.repeatsProfile() {
+			rs, err = runProfiles(ctx, client, stmt, o.queryOptions(mode), m, o)
+		} else {
+			rs, err = runInNewTransaction(ctx, client, stmt, o.queryOptions(mode), m, o.RedactRows)
+		}
 		if err != nil {
 			return err
 		}
+		if o.SaveResultset != "" {
+			if err := o.saveResultSet(header, rs); err != nil {
+				return err
+			}
+		}
+		return writeResultSet(o, out, header, rs, newWriter, jqCode, render)
+	}
 
-		fmt.Println("success")
-		return nil
+	switch {
+	case o.Format == "experimental_csv":
+		return runAndWriteCsv(ctx, client, stmt, o.queryOptions(mode), m, o.RedactRows, out)
+	case newWriter != nil:
+		return runAndWriteRows(ctx, client, stmt, o.queryOptions(mode), m, o.RedactRows, out, func(w io.Writer) rowwriter.Writer {
+			return newWriter(w, header)
+		})
+	}
+
+	return runJqOutput(ctx, client, stmt, o.queryOptions(mode), m, o, out, jqMode, jqCode, render)
+}
+
+// outputFile is a temporary file next to --output that replaces it when the
+// command succeeds, so that a failed query leaves an existing file as it was
+// and --output may name the --from-resultset file.
+type outputFile struct {
+	*os.File
+	name string
+}
+
+func createOutput(name string) (*outputFile, error) {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	// CreateTemp creates the file readable only by the owner.
+	if err := f.Chmod(0o644); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return nil, err
+	}
+	return &outputFile{File: f, name: name}, nil
+}
+
+// commit closes f and moves it to --output unless err, the error of the
+// command, is set. Plan check violations keep the output with the findings.
+func (f *outputFile) commit(err error) error {
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil && !errors.Is(err, plancheck.ErrViolations) {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if rerr := os.Rename(f.Name(), f.name); rerr != nil {
+		_ = os.Remove(f.Name())
+		return rerr
+	}
+	return err
+}
+
+// partitionQuery partitions stmt in a batch read-only transaction, which
+// fails if the query is not root partitionable.
+func partitionQuery(ctx context.Context, client *spanner.Client, tb spanner.TimestampBound, stmt spanner.Statement) error {
+	bt, err := client.BatchReadOnlyTransaction(ctx, tb)
+	if err != nil {
+		return err
+	}
+	defer bt.Close()
+	defer func() { bt.Cleanup(ctx) }()
+
+	_, err = bt.PartitionQuery(ctx, stmt, spanner.PartitionOptions{})
+	return err
+}
+
+// loadResultSet reads a --save-resultset file given by flag.
+func loadResultSet(flag, name string) (resultset.Header, *sppb.ResultSet, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return resultset.Header{}, nil, err
+	}
+	defer f.Close()
+	header, rs, err := resultset.Load(f)
+	if err != nil {
+		return resultset.Header{}, nil, fmt.Errorf("%s: %w", flag, err)
+	}
+	return header, rs, nil
+}
+
+// saveResultSet writes rs to --save-resultset. Like --output, the file is
+// replaced only when it has been written completely.
+func (o opts) saveResultSet(header resultset.Header, rs *sppb.ResultSet) (err error) {
+	f, err := createOutput(o.SaveResultset)
+	if err != nil {
+		return err
 	}
+	defer func() { err = f.commit(err) }()
+	return resultset.Save(f, resultset.FileFormat(o.SaveResultsetFormat), header, rs)
+}
 
-	if o.Format == "experimental_csv" {
-		return runAndWriteCsv(ctx, client, stmt, spanner.QueryOptions{Mode: &mode}, m, o.RedactRows)
+// writeResultSet writes a materialized ResultSet, saved or loaded, in --format.
+func writeResultSet(o opts, out io.Writer, header resultset.Header, rs *sppb.ResultSet, newWriter rowWriterFunc, jqCode *gojq.Code, render jqresult.RenderOptions) error {
+	if o.RedactRows {
+		rs.Rows = nil
+	}
+	switch {
+	case o.Format == "experimental_csv":
+		return writeCsvFromResultSet(out, rs)
+	case newWriter != nil:
+		return rowwriter.WriteResultSet(newWriter(out, header), rs)
+	default:
+		return writeJqResultSet(out, rs, o, jqCode, render)
 	}
+}
 
-	return runJqOutput(ctx, client, stmt, spanner.QueryOptions{Mode: &mode}, m, o, jqMode, jqCode)
+// runAndWriteRows streams query rows to the [rowwriter.Writer] returned by newWriter.
+// Read-write output is buffered per attempt so that a retried transaction does not emit rows twice.
+func runAndWriteRows(ctx context.Context, client *spanner.Client, stmt spanner.Statement, opts spanner.QueryOptions, mode queryMode, redactRows bool, out io.Writer, newWriter func(io.Writer) rowwriter.Writer) error {
+	statOpts := spaniterStatsOpts(mode, opts)
+	switch mode := mode.(type) {
+	case readWrite:
+		var buf bytes.Buffer
+		// A failed plan check is reported after the output, and does not
+		// roll the transaction back.
+		var checkErr error
+		_, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+			buf.Reset()
+			checkErr = rowwriter.WriteRowIterator(newWriter(&buf), tx.QueryWithOptions(ctx, stmt, opts), redactRows, statOpts...)
+			if errors.Is(checkErr, plancheck.ErrViolations) {
+				return nil
+			}
+			return checkErr
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, &buf); err != nil {
+			return err
+		}
+		return checkErr
+	case single:
+		return rowwriter.WriteRowIterator(
+			newWriter(out),
+			client.Single().WithTimestampBound(mode.TimestampBound).QueryWithOptions(ctx, stmt, opts),
+			redactRows,
+			statOpts...,
+		)
+	case partitionedDML:
+		count, err := client.PartitionedUpdateWithOptions(ctx, stmt, opts)
+		if err != nil {
+			return err
+		}
+		return rowwriter.WriteResultSet(newWriter(out), partitionedDMLResultSet(count))
+	default:
+		panic(fmt.Sprintf("unknown mode: %T", mode))
+	}
 }
 
-func runAndWriteCsv(ctx context.Context, client *spanner.Client, stmt spanner.Statement, opts spanner.QueryOptions, mode queryMode, redactRows bool) error {
+func runAndWriteCsv(ctx context.Context, client *spanner.Client, stmt spanner.Statement, opts spanner.QueryOptions, mode queryMode, redactRows bool, out io.Writer) error {
 	switch mode := mode.(type) {
 	case readWrite:
 		var buf bytes.Buffer
@@ -356,11 +1016,11 @@ func runAndWriteCsv(ctx context.Context, client *spanner.Client, stmt spanner.St
 		if err != nil {
 			return err
 		}
-		_, err = io.Copy(os.Stdout, &buf)
+		_, err = io.Copy(out, &buf)
 		return err
 	case single:
 		return writeCsvFromRowIter(
-			os.Stdout,
+			out,
 			client.Single().WithTimestampBound(mode.TimestampBound).QueryWithOptions(ctx, stmt, opts),
 			redactRows,
 		)
@@ -369,12 +1029,7 @@ func runAndWriteCsv(ctx context.Context, client *spanner.Client, stmt spanner.St
 		if err != nil {
 			return err
 		}
-		return writeCsvFromResultSet(os.Stdout, &sppb.ResultSet{
-			Metadata: &sppb.ResultSetMetadata{RowType: &sppb.StructType{}},
-			Stats: &sppb.ResultSetStats{
-				RowCount: &sppb.ResultSetStats_RowCountLowerBound{RowCountLowerBound: count},
-			},
-		})
+		return writeCsvFromResultSet(out, partitionedDMLResultSet(count))
 	default:
 		panic(fmt.Sprintf("unknown mode: %T", mode))
 	}
@@ -483,8 +1138,10 @@ func runJqOutput(
 	opts spanner.QueryOptions,
 	mode queryMode,
 	o opts,
+	out io.Writer,
 	jqMode jqresult.InputMode,
 	jqCode *gojq.Code,
+	render jqresult.RenderOptions,
 ) error {
 	useEager := jqMode == jqresult.InputEager
 	if _, ok := mode.(readWrite); ok {
@@ -495,29 +1152,19 @@ func runJqOutput(
 		if err != nil {
 			return err
 		}
-		enc, err := newEncoder(os.Stdout, o.Format, o.CompactOutput, o.JqRawOutput)
-		if err != nil {
-			return err
-		}
-		defer func() { _ = closeEncoder(enc) }()
-		iter, cleanup, err := jqresult.Execute(jqCode, jqresult.InputEager, nil, rs, o.RedactRows)
-		if err != nil {
-			return err
-		}
-		defer cleanup()
-		return jqresult.Print(enc, iter)
+		return writeJqResultSet(out, rs, o, jqCode, render)
 	}
 
 	switch mode := mode.(type) {
 	case readWrite:
 		panic("read-write jq uses eager materialization")
 	case single:
-		enc, err := newEncoder(os.Stdout, o.Format, o.CompactOutput, o.JqRawOutput)
+		enc, err := newEncoder(out, o.Format, o.CompactOutput, o.JqRawOutput, render.RendersRows(jqresult.RowShape(o.JqRowShape)))
 		if err != nil {
 			return err
 		}
 		rowIter := client.Single().WithTimestampBound(mode.TimestampBound).QueryWithOptions(ctx, stmt, opts)
-		return runJqOnRowIter(rowIter, o.RedactRows, jqCode, enc)
+		return runJqOnRowIter(rowIter, o.RedactRows, jqCode, enc, jqExecuteOptions(o, render)...)
 	case partitionedDML:
 		return fmt.Errorf("--jq-input-mode=lazy is not supported for partitioned DML")
 	default:
@@ -525,14 +1172,37 @@ func runJqOutput(
 	}
 }
 
+// writeJqResultSet prints the output of jqCode on a materialized ResultSet.
+func writeJqResultSet(out io.Writer, rs *sppb.ResultSet, o opts, jqCode *gojq.Code, render jqresult.RenderOptions) error {
+	enc, err := newEncoder(out, o.Format, o.CompactOutput, o.JqRawOutput, render.RendersRows(jqresult.RowShape(o.JqRowShape)))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = closeEncoder(enc) }()
+	iter, cleanup, err := jqresult.Execute(jqCode, jqresult.InputEager, nil, rs, o.RedactRows, jqExecuteOptions(o, render)...)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return jqresult.Print(enc, iter)
+}
+
+func jqExecuteOptions(o opts, render jqresult.RenderOptions) []jqresult.ExecuteOption {
+	return []jqresult.ExecuteOption{
+		jqresult.WithRowShape(jqresult.RowShape(o.JqRowShape)),
+		jqresult.WithRender(render),
+	}
+}
+
 func runJqOnRowIter(
 	rowIter *spanner.RowIterator,
 	redactRows bool,
 	jqCode *gojq.Code,
 	enc encoder,
+	opts ...jqresult.ExecuteOption,
 ) error {
 	defer func() { _ = closeEncoder(enc) }()
-	iter, cleanup, err := jqresult.Execute(jqCode, jqresult.InputLazy, rowIter, nil, redactRows)
+	iter, cleanup, err := jqresult.Execute(jqCode, jqresult.InputLazy, rowIter, nil, redactRows, opts...)
 	if err != nil {
 		return err
 	}
@@ -540,10 +1210,20 @@ func runJqOnRowIter(
 	return jqresult.Print(enc, iter)
 }
 
-func newEncoder(writer io.Writer, format string, compactOutput bool, rawOutput bool) (encoder, error) {
+// newEncoder returns the encoder for format. With plainNumbers, YAML writes
+// json.Number as a plain number instead of a quoted string. It is set when
+// rows are rendered (see [jqresult.RenderOptions.RendersRows]); the protojson
+// path keeps the quoted numbers --format=yaml has always printed.
+func newEncoder(writer io.Writer, format string, compactOutput bool, rawOutput bool, plainNumbers bool) (encoder, error) {
 	switch format {
 	case "yaml":
-		return yaml.NewEncoder(writer, yaml.Indent(4)), nil
+		yamlOpts := []yaml.EncodeOption{yaml.Indent(4)}
+		if plainNumbers {
+			yamlOpts = append(yamlOpts, yaml.CustomMarshaler(func(n json.Number) ([]byte, error) {
+				return []byte(n), nil
+			}))
+		}
+		return yaml.NewEncoder(writer, yamlOpts...), nil
 	case "json":
 		jsonenc := json.NewEncoder(writer)
 		jsonenc.SetEscapeHTML(false)
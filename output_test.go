@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apstndb/execspansql/plancheck"
+)
+
+func TestOutputFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"success", nil, "new"},
+		{"failure", errors.New("query failed"), "old"},
+		{"plan check violations", fmt.Errorf("%w: 1 of 1 findings are errors", plancheck.ErrViolations), "new"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			name := filepath.Join(dir, "out.txt")
+			if err := os.WriteFile(name, []byte("old"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := createOutput(name)
+			if err != nil {
+				t.Fatalf("createOutput() error = %v", err)
+			}
+			if _, err := f.WriteString("new"); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.commit(tc.err); !errors.Is(err, tc.err) {
+				t.Errorf("commit() error = %v, want %v", err, tc.err)
+			}
+
+			got, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("--output has %q, want %q", got, tc.want)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("%d files left in the directory, want only out.txt", len(entries))
+			}
+		})
+	}
+}
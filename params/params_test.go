@@ -5,6 +5,8 @@ import (
 
 	"cloud.google.com/go/spanner"
 	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/valuefmt"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
@@ -45,3 +47,73 @@ func TestGenerateParamsPermitType(t *testing.T) {
 		t.Fatalf("expected typed null value, got %v", v.Value)
 	}
 }
+
+// TestGenerateParamsLiteralRoundTrip checks that valuefmt.Literal, which
+// --format=sql-insert uses, parses back to the same typed value.
+func TestGenerateParamsLiteralRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	typ := func(code sppb.TypeCode) *sppb.Type { return &sppb.Type{Code: code} }
+	tests := map[string]spanner.GenericColumnValue{
+		"int64":  {Type: typ(sppb.TypeCode_INT64), Value: structpb.NewStringValue("-42")},
+		"bool":   {Type: typ(sppb.TypeCode_BOOL), Value: structpb.NewBoolValue(true)},
+		"string": {Type: typ(sppb.TypeCode_STRING), Value: structpb.NewStringValue("it's \"x\"\n")},
+		"bytes":  {Type: typ(sppb.TypeCode_BYTES), Value: structpb.NewStringValue("AAH/")},
+		"date":   {Type: typ(sppb.TypeCode_DATE), Value: structpb.NewStringValue("2024-06-01")},
+		"null":   {Type: typ(sppb.TypeCode_INT64), Value: structpb.NewNullValue()},
+		"array": {
+			Type: &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: typ(sppb.TypeCode_STRING)},
+			Value: structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{
+				structpb.NewStringValue("a"), structpb.NewNullValue(),
+			}}),
+		},
+		"numeric":          {Type: typ(sppb.TypeCode_NUMERIC), Value: structpb.NewStringValue("-123.456789")},
+		"json":             {Type: typ(sppb.TypeCode_JSON), Value: structpb.NewStringValue(`{"a":[1,"it's"]}`)},
+		"timestamp":        {Type: typ(sppb.TypeCode_TIMESTAMP), Value: structpb.NewStringValue("2024-06-01T12:34:56.123456789Z")},
+		"float64":          {Type: typ(sppb.TypeCode_FLOAT64), Value: structpb.NewNumberValue(2)},
+		"float64 fraction": {Type: typ(sppb.TypeCode_FLOAT64), Value: structpb.NewNumberValue(0.1)},
+		"float64 nan":      {Type: typ(sppb.TypeCode_FLOAT64), Value: structpb.NewStringValue("NaN")},
+		"float64 inf":      {Type: typ(sppb.TypeCode_FLOAT64), Value: structpb.NewStringValue("Infinity")},
+		"float64 -inf":     {Type: typ(sppb.TypeCode_FLOAT64), Value: structpb.NewStringValue("-Infinity")},
+		"float32":          {Type: typ(sppb.TypeCode_FLOAT32), Value: structpb.NewNumberValue(1.5)},
+		"uuid":             {Type: typ(sppb.TypeCode_UUID), Value: structpb.NewStringValue("550e8400-e29b-41d4-a716-446655440000")},
+		"interval":         {Type: typ(sppb.TypeCode_INTERVAL), Value: structpb.NewStringValue("P1Y2M3DT4H5M6.5S")},
+		"enum": {
+			Type:  &sppb.Type{Code: sppb.TypeCode_ENUM, ProtoTypeFqn: "examples.music.Genre"},
+			Value: structpb.NewStringValue("2"),
+		},
+		"struct": {
+			Type: &sppb.Type{Code: sppb.TypeCode_STRUCT, StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+				{Name: "id", Type: typ(sppb.TypeCode_INT64)},
+				{Name: "tags", Type: &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: typ(sppb.TypeCode_STRING)}},
+				{Name: "ts", Type: typ(sppb.TypeCode_TIMESTAMP)},
+			}}},
+			Value: structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{
+				structpb.NewStringValue("1"),
+				structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("x")}}),
+				structpb.NewNullValue(),
+			}}),
+		},
+	}
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lit, err := valuefmt.Literal(want.Type, want.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := GenerateParams(map[string]string{"p": lit}, false)
+			if err != nil {
+				t.Fatalf("GenerateParams(%s) failed: %v", lit, err)
+			}
+			v, ok := got["p"].(spanner.GenericColumnValue)
+			if !ok {
+				t.Fatalf("expected spanner.GenericColumnValue, got %T", got["p"])
+			}
+			if !proto.Equal(v.Type, want.Type) || !proto.Equal(v.Value, want.Value) {
+				t.Fatalf("GenerateParams(%s) = %v %v, want %v %v", lit, v.Type, v.Value, want.Type, want.Value)
+			}
+		})
+	}
+}
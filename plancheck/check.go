@@ -0,0 +1,170 @@
+package plancheck
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/queryplan"
+)
+
+// ErrViolations is returned, wrapped, when a check has error findings.
+var ErrViolations = errors.New("plan check failed")
+
+// Finding is an operator that breaks a rule.
+type Finding struct {
+	Rule     Rule     `json:"rule"`
+	Severity Severity `json:"severity"`
+	// Node is the index of the operator in the plan.
+	Node     int32  `json:"node"`
+	Operator string `json:"operator"`
+	Message  string `json:"message"`
+}
+
+// Check evaluates the rules of c on plan and returns the findings in the
+// order of the rules, and of [queryplan.Plan.Walk] for each rule.
+func Check(plan *queryplan.Plan, c Config) []Finding {
+	walk := plan.Walk()
+	if len(walk) == 0 {
+		return nil
+	}
+	var findings []Finding
+	for _, rc := range c.Rules {
+		report := func(n *sppb.PlanNode, format string, args ...any) {
+			findings = append(findings, Finding{
+				Rule:     rc.Rule,
+				Severity: rc.Severity,
+				Node:     n.GetIndex(),
+				Operator: queryplan.Operator(n),
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+		if rc.Rule == RootDistributedUnion {
+			root := walk[0].Node
+			switch {
+			case c.Partition != nil:
+				if c.Partition.Err != nil {
+					report(root, "the query is not root partitionable: %v", c.Partition.Err)
+				}
+			case root.GetDisplayName() != "Distributed Union":
+				report(root, "the root operator is not a Distributed Union, so the query is likely not root partitionable")
+			}
+			continue
+		}
+		for _, r := range walk {
+			n := r.Node
+			switch rc.Rule {
+			case FullScan:
+				if isFullScan(n) && (len(rc.Tables) == 0 || slices.ContainsFunc(rc.Tables, func(t string) bool {
+					return strings.EqualFold(t, queryplan.ScanTarget(n))
+				})) {
+					report(n, "full scan of %s", queryplan.ScanTarget(n))
+				}
+			case MissingIndex:
+				if !isFullScan(n) {
+					continue
+				}
+				filters := []*sppb.PlanNode{n}
+				if parent := plan.Node(r.Parent); isFilter(parent) {
+					filters = append(filters, parent)
+				}
+				for _, f := range filters {
+					for _, pred := range filterPredicates(plan, f) {
+						report(n, "full scan of %s filtered by %s; an index on the filtered columns could seek instead", queryplan.ScanTarget(n), pred.Description)
+					}
+				}
+			case CrossApplyFanout:
+				if n.GetDisplayName() != "Distributed Cross Apply" {
+					continue
+				}
+				execs, ok := mapExecutions(plan, n)
+				switch {
+				case !ok || rc.MaxExecutions == 0:
+					report(n, "the map side is called remotely for each batch of input rows")
+				case execs > rc.MaxExecutions:
+					report(n, "the map side ran %d times, more than %d", execs, rc.MaxExecutions)
+				}
+			case ResidualFilter:
+				for _, pred := range plan.Predicates(n) {
+					if pred.Type == "Residual Condition" {
+						report(n, "residual condition %s is evaluated on every row read", pred.Description)
+					}
+				}
+			case BackJoin:
+				if !strings.HasSuffix(n.GetDisplayName(), "Cross Apply") {
+					continue
+				}
+				index := findScan(plan, childOfType(plan, n, "Input"), "IndexScan")
+				table := findScan(plan, childOfType(plan, n, "Map"), "TableScan")
+				if index != nil && table != nil {
+					report(n, "rows of %s are looked up for the keys found in index %s; storing the needed columns in the index would avoid the back join",
+						queryplan.ScanTarget(table), queryplan.ScanTarget(index))
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// HasErrors reports whether findings contain an error.
+func HasErrors(findings []Finding) bool {
+	return slices.ContainsFunc(findings, func(f Finding) bool { return f.Severity == Error })
+}
+
+func isFullScan(n *sppb.PlanNode) bool {
+	return n.GetMetadata().GetFields()["Full scan"].GetStringValue() == "true"
+}
+
+func isFilter(n *sppb.PlanNode) bool {
+	return n.GetDisplayName() == "Filter Scan" || n.GetDisplayName() == "Filter"
+}
+
+// filterPredicates returns the predicates of n that filter rows rather than
+// select the ranges to read.
+func filterPredicates(plan *queryplan.Plan, n *sppb.PlanNode) []queryplan.Predicate {
+	return slices.DeleteFunc(plan.Predicates(n), func(p queryplan.Predicate) bool {
+		return p.Type == "Seek Condition" || p.Type == "Split Range"
+	})
+}
+
+// childOfType returns the child of n linked as linkType, or nil.
+func childOfType(plan *queryplan.Plan, n *sppb.PlanNode, linkType string) *sppb.PlanNode {
+	for _, link := range n.GetChildLinks() {
+		if link.GetType() == linkType {
+			return plan.Node(link.GetChildIndex())
+		}
+	}
+	return nil
+}
+
+// findScan returns the first scan of scanType in the subtree of n, or nil.
+func findScan(plan *queryplan.Plan, n *sppb.PlanNode, scanType string) *sppb.PlanNode {
+	visited := make(map[int32]bool)
+	var find func(n *sppb.PlanNode) *sppb.PlanNode
+	find = func(n *sppb.PlanNode) *sppb.PlanNode {
+		if n == nil || visited[n.GetIndex()] {
+			return nil
+		}
+		visited[n.GetIndex()] = true
+		if n.GetMetadata().GetFields()["scan_type"].GetStringValue() == scanType {
+			return n
+		}
+		for _, link := range plan.Children(n) {
+			if scan := find(plan.Node(link.GetChildIndex())); scan != nil {
+				return scan
+			}
+		}
+		return nil
+	}
+	return find(n)
+}
+
+// mapExecutions returns the number of executions of the map side of a
+// Distributed Cross Apply, which PROFILE mode reports.
+func mapExecutions(plan *queryplan.Plan, n *sppb.PlanNode) (int, bool) {
+	execs, err := strconv.Atoi(queryplan.Executions(childOfType(plan, n, "Map")))
+	return execs, err == nil
+}
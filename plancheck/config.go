@@ -0,0 +1,153 @@
+package plancheck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Rule names a check of [Check].
+type Rule string
+
+const (
+	// FullScan reports scans that read a whole table or index.
+	FullScan Rule = "full_scan"
+	// MissingIndex reports full scans whose rows are filtered by a
+	// predicate, which an index on the filtered columns could seek instead.
+	MissingIndex Rule = "missing_index"
+	// CrossApplyFanout reports Distributed Cross Apply operators, which call
+	// the remote servers of their map side for batches of input rows.
+	CrossApplyFanout Rule = "cross_apply_fanout"
+	// ResidualFilter reports predicates evaluated on every row that a scan
+	// reads rather than used to seek.
+	ResidualFilter Rule = "residual_filter"
+	// BackJoin reports cross applies that look up rows of a table for the
+	// keys found by an index scan, because the index does not store the
+	// columns that the query needs.
+	BackJoin Rule = "back_join"
+	// RootDistributedUnion reports queries that are not root partitionable.
+	// With [Config.Partition] it reports the error of partitioning the
+	// query, as --try-partition-query does; without it, such as for saved
+	// results, it reports plans whose root is not a Distributed Union, which
+	// is only a heuristic.
+	RootDistributedUnion Rule = "root_distributed_union"
+)
+
+// Rules are all rules in the order that [Check] evaluates them.
+var Rules = []Rule{FullScan, MissingIndex, CrossApplyFanout, ResidualFilter, BackJoin, RootDistributedUnion}
+
+// Severity is how a finding of a rule is treated.
+type Severity string
+
+const (
+	// Error findings fail the check.
+	Error Severity = "error"
+	// Warning findings are reported only.
+	Warning Severity = "warning"
+)
+
+// RuleConfig enables a rule.
+type RuleConfig struct {
+	Rule Rule `yaml:"rule"`
+	// Severity is Error if empty.
+	Severity Severity `yaml:"severity,omitempty"`
+	// Tables restricts FullScan to scans of these tables and indexes,
+	// compared case-insensitively; all scans are checked if it is empty.
+	Tables []string `yaml:"tables,omitempty"`
+	// MaxExecutions lets CrossApplyFanout pass a Distributed Cross Apply
+	// whose map side ran at most this many times. It needs PROFILE stats;
+	// without them, or if it is 0, every Distributed Cross Apply is reported.
+	MaxExecutions int `yaml:"max_executions,omitempty"`
+}
+
+// Config lists the rules of a check, such as
+//
+//	rules:
+//	  - rule: full_scan
+//	    tables: [Singers, Albums]
+//	  - rule: residual_filter
+//	    severity: warning
+type Config struct {
+	Rules []RuleConfig `yaml:"rules"`
+	// Partition is the outcome of partitioning the query, set when the plan
+	// comes from a database.
+	Partition *Partition `yaml:"-"`
+}
+
+// Partition is the outcome of partitioning a query.
+type Partition struct {
+	// Err is why the query cannot be partitioned, or nil if it can.
+	Err error
+}
+
+// Has reports whether c enables rule.
+func (c Config) Has(rule Rule) bool {
+	return slices.ContainsFunc(c.Rules, func(rc RuleConfig) bool { return rc.Rule == rule })
+}
+
+// DefaultConfig enables every rule with its defaults. Only MissingIndex is
+// an error: the other rules also report plans that suit many workloads,
+// such as scans of small tables and interleaved joins, so they are warnings
+// until a config makes them errors.
+func DefaultConfig() Config {
+	var c Config
+	for _, r := range Rules {
+		severity := Warning
+		if r == MissingIndex {
+			severity = Error
+		}
+		c.Rules = append(c.Rules, RuleConfig{Rule: r, Severity: severity})
+	}
+	return c
+}
+
+// ParseConfig parses a YAML or JSON config. Unknown rules and fields are
+// errors, so that a typo does not disable a rule.
+func ParseConfig(b []byte) (Config, error) {
+	var c Config
+	if len(bytes.TrimSpace(b)) == 0 {
+		return c, errors.New("no rules")
+	}
+	if err := yaml.UnmarshalWithOptions(b, &c, yaml.DisallowUnknownField()); err != nil {
+		return c, err
+	}
+	if len(c.Rules) == 0 {
+		return c, errors.New("no rules")
+	}
+	for i, rc := range c.Rules {
+		if !slices.Contains(Rules, rc.Rule) {
+			return c, fmt.Errorf("rules[%d]: unknown rule %q", i, rc.Rule)
+		}
+		switch rc.Severity {
+		case "":
+			c.Rules[i].Severity = Error
+		case Error, Warning:
+		default:
+			return c, fmt.Errorf("rules[%d]: severity must be error or warning", i)
+		}
+		if len(rc.Tables) > 0 && rc.Rule != FullScan {
+			return c, fmt.Errorf("rules[%d]: tables is only supported by %s", i, FullScan)
+		}
+		if rc.MaxExecutions != 0 && rc.Rule != CrossApplyFanout {
+			return c, fmt.Errorf("rules[%d]: max_executions is only supported by %s", i, CrossApplyFanout)
+		}
+	}
+	return c, nil
+}
+
+// LoadConfig reads a config file with [ParseConfig].
+func LoadConfig(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	c, err := ParseConfig(b)
+	if err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,8 @@
+// Package plancheck lints query plans.
+//
+// A [Config], usually loaded from YAML by [LoadConfig], lists the rules to
+// apply; [Check] evaluates them on a PLAN or PROFILE plan and returns a
+// [Finding] for each operator that breaks one. The rules look for operators
+// that do not scale with the data, such as full scans and residual filters,
+// so that they can be caught in CI before a query reaches production.
+package plancheck
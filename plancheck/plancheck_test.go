@@ -0,0 +1,210 @@
+package plancheck
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/queryplan"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func mustStruct(t *testing.T, m map[string]any) *structpb.Struct {
+	t.Helper()
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// fixturePlan is
+//
+//	0 Global Limit
+//	  1 Distributed Cross Apply
+//	    2 Index Scan of SingersByName (Input, full scan)
+//	    4 Filter Scan (Map, Residual Condition: 3), run 25 times
+//	      5 Table Scan of Singers (full scan)
+func fixturePlan(t *testing.T) *queryplan.Plan {
+	t.Helper()
+	scan := func(typ, target string) *structpb.Struct {
+		return mustStruct(t, map[string]any{"scan_type": typ, "scan_target": target, "Full scan": "true"})
+	}
+	return queryplan.New([]*sppb.PlanNode{
+		{
+			Index: 0, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Limit",
+			ChildLinks: []*sppb.PlanNode_ChildLink{{ChildIndex: 1}},
+			Metadata:   mustStruct(t, map[string]any{"call_type": "Global"}),
+		},
+		{
+			Index: 1, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Distributed Cross Apply",
+			ChildLinks: []*sppb.PlanNode_ChildLink{{ChildIndex: 2, Type: "Input"}, {ChildIndex: 4, Type: "Map"}},
+		},
+		{Index: 2, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Scan", Metadata: scan("IndexScan", "SingersByName")},
+		{Index: 3, Kind: sppb.PlanNode_SCALAR, DisplayName: "Function", ShortRepresentation: &sppb.PlanNode_ShortRepresentation{Description: "($FirstName = 'Marc')"}},
+		{
+			Index: 4, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Filter Scan",
+			ChildLinks:     []*sppb.PlanNode_ChildLink{{ChildIndex: 5}, {ChildIndex: 3, Type: "Residual Condition"}},
+			ExecutionStats: mustStruct(t, map[string]any{"execution_summary": map[string]any{"num_executions": "25"}}),
+		},
+		{Index: 5, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Scan", Metadata: scan("TableScan", "Singers")},
+	})
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	c, err := ParseConfig([]byte(`
+rules:
+  - rule: full_scan
+    tables: [singersbyname]
+  - rule: missing_index
+  - rule: cross_apply_fanout
+    max_executions: 10
+  - rule: residual_filter
+    severity: warning
+  - rule: back_join
+  - rule: root_distributed_union
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Check(fixturePlan(t), c)
+	want := []Finding{
+		{FullScan, Error, 2, "Index Scan", "full scan of SingersByName"},
+		{MissingIndex, Error, 5, "Table Scan", "full scan of Singers filtered by ($FirstName = 'Marc'); an index on the filtered columns could seek instead"},
+		{CrossApplyFanout, Error, 1, "Distributed Cross Apply", "the map side ran 25 times, more than 10"},
+		{ResidualFilter, Warning, 4, "Filter Scan", "residual condition ($FirstName = 'Marc') is evaluated on every row read"},
+		{BackJoin, Error, 1, "Distributed Cross Apply", "rows of Singers are looked up for the keys found in index SingersByName; storing the needed columns in the index would avoid the back join"},
+		{RootDistributedUnion, Error, 0, "Global Limit", "the root operator is not a Distributed Union, so the query is likely not root partitionable"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Check() mismatch (-want +got):\n%s", diff)
+	}
+	if !HasErrors(got) {
+		t.Errorf("HasErrors() = false, want true")
+	}
+}
+
+func TestCheckFanout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		maxExecutions int
+		want          int
+	}{
+		{"unlimited", 0, 1},
+		{"below", 25, 0},
+		{"above", 24, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := Config{Rules: []RuleConfig{{Rule: CrossApplyFanout, Severity: Warning, MaxExecutions: tt.maxExecutions}}}
+			got := Check(fixturePlan(t), c)
+			if len(got) != tt.want {
+				t.Errorf("Check() = %v, want %d findings", got, tt.want)
+			}
+			if HasErrors(got) {
+				t.Errorf("HasErrors() = true for warnings")
+			}
+		})
+	}
+}
+
+func TestCheckPartition(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		partition *Partition
+		want      []Finding
+	}{
+		{
+			name: "heuristic",
+			want: []Finding{{RootDistributedUnion, Error, 0, "Global Limit", "the root operator is not a Distributed Union, so the query is likely not root partitionable"}},
+		},
+		{
+			name:      "partitioned",
+			partition: &Partition{},
+		},
+		{
+			name:      "not partitionable",
+			partition: &Partition{Err: errors.New("Query is not root partitionable")},
+			want:      []Finding{{RootDistributedUnion, Error, 0, "Global Limit", "the query is not root partitionable: Query is not root partitionable"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := Config{Rules: []RuleConfig{{Rule: RootDistributedUnion, Severity: Error}}, Partition: tt.partition}
+			if diff := cmp.Diff(tt.want, Check(fixturePlan(t), c)); diff != "" {
+				t.Errorf("Check() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"json", `{"rules": [{"rule": "back_join"}]}`, ""},
+		{"empty", ``, "no rules"},
+		{"unknown rule", "rules:\n  - rule: full_scans\n", `unknown rule "full_scans"`},
+		{"unknown field", "rules:\n  - rule: full_scan\n    table: [Singers]\n", "unknown field"},
+		{"severity", "rules:\n  - rule: full_scan\n    severity: fatal\n", "severity must be error or warning"},
+		{"tables", "rules:\n  - rule: back_join\n    tables: [Singers]\n", "tables is only supported by full_scan"},
+		{"max executions", "rules:\n  - rule: full_scan\n    max_executions: 1\n", "max_executions is only supported by cross_apply_fanout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := ParseConfig([]byte(tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseConfig() error = %v", err)
+				}
+				if c.Rules[0].Severity != Error {
+					t.Errorf("Severity = %q, want %q", c.Rules[0].Severity, Error)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	t.Parallel()
+
+	// The fixture breaks every rule.
+	seen := make(map[Rule]bool)
+	for _, f := range Check(fixturePlan(t), DefaultConfig()) {
+		seen[f.Rule] = true
+		want := Warning
+		if f.Rule == MissingIndex {
+			want = Error
+		}
+		if f.Severity != want {
+			t.Errorf("%s finding has severity %s, want %s", f.Rule, f.Severity, want)
+		}
+	}
+	for _, r := range Rules {
+		if !seen[r] {
+			t.Errorf("no finding of %s", r)
+		}
+	}
+}
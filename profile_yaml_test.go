@@ -7,6 +7,8 @@ import (
 
 	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
 	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/apstndb/execspansql/jqresult"
 )
 
 func loadProfileJSONFixture(path string) (*sppb.ResultSet, error) {
@@ -80,7 +82,7 @@ func TestProfileJSONToYamlGolden(t *testing.T) {
 				t.Fatal("missing query plan or query stats")
 			}
 
-			got, err := encodeResultSetYAML(tc.filter, rs)
+			got, err := encodeResultSetYAML(tc.filter, rs, jqresult.RenderOptions{})
 			if err != nil {
 				t.Fatalf("encodeResultSetYAML() error = %v", err)
 			}
@@ -0,0 +1,180 @@
+package queryplan
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// AggregatedQueryStats are the query stats that [Aggregate] summarizes.
+var AggregatedQueryStats = []string{"elapsed_time", "cpu_time", "rows_scanned"}
+
+// Aggregate combines the stats of PROFILE runs of the same query, which must
+// have plans of the same shape: [Diff] must find no differences between the
+// plan of the last run and the others.
+//
+// The result is the stats of the last run in which every execution stat of a
+// plan node, such as {"total": "7.2", "unit": "msecs"}, has the median total,
+// mean and std_deviation of all runs, and also the min, median, p95 and max of
+// the totals. Of the query stats, those in [AggregatedQueryStats] are the
+// medians, and <name>_min, <name>_median, <name>_p95 and <name>_max are added
+// along with profile_runs. Totals in other units of time are converted to the
+// unit of the last run.
+func Aggregate(runs []*sppb.ResultSetStats) (*sppb.ResultSetStats, error) {
+	if len(runs) == 0 {
+		return nil, errors.New("no runs to aggregate")
+	}
+	last := runs[len(runs)-1]
+	plan := New(last.GetQueryPlan().GetPlanNodes())
+	if len(plan.nodes) == 0 {
+		return nil, errors.New("the runs have no query plan")
+	}
+
+	// samples[i][name] are the stats named name of node i in each run.
+	samples := make(map[int32]map[string][]Stat)
+	for i, run := range runs {
+		for _, r := range Diff(New(run.GetQueryPlan().GetPlanNodes()), plan) {
+			if r.Change != Same {
+				return nil, fmt.Errorf("the plan of run %d differs from run %d: %s", i+1, len(runs), describeChange(r))
+			}
+			idx := r.Node.GetIndex()
+			if samples[idx] == nil {
+				samples[idx] = make(map[string][]Stat)
+			}
+			for _, name := range StatNames(r.Base) {
+				samples[idx][name] = append(samples[idx][name], ExecutionStat(r.Base, name))
+			}
+		}
+	}
+
+	agg := proto.Clone(last).(*sppb.ResultSetStats)
+	for _, n := range agg.GetQueryPlan().GetPlanNodes() {
+		for name, stats := range samples[n.GetIndex()] {
+			s := n.GetExecutionStats().GetFields()[name].GetStructValue()
+			if s == nil || len(stats) != len(runs) {
+				continue
+			}
+			unit := stats[len(stats)-1].Unit
+			var totals, means, stdDevs []float64
+			for _, stat := range stats {
+				totals = append(totals, statValue(stat.Total, stat.Unit, unit))
+				means = append(means, statValue(stat.Mean, stat.Unit, unit))
+				stdDevs = append(stdDevs, statValue(stat.StdDev, stat.Unit, unit))
+			}
+			if slices.ContainsFunc(totals, math.IsNaN) {
+				continue
+			}
+			sum := summarize(totals)
+			for key, v := range map[string]float64{
+				"total": sum.median, "min": sum.min, "median": sum.median, "p95": sum.p95, "max": sum.max,
+			} {
+				s.Fields[key] = structpb.NewStringValue(formatStat(v))
+			}
+			for key, values := range map[string][]float64{"mean": means, "std_deviation": stdDevs} {
+				if _, ok := s.Fields[key]; ok && !slices.ContainsFunc(values, math.IsNaN) {
+					s.Fields[key] = structpb.NewStringValue(formatStat(summarize(values).median))
+				}
+			}
+		}
+	}
+
+	if agg.QueryStats == nil {
+		agg.QueryStats = &structpb.Struct{}
+	}
+	if agg.QueryStats.Fields == nil {
+		agg.QueryStats.Fields = make(map[string]*structpb.Value)
+	}
+	fields := agg.QueryStats.Fields
+	for _, name := range AggregatedQueryStats {
+		lastTotal, unit, _ := strings.Cut(fields[name].GetStringValue(), " ")
+		if lastTotal == "" {
+			continue
+		}
+		var values []float64
+		for _, run := range runs {
+			total, u, _ := strings.Cut(run.GetQueryStats().GetFields()[name].GetStringValue(), " ")
+			values = append(values, statValue(total, u, unit))
+		}
+		if slices.ContainsFunc(values, math.IsNaN) {
+			continue
+		}
+		withUnit := func(v float64) *structpb.Value {
+			return structpb.NewStringValue(strings.TrimSpace(formatStat(v) + " " + unit))
+		}
+		sum := summarize(values)
+		fields[name] = withUnit(sum.median)
+		fields[name+"_min"] = withUnit(sum.min)
+		fields[name+"_median"] = withUnit(sum.median)
+		fields[name+"_p95"] = withUnit(sum.p95)
+		fields[name+"_max"] = withUnit(sum.max)
+	}
+	fields["profile_runs"] = structpb.NewStringValue(strconv.Itoa(len(runs)))
+	return agg, nil
+}
+
+func describeChange(r DiffRow) string {
+	switch r.Change {
+	case Added:
+		return Operator(r.Node) + " was added"
+	case Removed:
+		return Operator(r.Base) + " was removed"
+	default:
+		return fmt.Sprintf("node %d changed: %s", r.Node.GetIndex(), strings.Join(r.Details, ", "))
+	}
+}
+
+// statValue parses total, in unit from, as a number in unit to. It returns
+// NaN if total is not a number or the units differ and are not both units of
+// time.
+func statValue(total, from, to string) float64 {
+	f, err := strconv.ParseFloat(total, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	if from == to {
+		return f
+	}
+	fromUnit, ok := timeUnit(from)
+	if !ok {
+		return math.NaN()
+	}
+	toUnit, ok := timeUnit(to)
+	if !ok {
+		return math.NaN()
+	}
+	return f * float64(fromUnit) / float64(toUnit)
+}
+
+type summary struct {
+	min, median, p95, max float64
+}
+
+// summarize returns the min, median, nearest-rank 95th percentile and max of
+// values, which must not be empty.
+func summarize(values []float64) summary {
+	sorted := slices.Sorted(slices.Values(values))
+	n := len(sorted)
+	median := sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return summary{
+		min:    sorted[0],
+		median: median,
+		p95:    sorted[int(math.Ceil(0.95*float64(n)))-1],
+		max:    sorted[n-1],
+	}
+}
+
+// formatStat formats an aggregated stat without the rounding errors of
+// floating point arithmetic.
+func formatStat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
@@ -0,0 +1,244 @@
+package queryplan
+
+import (
+	"slices"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+// Change is how a node of a [Diff] differs between the two plans.
+type Change string
+
+const (
+	Same    Change = ""
+	Added   Change = "added"
+	Removed Change = "removed"
+	Changed Change = "changed"
+)
+
+// DiffRow is a node of the tree merged by [Diff].
+type DiffRow struct {
+	// Base is the node in the base plan and Node the node in the other plan.
+	// Base is nil for an added node and Node is nil for a removed one.
+	Base, Node *sppb.PlanNode
+	// Depth, LinkType and Last are as in [Row].
+	Depth    int
+	LinkType string
+	Last     bool
+	Change   Change
+	// Details describes the differences of a changed node, such as
+	// "scan_target: Singers -> SingersByFirstLastName".
+	Details []string
+}
+
+// Diff merges the trees that [Plan.Walk] visits in base and p into one,
+// in depth-first order.
+//
+// Nodes are matched from the roots down. The children of two matched nodes
+// are aligned by the longest common subsequence of their link types and
+// display names; the others are added or removed with their subtrees, except
+// that a single child replaced by another is matched so that the subtrees
+// below are still compared. Matched nodes are compared by [Operator],
+// metadata and predicates. Execution stats are not compared.
+func Diff(base, p *Plan) []DiffRow {
+	d := &differ{
+		base:        base,
+		plan:        p,
+		visitedBase: make(map[int32]bool),
+		visited:     make(map[int32]bool),
+	}
+	switch {
+	case len(base.nodes) == 0 && len(p.nodes) == 0:
+	case len(base.nodes) == 0:
+		d.subtree(Added, 0, "", 0, true)
+	case len(p.nodes) == 0:
+		d.subtree(Removed, 0, "", 0, true)
+	default:
+		d.match(0, 0, "", 0, true)
+	}
+	return d.rows
+}
+
+type differ struct {
+	base, plan           *Plan
+	visitedBase, visited map[int32]bool
+	rows                 []DiffRow
+}
+
+// linkPair is an aligned child of two matched nodes; either side is nil
+// when the child is only in one plan.
+type linkPair struct {
+	base, link *sppb.PlanNode_ChildLink
+}
+
+func (d *differ) match(baseIdx, idx int32, linkType string, depth int, last bool) {
+	d.visitedBase[baseIdx], d.visited[idx] = true, true
+	b, n := d.base.nodes[baseIdx], d.plan.nodes[idx]
+	row := DiffRow{Base: b, Node: n, Depth: depth, LinkType: linkType, Last: last, Details: d.compare(b, n)}
+	if len(row.Details) > 0 {
+		row.Change = Changed
+	}
+	d.rows = append(d.rows, row)
+
+	pairs := d.align(d.base.treeLinks(b, d.visitedBase), d.plan.treeLinks(n, d.visited))
+	for i, pair := range pairs {
+		last := i == len(pairs)-1
+		switch {
+		case pair.base == nil:
+			d.subtree(Added, pair.link.GetChildIndex(), pair.link.GetType(), depth+1, last)
+		case pair.link == nil:
+			d.subtree(Removed, pair.base.GetChildIndex(), pair.base.GetType(), depth+1, last)
+		default:
+			d.match(pair.base.GetChildIndex(), pair.link.GetChildIndex(), pair.link.GetType(), depth+1, last)
+		}
+	}
+}
+
+// subtree adds the subtree of the node at idx, of the base plan if removed
+// and of the other plan if added.
+func (d *differ) subtree(change Change, idx int32, linkType string, depth int, last bool) {
+	plan, visited := d.plan, d.visited
+	if change == Removed {
+		plan, visited = d.base, d.visitedBase
+	}
+	visited[idx] = true
+	n := plan.nodes[idx]
+	row := DiffRow{Depth: depth, LinkType: linkType, Last: last, Change: change}
+	if change == Removed {
+		row.Base = n
+	} else {
+		row.Node = n
+	}
+	d.rows = append(d.rows, row)
+
+	links := plan.treeLinks(n, visited)
+	for i, link := range links {
+		d.subtree(change, link.GetChildIndex(), link.GetType(), depth+1, i == len(links)-1)
+	}
+}
+
+// align pairs the child links of two matched nodes. Unmatched links between
+// two matches are listed removed first.
+func (d *differ) align(baseLinks, links []*sppb.PlanNode_ChildLink) []linkPair {
+	key := func(p *Plan, link *sppb.PlanNode_ChildLink) string {
+		return link.GetType() + "\x00" + p.Node(link.GetChildIndex()).GetDisplayName()
+	}
+	same := func(i, j int) bool {
+		return i < len(baseLinks) && j < len(links) && key(d.base, baseLinks[i]) == key(d.plan, links[j])
+	}
+	// lcs[i][j] is the length of the longest common subsequence of
+	// baseLinks[i:] and links[j:].
+	lcs := make([][]int, len(baseLinks)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(links)+1)
+	}
+	for i := len(baseLinks) - 1; i >= 0; i-- {
+		for j := len(links) - 1; j >= 0; j-- {
+			if same(i, j) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var pairs []linkPair
+	for i, j := 0, 0; i < len(baseLinks) || j < len(links); {
+		if same(i, j) {
+			pairs = append(pairs, linkPair{baseLinks[i], links[j]})
+			i, j = i+1, j+1
+			continue
+		}
+		// Skip to the next match.
+		gi, gj := i, j
+		for (i < len(baseLinks) || j < len(links)) && !same(i, j) {
+			if j == len(links) || (i < len(baseLinks) && lcs[i+1][j] >= lcs[i][j+1]) {
+				i++
+			} else {
+				j++
+			}
+		}
+		if i-gi == 1 && j-gj == 1 {
+			pairs = append(pairs, linkPair{baseLinks[gi], links[gj]})
+			continue
+		}
+		for _, link := range baseLinks[gi:i] {
+			pairs = append(pairs, linkPair{base: link})
+		}
+		for _, link := range links[gj:j] {
+			pairs = append(pairs, linkPair{link: link})
+		}
+	}
+	return pairs
+}
+
+// compare describes the differences between two matched nodes as
+// "what: base -> other" strings.
+func (d *differ) compare(b, n *sppb.PlanNode) []string {
+	var details []string
+	changed := func(what, from, to string) {
+		if from != to {
+			details = append(details, what+": "+orNone(from)+" -> "+orNone(to))
+		}
+	}
+	changed("operator", Operator(b), Operator(n))
+
+	// The keys folded into the operator are compared above. This also leaves
+	// out subquery_cluster_node, a node index that differs whenever the
+	// plans are numbered differently.
+	baseMD, md := metadataMap(b), metadataMap(n)
+	for _, key := range unionKeys(baseMD, md) {
+		if !slices.Contains(operatorMetadata, key) {
+			changed(key, baseMD[key], md[key])
+		}
+	}
+
+	basePreds, preds := predicateMap(d.base, b), predicateMap(d.plan, n)
+	for _, typ := range unionKeys(basePreds, preds) {
+		changed(typ, basePreds[typ], preds[typ])
+	}
+	return details
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+func metadataMap(n *sppb.PlanNode) map[string]string {
+	m := make(map[string]string)
+	for _, prop := range Metadata(n) {
+		m[prop.Key] = prop.Value
+	}
+	return m
+}
+
+// predicateMap returns the predicates of n by type; predicates of the same
+// type are joined by " AND ".
+func predicateMap(p *Plan, n *sppb.PlanNode) map[string]string {
+	m := make(map[string]string)
+	for _, pred := range p.Predicates(n) {
+		if s, ok := m[pred.Type]; ok {
+			m[pred.Type] = s + " AND " + pred.Description
+		} else {
+			m[pred.Type] = pred.Description
+		}
+	}
+	return m
+}
+
+func unionKeys(a, b map[string]string) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
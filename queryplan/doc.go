@@ -0,0 +1,10 @@
+// Package queryplan navigates the query plan of PLAN and PROFILE results.
+//
+// A [Plan] indexes [sppb.PlanNode]s by their index and walks them as the tree
+// that the plan rendering formats draw: relational operators and scalar
+// subqueries, with predicates and PROFILE execution stats attached to the
+// nodes that own them. It mirrors the traversal of examples/plan.jq.
+//
+// [Diff] matches the trees of two plans of a query, and [Aggregate] combines
+// the stats of repeated PROFILE runs of the same plan.
+package queryplan
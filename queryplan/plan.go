@@ -0,0 +1,240 @@
+package queryplan
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Plan is a query plan whose first node is the root.
+type Plan struct {
+	nodes []*sppb.PlanNode
+}
+
+// New returns the plan of nodes, as found in stats.queryPlan.planNodes.
+func New(nodes []*sppb.PlanNode) *Plan {
+	return &Plan{nodes: nodes}
+}
+
+// Nodes returns the plan nodes in index order.
+func (p *Plan) Nodes() []*sppb.PlanNode {
+	return p.nodes
+}
+
+// Node returns the node at index i, or nil if there is none.
+func (p *Plan) Node(i int32) *sppb.PlanNode {
+	if i < 0 || int(i) >= len(p.nodes) {
+		return nil
+	}
+	return p.nodes[i]
+}
+
+// Row is a node reached by [Plan.Walk].
+type Row struct {
+	Node *sppb.PlanNode
+	// Parent is the index of the parent node, or -1 for the root.
+	Parent int32
+	// Depth is 0 for the root.
+	Depth int
+	// LinkType is the type of the child link from the parent, such as
+	// "Input" or "Scalar"; it is often empty.
+	LinkType string
+	// Last reports whether the node is the last visited child of its parent.
+	// It is only set by Walk.
+	Last bool
+}
+
+// Walk returns the nodes of the plan tree in depth-first order. Relational
+// children are visited, and so are scalar children linked as "Scalar", which
+// are the roots of scalar subqueries. Other scalar children are expressions
+// such as predicates, references and constants, and are not part of the tree.
+//
+// A node is visited once even if a malformed plan links it twice.
+func (p *Plan) Walk() []Row {
+	if len(p.nodes) == 0 {
+		return nil
+	}
+	var rows []Row
+	visited := make(map[int32]bool)
+	var walk func(idx, parent int32, depth int, linkType string, last bool)
+	walk = func(idx, parent int32, depth int, linkType string, last bool) {
+		visited[idx] = true
+		n := p.nodes[idx]
+		rows = append(rows, Row{Node: n, Parent: parent, Depth: depth, LinkType: linkType, Last: last})
+		links := p.treeLinks(n, visited)
+		for i, link := range links {
+			walk(link.GetChildIndex(), idx, depth+1, link.GetType(), i == len(links)-1)
+		}
+	}
+	walk(0, -1, 0, "", true)
+	return rows
+}
+
+// Flatten returns a row for every node in index order, including the scalar
+// expressions that [Plan.Walk] leaves out. The parent of a node is the first
+// node linking to it. The root, and any node that nothing links to, has no
+// parent and depth 0.
+func (p *Plan) Flatten() []Row {
+	type link struct {
+		parent   int32
+		linkType string
+	}
+	parents := make(map[int32]link)
+	for _, n := range p.nodes {
+		for _, l := range n.GetChildLinks() {
+			if _, ok := parents[l.GetChildIndex()]; !ok && l.GetChildIndex() != 0 {
+				parents[l.GetChildIndex()] = link{n.GetIndex(), l.GetType()}
+			}
+		}
+	}
+	depth := func(idx int32) int {
+		d := 0
+		// A malformed plan must not loop forever.
+		for l, ok := parents[idx]; ok && d < len(p.nodes); l, ok = parents[l.parent] {
+			d++
+		}
+		return d
+	}
+	rows := make([]Row, len(p.nodes))
+	for i, n := range p.nodes {
+		rows[i] = Row{Node: n, Parent: -1, Depth: depth(n.GetIndex())}
+		if l, ok := parents[n.GetIndex()]; ok {
+			rows[i].Parent, rows[i].LinkType = l.parent, l.linkType
+		}
+	}
+	return rows
+}
+
+// Children returns the child links of n that [Plan.Walk] follows.
+func (p *Plan) Children(n *sppb.PlanNode) []*sppb.PlanNode_ChildLink {
+	return p.treeLinks(n, nil)
+}
+
+func (p *Plan) treeLinks(n *sppb.PlanNode, visited map[int32]bool) []*sppb.PlanNode_ChildLink {
+	var links []*sppb.PlanNode_ChildLink
+	for _, link := range n.GetChildLinks() {
+		child := p.Node(link.GetChildIndex())
+		if child == nil || visited[link.GetChildIndex()] {
+			continue
+		}
+		if child.GetKind() == sppb.PlanNode_RELATIONAL || link.GetType() == "Scalar" {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// Predicate is a condition evaluated by a node, such as a "Residual
+// Condition" of a filter or the "Split Range" of a distributed union.
+type Predicate struct {
+	Type        string
+	Description string
+}
+
+// Predicates returns the predicates of n, described by the short
+// representation of their scalar nodes.
+func (p *Plan) Predicates(n *sppb.PlanNode) []Predicate {
+	var preds []Predicate
+	for _, link := range n.GetChildLinks() {
+		if !IsPredicate(link.GetType()) {
+			continue
+		}
+		preds = append(preds, Predicate{
+			Type:        link.GetType(),
+			Description: p.Node(link.GetChildIndex()).GetShortRepresentation().GetDescription(),
+		})
+	}
+	return preds
+}
+
+// IsPredicate reports whether a child link of linkType is a predicate.
+func IsPredicate(linkType string) bool {
+	return strings.HasSuffix(linkType, "Condition") || linkType == "Split Range"
+}
+
+// operatorMetadata are the metadata keys folded into [Operator].
+var operatorMetadata = []string{"call_type", "iterator_type", "scan_type", "subquery_cluster_node"}
+
+// Operator returns the display name of n qualified by its call, iterator and
+// scan types, such as "Global Limit", "Hash Join" or "Table Scan".
+func Operator(n *sppb.PlanNode) string {
+	md := n.GetMetadata().GetFields()
+	var parts []string
+	for _, key := range []string{"call_type", "iterator_type"} {
+		if s := md[key].GetStringValue(); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if s := scanType(n); s != "" {
+		parts = append(parts, s)
+	}
+	return strings.Join(append(parts, n.GetDisplayName()), " ")
+}
+
+// ScanTarget returns the table or index that a scan node reads, or "".
+func ScanTarget(n *sppb.PlanNode) string {
+	return n.GetMetadata().GetFields()["scan_target"].GetStringValue()
+}
+
+// scanType returns the scan type of n without its "Scan" suffix, such as
+// "Table" or "Index".
+func scanType(n *sppb.PlanNode) string {
+	return strings.TrimSuffix(n.GetMetadata().GetFields()["scan_type"].GetStringValue(), "Scan")
+}
+
+// Properties returns the metadata of n that [Operator] does not show, as
+// sorted "key: value" strings. The scan target is keyed by the scan type,
+// as in "Table: Singers".
+func Properties(n *sppb.PlanNode) []string {
+	md := n.GetMetadata().GetFields()
+	props := make([]string, 0, len(md))
+	for key := range md {
+		if slices.Contains(operatorMetadata, key) {
+			continue
+		}
+		name := key
+		if key == "scan_target" && scanType(n) != "" {
+			name = scanType(n)
+		}
+		props = append(props, name+": "+ValueText(md[key]))
+	}
+	slices.Sort(props)
+	return props
+}
+
+// ValueText formats a metadata value, a query stat or a field of an
+// execution stat, which is usually a string; other values are JSON.
+func ValueText(v *structpb.Value) string {
+	switch k := v.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return k.StringValue
+	case nil:
+		return ""
+	default:
+		b, err := v.MarshalJSON()
+		if err != nil {
+			return fmt.Sprint(v.AsInterface())
+		}
+		return string(b)
+	}
+}
+
+// Property is a metadata entry of a plan node.
+type Property struct {
+	Key   string
+	Value string
+}
+
+// Metadata returns all metadata of n sorted by key.
+func Metadata(n *sppb.PlanNode) []Property {
+	md := n.GetMetadata().GetFields()
+	props := make([]Property, 0, len(md))
+	for key, v := range md {
+		props = append(props, Property{Key: key, Value: ValueText(v)})
+	}
+	slices.SortFunc(props, func(a, b Property) int { return strings.Compare(a.Key, b.Key) })
+	return props
+}
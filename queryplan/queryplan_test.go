@@ -0,0 +1,376 @@
+package queryplan
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func mustStruct(t *testing.T, m map[string]any) *structpb.Struct {
+	t.Helper()
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func latency(total string) map[string]any {
+	return map[string]any{"latency": map[string]any{"total": total, "unit": "msecs"}}
+}
+
+// fixturePlan is
+//
+//	0 Filter (Residual Condition: 3)
+//	  1 Table Scan (Input), with a Scalar subquery 4 and a reference 2
+//	  5 Hash Join, which links back to 0
+func fixturePlan(t *testing.T) *Plan {
+	t.Helper()
+	return New([]*sppb.PlanNode{
+		{
+			Index: 0, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Filter",
+			ChildLinks: []*sppb.PlanNode_ChildLink{
+				{ChildIndex: 1, Type: "Input"},
+				{ChildIndex: 3, Type: "Residual Condition"},
+				{ChildIndex: 5},
+			},
+			ExecutionStats: mustStruct(t, latency("9")),
+		},
+		{
+			Index: 1, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Scan",
+			ChildLinks: []*sppb.PlanNode_ChildLink{
+				{ChildIndex: 2, Variable: "SingerId"},
+				{ChildIndex: 4, Type: "Scalar"},
+			},
+			Metadata:       mustStruct(t, map[string]any{"scan_type": "TableScan", "scan_target": "Singers", "Full scan": "true"}),
+			ExecutionStats: mustStruct(t, latency("2")),
+		},
+		{Index: 2, Kind: sppb.PlanNode_SCALAR, DisplayName: "Reference", ShortRepresentation: &sppb.PlanNode_ShortRepresentation{Description: "SingerId"}},
+		{Index: 3, Kind: sppb.PlanNode_SCALAR, DisplayName: "Function", ShortRepresentation: &sppb.PlanNode_ShortRepresentation{Description: "($SingerId > 1)"}},
+		{Index: 4, Kind: sppb.PlanNode_SCALAR, DisplayName: "Scalar Subquery"},
+		{
+			Index: 5, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Join",
+			ChildLinks:     []*sppb.PlanNode_ChildLink{{ChildIndex: 0}, {ChildIndex: 99}},
+			Metadata:       mustStruct(t, map[string]any{"join_type": "INNER", "iterator_type": "Hash"}),
+			ExecutionStats: mustStruct(t, latency("5")),
+		},
+	})
+}
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+
+	type row struct {
+		Index    int32
+		Parent   int32
+		Depth    int
+		LinkType string
+		Last     bool
+	}
+	var got []row
+	for _, r := range fixturePlan(t).Walk() {
+		got = append(got, row{r.Node.GetIndex(), r.Parent, r.Depth, r.LinkType, r.Last})
+	}
+	want := []row{
+		{0, -1, 0, "", true},
+		{1, 0, 1, "Input", false},
+		{4, 1, 2, "Scalar", true},
+		{5, 0, 1, "", true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Walk() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNodeText(t *testing.T) {
+	t.Parallel()
+
+	p := fixturePlan(t)
+	if got, want := Operator(p.Node(1)), "Table Scan"; got != want {
+		t.Errorf("Operator() = %q, want %q", got, want)
+	}
+	if got, want := Operator(p.Node(5)), "Hash Join"; got != want {
+		t.Errorf("Operator() = %q, want %q", got, want)
+	}
+	if diff := cmp.Diff([]string{"Full scan: true", "Table: Singers"}, Properties(p.Node(1))); diff != "" {
+		t.Errorf("Properties() mismatch (-want +got):\n%s", diff)
+	}
+	wantMetadata := []Property{{"Full scan", "true"}, {"scan_target", "Singers"}, {"scan_type", "TableScan"}}
+	if diff := cmp.Diff(wantMetadata, Metadata(p.Node(1))); diff != "" {
+		t.Errorf("Metadata() mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := ScanTarget(p.Node(1)), "Singers"; got != want {
+		t.Errorf("ScanTarget() = %q, want %q", got, want)
+	}
+	if diff := cmp.Diff([]Predicate{{"Residual Condition", "($SingerId > 1)"}}, p.Predicates(p.Node(0))); diff != "" {
+		t.Errorf("Predicates() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHotPath(t *testing.T) {
+	t.Parallel()
+
+	if diff := cmp.Diff(map[int32]bool{0: true, 5: true}, fixturePlan(t).HotPath()); diff != "" {
+		t.Errorf("HotPath() mismatch (-want +got):\n%s", diff)
+	}
+	if got := New([]*sppb.PlanNode{{DisplayName: "Scan"}}).HotPath(); got != nil {
+		t.Errorf("HotPath() without stats = %v, want nil", got)
+	}
+}
+
+func TestStat(t *testing.T) {
+	t.Parallel()
+
+	n := &sppb.PlanNode{ExecutionStats: mustStruct(t, map[string]any{
+		"rows":              map[string]any{"total": "3", "unit": "rows"},
+		"cpu_time":          map[string]any{"total": "0.7", "mean": "0.35", "std_deviation": "0.1", "unit": "msecs"},
+		"execution_summary": map[string]any{"num_executions": "2"},
+	})}
+	if got, want := ExecutionStat(n, "rows").String(), "3"; got != want {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+	cpu := ExecutionStat(n, "cpu_time")
+	if diff := cmp.Diff(Stat{Total: "0.7", Mean: "0.35", StdDev: "0.1", Unit: "msecs"}, cpu); diff != "" {
+		t.Errorf("cpu_time mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := cpu.String(), "0.7 msecs"; got != want {
+		t.Errorf("cpu_time = %q, want %q", got, want)
+	}
+	if got, want := Executions(n), "2"; got != want {
+		t.Errorf("Executions() = %q, want %q", got, want)
+	}
+	if diff := cmp.Diff([]string{"cpu_time", "rows"}, StatNames(n)); diff != "" {
+		t.Errorf("StatNames() mismatch (-want +got):\n%s", diff)
+	}
+	if got := ExecutionStat(n, "latency"); got != (Stat{}) {
+		t.Errorf("missing stat = %+v, want zero", got)
+	}
+}
+
+func TestSamples(t *testing.T) {
+	t.Parallel()
+
+	type sample struct {
+		Stack   []int32
+		Latency time.Duration
+	}
+	var got []sample
+	for _, s := range fixturePlan(t).Samples() {
+		var stack []int32
+		for _, n := range s.Stack {
+			stack = append(stack, n.GetIndex())
+		}
+		got = append(got, sample{stack, s.Latency})
+	}
+	want := []sample{
+		{[]int32{0}, 2 * time.Millisecond},
+		{[]int32{0, 1}, 2 * time.Millisecond},
+		{[]int32{0, 1, 4}, 0},
+		{[]int32{0, 5}, 5 * time.Millisecond},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Samples() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStatDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		stat Stat
+		want time.Duration
+		ok   bool
+	}{
+		{Stat{Total: "1.5", Unit: "secs"}, 1500 * time.Millisecond, true},
+		{Stat{Total: "0.71", Unit: "msecs"}, 710 * time.Microsecond, true},
+		{Stat{Total: "12", Unit: "usecs"}, 12 * time.Microsecond, true},
+		{Stat{Total: "3", Unit: "rows"}, 0, false},
+		{Stat{}, 0, false},
+	}
+	for _, tc := range tests {
+		got, ok := tc.stat.Duration()
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%+v.Duration() = %v, %v, want %v, %v", tc.stat, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	t.Parallel()
+
+	type row struct {
+		Index    int32
+		Parent   int32
+		Depth    int
+		LinkType string
+	}
+	var got []row
+	for _, r := range fixturePlan(t).Flatten() {
+		got = append(got, row{r.Node.GetIndex(), r.Parent, r.Depth, r.LinkType})
+	}
+	// 5 links back to 0, which is still the root.
+	want := []row{
+		{0, -1, 0, ""},
+		{1, 0, 1, "Input"},
+		{2, 1, 2, ""},
+		{3, 0, 1, "Residual Condition"},
+		{4, 1, 2, "Scalar"},
+		{5, 0, 1, ""},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Flatten() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	base := fixturePlan(t)
+	nodes := slices.Clone(base.Nodes())
+	nodes[1] = proto.Clone(nodes[1]).(*sppb.PlanNode)
+	nodes[1].Metadata = mustStruct(t, map[string]any{"scan_type": "IndexScan", "scan_target": "SingersByName"})
+	nodes[3] = &sppb.PlanNode{Index: 3, Kind: sppb.PlanNode_SCALAR, DisplayName: "Function", ShortRepresentation: &sppb.PlanNode_ShortRepresentation{Description: "($SingerId > 2)"}}
+	nodes[5] = proto.Clone(nodes[5]).(*sppb.PlanNode)
+	nodes[5].ChildLinks = []*sppb.PlanNode_ChildLink{{ChildIndex: 0}, {ChildIndex: 6}}
+	nodes = append(nodes, &sppb.PlanNode{Index: 6, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Sort"})
+
+	type row struct {
+		Base, Node int32
+		Depth      int
+		LinkType   string
+		Last       bool
+		Change     Change
+		Details    []string
+	}
+	index := func(n *sppb.PlanNode) int32 {
+		if n == nil {
+			return -1
+		}
+		return n.GetIndex()
+	}
+	var got []row
+	for _, r := range Diff(base, New(nodes)) {
+		got = append(got, row{index(r.Base), index(r.Node), r.Depth, r.LinkType, r.Last, r.Change, r.Details})
+	}
+	want := []row{
+		{0, 0, 0, "", true, Changed, []string{"Residual Condition: ($SingerId > 1) -> ($SingerId > 2)"}},
+		{1, 1, 1, "Input", false, Changed, []string{
+			"operator: Table Scan -> Index Scan",
+			"Full scan: true -> (none)",
+			"scan_target: Singers -> SingersByName",
+		}},
+		{4, 4, 2, "Scalar", true, Same, nil},
+		{5, 5, 1, "", true, Same, nil},
+		{-1, 6, 2, "", true, Added, nil},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Diff() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiffAlign(t *testing.T) {
+	t.Parallel()
+
+	plan := func(children ...string) *Plan {
+		nodes := []*sppb.PlanNode{{Index: 0, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Union All"}}
+		for i, name := range children {
+			idx := int32(i + 1)
+			nodes[0].ChildLinks = append(nodes[0].ChildLinks, &sppb.PlanNode_ChildLink{ChildIndex: idx})
+			nodes = append(nodes, &sppb.PlanNode{Index: idx, Kind: sppb.PlanNode_RELATIONAL, DisplayName: name})
+		}
+		return New(nodes)
+	}
+	tests := []struct {
+		name        string
+		base, other []string
+		want        []string
+	}{
+		{"same", []string{"A", "B"}, []string{"A", "B"}, []string{" A/A", " B/B"}},
+		{"inserted", []string{"A", "C"}, []string{"A", "B", "C"}, []string{" A/A", "+/B", " C/C"}},
+		{"replaced", []string{"A", "B", "C"}, []string{"A", "X", "C"}, []string{" A/A", "~B/X", " C/C"}},
+		{"replaced by two", []string{"A", "B"}, []string{"X", "Y", "B"}, []string{"-A/", "+/X", "+/Y", " B/B"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			marks := map[Change]string{Same: " ", Added: "+", Removed: "-", Changed: "~"}
+			var got []string
+			for _, r := range Diff(plan(tt.base...), plan(tt.other...))[1:] {
+				got = append(got, marks[r.Change]+r.Base.GetDisplayName()+"/"+r.Node.GetDisplayName())
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Diff() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	t.Parallel()
+
+	run := func(rootLatency, unit, elapsed string) *sppb.ResultSetStats {
+		nodes := fixturePlan(t).Nodes()
+		nodes[0].ExecutionStats = mustStruct(t, map[string]any{
+			"latency": map[string]any{"total": rootLatency, "mean": rootLatency, "unit": unit},
+		})
+		return &sppb.ResultSetStats{
+			QueryPlan:  &sppb.QueryPlan{PlanNodes: nodes},
+			QueryStats: mustStruct(t, map[string]any{"elapsed_time": elapsed, "query_text": "SELECT 1"}),
+		}
+	}
+	got, err := Aggregate([]*sppb.ResultSetStats{
+		run("1", "msecs", "10 msecs"),
+		run("4", "msecs", "30 msecs"),
+		run("3000", "usecs", "20 msecs"),
+		run("2", "msecs", "40 msecs"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	latency := got.GetQueryPlan().GetPlanNodes()[0].GetExecutionStats().GetFields()["latency"].GetStructValue()
+	wantLatency := mustStruct(t, map[string]any{
+		"total": "2.5", "mean": "2.5", "unit": "msecs",
+		"min": "1", "median": "2.5", "p95": "4", "max": "4",
+	})
+	if diff := cmp.Diff(wantLatency, latency, protocmp.Transform()); diff != "" {
+		t.Errorf("latency mismatch (-want +got):\n%s", diff)
+	}
+	wantQueryStats := mustStruct(t, map[string]any{
+		"query_text":          "SELECT 1",
+		"elapsed_time":        "25 msecs",
+		"elapsed_time_min":    "10 msecs",
+		"elapsed_time_median": "25 msecs",
+		"elapsed_time_p95":    "40 msecs",
+		"elapsed_time_max":    "40 msecs",
+		"profile_runs":        "4",
+	})
+	if diff := cmp.Diff(wantQueryStats, got.GetQueryStats(), protocmp.Transform()); diff != "" {
+		t.Errorf("query stats mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAggregatePlanChange(t *testing.T) {
+	t.Parallel()
+
+	nodes := fixturePlan(t).Nodes()
+	other := slices.Clone(nodes)
+	other[1] = proto.Clone(other[1]).(*sppb.PlanNode)
+	other[1].Metadata = mustStruct(t, map[string]any{"scan_type": "IndexScan", "scan_target": "SingersByName"})
+	_, err := Aggregate([]*sppb.ResultSetStats{
+		{QueryPlan: &sppb.QueryPlan{PlanNodes: other}},
+		{QueryPlan: &sppb.QueryPlan{PlanNodes: nodes}},
+	})
+	want := "the plan of run 1 differs from run 2: node 1 changed: operator: Index Scan -> Table Scan"
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Aggregate() error = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,189 @@
+package queryplan
+
+import (
+	"slices"
+	"strconv"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+// Stat is an execution stat of a PROFILE plan node, such as
+// {"total": "7.2", "unit": "msecs"}. Its fields are empty when absent.
+type Stat struct {
+	Total  string
+	Mean   string
+	StdDev string
+	Unit   string
+}
+
+// ExecutionStat returns the execution stat of n named name, such as "rows",
+// "latency" or "cpu_time".
+func ExecutionStat(n *sppb.PlanNode, name string) Stat {
+	s := n.GetExecutionStats().GetFields()[name].GetStructValue().GetFields()
+	return Stat{
+		Total:  ValueText(s["total"]),
+		Mean:   ValueText(s["mean"]),
+		StdDev: ValueText(s["std_deviation"]),
+		Unit:   ValueText(s["unit"]),
+	}
+}
+
+// String formats the total with its unit, as in "7.2 msecs". Counts of rows
+// are formatted without their unit.
+func (s Stat) String() string {
+	if s.Total == "" || s.Unit == "" || s.Unit == "rows" {
+		return s.Total
+	}
+	return s.Total + " " + s.Unit
+}
+
+// Value returns the total as a number, or 0 if it is absent or malformed.
+func (s Stat) Value() float64 {
+	f, err := strconv.ParseFloat(s.Total, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// Executions returns the number of executions of n as text, or "".
+func Executions(n *sppb.PlanNode) string {
+	summary := n.GetExecutionStats().GetFields()["execution_summary"].GetStructValue().GetFields()
+	return ValueText(summary["num_executions"])
+}
+
+// HasExecutionStats reports whether the plan carries PROFILE stats.
+func (p *Plan) HasExecutionStats() bool {
+	for _, n := range p.nodes {
+		if len(n.GetExecutionStats().GetFields()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// HotPath returns the indexes of the nodes on the path from the root that
+// follows, at each node, the child of [Plan.Walk] with the highest total
+// latency. It is empty without execution stats.
+func (p *Plan) HotPath() map[int32]bool {
+	if !p.HasExecutionStats() {
+		return nil
+	}
+	hot := make(map[int32]bool)
+	for idx := int32(0); !hot[idx]; {
+		hot[idx] = true
+		next, best := int32(-1), -1.0
+		for _, link := range p.Children(p.nodes[idx]) {
+			if latency := ExecutionStat(p.Node(link.GetChildIndex()), "latency").Value(); latency > best {
+				next, best = link.GetChildIndex(), latency
+			}
+		}
+		if next < 0 {
+			break
+		}
+		idx = next
+	}
+	return hot
+}
+
+// StatNames returns the sorted names of the execution stats of n that
+// [ExecutionStat] reads, leaving out execution_summary.
+func StatNames(n *sppb.PlanNode) []string {
+	var names []string
+	for name := range n.GetExecutionStats().GetFields() {
+		if name != "execution_summary" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Duration returns the total as a duration if the unit is a unit of time
+// that Spanner reports: secs, msecs or usecs.
+func (s Stat) Duration() (time.Duration, bool) {
+	unit, ok := timeUnit(s.Unit)
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s.Total, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(f * float64(unit)), true
+}
+
+// timeUnit returns the duration of a unit of time that Spanner reports.
+func timeUnit(unit string) (time.Duration, bool) {
+	switch unit {
+	case "secs":
+		return time.Second, true
+	case "msecs":
+		return time.Millisecond, true
+	case "usecs":
+		return time.Microsecond, true
+	default:
+		return 0, false
+	}
+}
+
+// Sample is the time spent in a node of the plan tree itself, excluding the
+// time of its children in [Plan.Walk], which the node's stats include.
+type Sample struct {
+	// Stack is the path from the root to the node.
+	Stack   []*sppb.PlanNode
+	Latency time.Duration
+	CPU     time.Duration
+}
+
+// Samples returns a sample for each node walked by [Plan.Walk], in the same
+// order. Latency and CPU time are the totals of the node minus the totals of
+// its children; they are clamped to zero when children ran in parallel. A
+// child without the stat, such as a Filter Scan folded into its scan, counts
+// as the sum of its own children.
+func (p *Plan) Samples() []Sample {
+	walk := p.Walk()
+	samples := make([]Sample, len(walk))
+	stacks := make(map[int32][]*sppb.PlanNode)
+	for i, r := range walk {
+		stack := append(slices.Clone(stacks[r.Parent]), r.Node)
+		stacks[r.Node.GetIndex()] = stack
+		samples[i].Stack = stack
+	}
+
+	pos := make(map[int32]int)
+	for i, r := range walk {
+		pos[r.Node.GetIndex()] = i
+	}
+	// Children follow their parent in walk order, so the totals of the
+	// children are known when a node is reached in reverse order.
+	selfTimes := func(name string) []time.Duration {
+		own := make([]time.Duration, len(walk))
+		has := make([]bool, len(walk))
+		children := make([]time.Duration, len(walk))
+		for i := len(walk) - 1; i >= 0; i-- {
+			own[i], has[i] = ExecutionStat(walk[i].Node, name).Duration()
+			if !has[i] {
+				own[i] = children[i]
+			}
+			if j, ok := pos[walk[i].Parent]; ok {
+				children[j] += own[i]
+			}
+		}
+		self := make([]time.Duration, len(walk))
+		for i := range walk {
+			if has[i] {
+				self[i] = max(own[i]-children[i], 0)
+			}
+		}
+		return self
+	}
+	for i, d := range selfTimes("latency") {
+		samples[i].Latency = d
+	}
+	for i, d := range selfTimes("cpu_time") {
+		samples[i].CPU = d
+	}
+	return samples
+}
@@ -0,0 +1,136 @@
+package resultset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// FileFormat is the encoding of a saved ResultSet file.
+type FileFormat string
+
+const (
+	// FileBinary is a magic line and a JSON header line followed by the
+	// binary protobuf encoding of the ResultSet.
+	FileBinary FileFormat = "binary"
+	// FileJSON is a JSON object {"header": ..., "resultSet": ...} where
+	// resultSet is the protojson encoding of the ResultSet.
+	FileJSON FileFormat = "json"
+)
+
+// ParseFileFormat parses the value of --save-resultset-format.
+func ParseFileFormat(s string) (FileFormat, error) {
+	switch FileFormat(s) {
+	case FileBinary, FileJSON:
+		return FileFormat(s), nil
+	default:
+		return "", fmt.Errorf("resultset file format must be binary or json")
+	}
+}
+
+// binaryMagic starts a binary ResultSet file.
+const binaryMagic = "execspansql-resultset v1\n"
+
+// Header describes the query that produced a saved ResultSet.
+type Header struct {
+	SQL       string            `json:"sql"`
+	Params    map[string]string `json:"params,omitempty"`
+	QueryMode string            `json:"queryMode,omitempty"`
+	// ReadTimestamp is the RFC 3339 read timestamp, set by Save from the ResultSet metadata.
+	ReadTimestamp string `json:"readTimestamp,omitempty"`
+}
+
+type jsonFile struct {
+	Header    *Header         `json:"header"`
+	ResultSet json.RawMessage `json:"resultSet"`
+}
+
+// Save writes rs with header h in format.
+func Save(w io.Writer, format FileFormat, h Header, rs *sppb.ResultSet) error {
+	if ts := rs.GetMetadata().GetTransaction().GetReadTimestamp(); ts != nil {
+		h.ReadTimestamp = ts.AsTime().Format(time.RFC3339Nano)
+	}
+
+	switch format {
+	case FileBinary:
+		header, err := json.Marshal(h)
+		if err != nil {
+			return err
+		}
+		b, err := proto.Marshal(rs)
+		if err != nil {
+			return err
+		}
+		bw := bufio.NewWriter(w)
+		bw.WriteString(binaryMagic)
+		bw.Write(header)
+		bw.WriteByte('\n')
+		bw.Write(b)
+		return bw.Flush()
+	case FileJSON:
+		b, err := protojson.Marshal(rs)
+		if err != nil {
+			return err
+		}
+		// MarshalIndent also normalizes the deliberately unstable protojson spacing.
+		out, err := json.MarshalIndent(jsonFile{Header: &h, ResultSet: b}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(out, '\n'))
+		return err
+	default:
+		return fmt.Errorf("unknown resultset file format %q", format)
+	}
+}
+
+// Load reads a file written by [Save]. A bare protojson ResultSet, such as
+// the JSON output of --format=json with the default filter, is also accepted
+// and returns an empty Header.
+func Load(r io.Reader) (Header, *sppb.ResultSet, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return Header{}, nil, err
+	}
+
+	var rs sppb.ResultSet
+	if rest, ok := bytes.CutPrefix(b, []byte(binaryMagic)); ok {
+		line, body, ok := bytes.Cut(rest, []byte("\n"))
+		if !ok {
+			return Header{}, nil, errors.New("resultset file has no header line")
+		}
+		var h Header
+		if err := json.Unmarshal(line, &h); err != nil {
+			return Header{}, nil, fmt.Errorf("invalid resultset header: %w", err)
+		}
+		if err := proto.Unmarshal(body, &rs); err != nil {
+			return Header{}, nil, fmt.Errorf("invalid resultset: %w", err)
+		}
+		return h, &rs, nil
+	}
+
+	var f jsonFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return Header{}, nil, fmt.Errorf("resultset file is neither binary nor JSON: %w", err)
+	}
+	if f.ResultSet == nil {
+		// A bare ResultSet.
+		f.Header, f.ResultSet = &Header{}, b
+	}
+	if err := protojson.Unmarshal(f.ResultSet, &rs); err != nil {
+		return Header{}, nil, fmt.Errorf("invalid resultset: %w", err)
+	}
+	var h Header
+	if f.Header != nil {
+		h = *f.Header
+	}
+	return h, &rs, nil
+}
@@ -0,0 +1,98 @@
+package resultset
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func savedFixture() *sppb.ResultSet {
+	return &sppb.ResultSet{
+		Metadata: &sppb.ResultSetMetadata{
+			RowType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+				{Name: "n", Type: &sppb.Type{Code: sppb.TypeCode_INT64}},
+			}},
+			Transaction: &sppb.Transaction{ReadTimestamp: timestamppb.New(time.Date(2024, 6, 1, 0, 0, 0, 5000, time.UTC))},
+		},
+		Rows:  []*structpb.ListValue{{Values: []*structpb.Value{structpb.NewStringValue("1")}}},
+		Stats: &sppb.ResultSetStats{RowCount: &sppb.ResultSetStats_RowCountExact{RowCountExact: 1}},
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	header := Header{SQL: "SELECT @n AS n", Params: map[string]string{"n": "1"}, QueryMode: "PROFILE"}
+	wantHeader := header
+	wantHeader.ReadTimestamp = "2024-06-01T00:00:00.000005Z"
+
+	for _, format := range []FileFormat{FileBinary, FileJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := Save(&buf, format, header, savedFixture()); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			gotHeader, gotRS, err := Load(&buf)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if diff := cmp.Diff(wantHeader, gotHeader); diff != "" {
+				t.Errorf("header mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(savedFixture(), gotRS, protocmp.Transform()); diff != "" {
+				t.Errorf("ResultSet mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadBareResultSet(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("../testdata/profile/singers_limit3.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	header, rs, err := Load(f)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cmp.Equal(header, Header{}) {
+		t.Errorf("header = %+v, want zero", header)
+	}
+	if len(rs.GetRows()) != 3 || rs.GetStats().GetQueryPlan() == nil {
+		t.Errorf("got %d rows and plan %v, want 3 rows and a plan", len(rs.GetRows()), rs.GetStats().GetQueryPlan() != nil)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{"", "not json", binaryMagic, binaryMagic + "{}\n\xff"} {
+		if _, _, err := Load(strings.NewReader(input)); err == nil {
+			t.Errorf("Load(%q) error = nil, want error", input)
+		}
+	}
+}
+
+func TestParseFileFormat(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParseFileFormat("yaml"); err == nil {
+		t.Error("ParseFileFormat(yaml) error = nil")
+	}
+	if got, err := ParseFileFormat("json"); err != nil || got != FileJSON {
+		t.Errorf("ParseFileFormat(json) = %v, %v", got, err)
+	}
+}
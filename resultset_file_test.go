@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/apstndb/execspansql/jqresult"
+	"github.com/apstndb/execspansql/plancheck"
+	"github.com/apstndb/execspansql/resultset"
+)
+
+// TestWriteSavedResultSet renders a saved ResultSet without Spanner, as --from-resultset does.
+func TestWriteSavedResultSet(t *testing.T) {
+	rs, err := loadProfileJSONFixture("testdata/profile/singers_limit3.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := filepath.Join(t.TempDir(), "singers.pb")
+	o := opts{SaveResultset: saved, SaveResultsetFormat: "binary"}
+	if err := o.saveResultSet(resultset.Header{SQL: "SELECT SingerId, FirstName FROM Singers LIMIT 3"}, rs); err != nil {
+		t.Fatalf("saveResultSet() error = %v", err)
+	}
+	base, err := loadProfileJSONFixture("testdata/profile/albums_by_singer.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedBase := filepath.Join(t.TempDir(), "albums.pb")
+	o = opts{SaveResultset: savedBase, SaveResultsetFormat: "binary"}
+	if err := o.saveResultSet(resultset.Header{SQL: "SELECT * FROM Albums WHERE SingerId = @sid"}, base); err != nil {
+		t.Fatalf("saveResultSet() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+		// contains makes want a substring of the output.
+		contains bool
+		wantErr  error
+	}{
+		{
+			name: "csv",
+			args: []string{"--format=csv"},
+			want: "SingerId,FirstName\n1,Marc\n2,Catalina\n3,Alice\n",
+		},
+		{
+			name: "json",
+			args: []string{"--format=json", "--compact-output", "--jq-row-shape=object", "--filter=.rows | map(.FirstName)"},
+			want: "[\"Marc\",\"Catalina\",\"Alice\"]\n",
+		},
+		{
+			name: "redacted",
+			args: []string{"--format=csv", "--redact-rows"},
+			want: "SingerId,FirstName\n",
+		},
+		{
+			name: "codegen",
+			args: []string{"--format=codegen", "--codegen-lang=typescript"},
+			want: "// Code generated by execspansql. DO NOT EDIT.\n\n/** Row is a row of the query result. */\nexport interface Row {\n  SingerId: string | null;\n  FirstName: string | null;\n}\n",
+		},
+		{
+			name:     "plan-diff",
+			args:     []string{"--format=plan-diff", "--plan-diff-base", saved},
+			want:     "\nOperators: 0 changed, 0 added, 0 removed\n",
+			contains: true,
+		},
+		{
+			name:     "plan-diff two files",
+			args:     []string{"--format=plan-diff", "--plan-diff-base", savedBase},
+			want:     "   scan_target: Albums -> Singers\n",
+			contains: true,
+		},
+		{
+			name:     "plan-check",
+			args:     []string{"--format=plan-check", "--plan-check=testdata/plan_check.yaml"},
+			want:     `{"rule":"full_scan","severity":"error","node":5,"operator":"Table Scan","message":"full scan of Singers"}` + "\n",
+			contains: true,
+			wantErr:  plancheck.ErrViolations,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var o opts
+			parser, err := kong.New(&o, kong.Name("execspansql"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := parser.Parse(append([]string{"--from-resultset", saved}, tc.args...)); err != nil {
+				t.Fatal(err)
+			}
+			if err := o.validateSource(); err != nil {
+				t.Fatal(err)
+			}
+
+			header, rs, err := loadResultSet("--from-resultset", o.FromResultset)
+			if err != nil {
+				t.Fatalf("loadResultSet() error = %v", err)
+			}
+			render, err := o.renderOptions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			newWriter, err := o.rowWriter(render)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, err := jqresult.Compile(cmp.Or(o.JqFilter, "."), jqresult.InputEager)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := writeResultSet(o, &buf, header, rs, newWriter, code, render); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("writeResultSet() error = %v, want %v", err, tc.wantErr)
+			}
+			if got := buf.String(); got != tc.want && !(tc.contains && strings.Contains(got, tc.want)) {
+				t.Errorf("output = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateSource(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		o       opts
+		wantErr bool
+	}{
+		{"query", opts{Database: "db", Project: "p", Instance: "i", Sql: "SELECT 1"}, false},
+		{"missing database", opts{Project: "p", Instance: "i", Sql: "SELECT 1"}, true},
+		{"missing sql", opts{Database: "db", Project: "p", Instance: "i"}, true},
+		{"from resultset", opts{FromResultset: "rs.pb"}, false},
+		{"from resultset with sql", opts{FromResultset: "rs.pb", Sql: "SELECT 1"}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tc.o.validateSource(); (err != nil) != tc.wantErr {
+				t.Errorf("validateSource() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
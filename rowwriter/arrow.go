@@ -0,0 +1,285 @@
+package rowwriter
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/civil"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apstndb/execspansql/jqresult"
+	"github.com/apstndb/execspansql/valuefmt"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Spanner NUMERIC has 29 integer digits and 9 fractional digits, which fits DECIMAL(38, 9).
+const (
+	numericPrecision = 38
+	numericScale     = 9
+)
+
+// arrowTypeMetadataKey carries the Spanner type of each column, which the Arrow
+// type alone cannot always tell apart (e.g. STRING, JSON and UUID are all strings).
+const arrowTypeMetadataKey = "spanner.type"
+
+// File or stream level metadata recorded by the columnar writers.
+const (
+	sqlMetadataKey           = "execspansql.sql"
+	readTimestampMetadataKey = "execspansql.read_timestamp"
+)
+
+// queryMetadata returns the key-value metadata describing the query: the SQL
+// text and, when Spanner returns it, the read timestamp.
+func queryMetadata(sql string, metadata *sppb.ResultSetMetadata) (keys, values []string) {
+	keys, values = []string{sqlMetadataKey}, []string{sql}
+	if ts := metadata.GetTransaction().GetReadTimestamp(); ts != nil {
+		keys = append(keys, readTimestampMetadataKey)
+		values = append(values, ts.AsTime().Format(time.RFC3339Nano))
+	}
+	return keys, values
+}
+
+// arrowFields maps a row type to Arrow fields for the columnar writers.
+// Field names are made unique with [jqresult.ObjectKeys].
+//
+//	BOOL -> bool, INT64/ENUM -> int64, FLOAT32/FLOAT64 -> float32/float64,
+//	NUMERIC -> decimal128(38, 9), DATE -> date32, TIMESTAMP -> timestamp[us, UTC],
+//	BYTES/PROTO -> binary, ARRAY -> list, STRUCT -> struct, other types -> utf8.
+func arrowFields(fields []*sppb.StructType_Field) ([]arrow.Field, error) {
+	names := jqresult.ObjectKeys(fields)
+	out := make([]arrow.Field, len(fields))
+	for i, f := range fields {
+		typ, err := arrowType(f.GetType())
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", names[i], err)
+		}
+		out[i] = arrow.Field{
+			Name:     names[i],
+			Type:     typ,
+			Nullable: true,
+			Metadata: arrow.NewMetadata([]string{arrowTypeMetadataKey}, []string{valuefmt.TypeString(f.GetType())}),
+		}
+	}
+	return out, nil
+}
+
+func arrowType(typ *sppb.Type) (arrow.DataType, error) {
+	switch typ.GetCode() {
+	case sppb.TypeCode_BOOL:
+		return arrow.FixedWidthTypes.Boolean, nil
+	case sppb.TypeCode_INT64, sppb.TypeCode_ENUM:
+		return arrow.PrimitiveTypes.Int64, nil
+	case sppb.TypeCode_FLOAT64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case sppb.TypeCode_FLOAT32:
+		return arrow.PrimitiveTypes.Float32, nil
+	case sppb.TypeCode_NUMERIC:
+		if typ.GetTypeAnnotation() == sppb.TypeAnnotationCode_PG_NUMERIC {
+			// PostgreSQL NUMERIC has arbitrary precision and NaN.
+			return arrow.BinaryTypes.String, nil
+		}
+		return &arrow.Decimal128Type{Precision: numericPrecision, Scale: numericScale}, nil
+	case sppb.TypeCode_DATE:
+		return arrow.FixedWidthTypes.Date32, nil
+	case sppb.TypeCode_TIMESTAMP:
+		return arrow.FixedWidthTypes.Timestamp_us, nil
+	case sppb.TypeCode_BYTES, sppb.TypeCode_PROTO:
+		return arrow.BinaryTypes.Binary, nil
+	case sppb.TypeCode_STRING, sppb.TypeCode_JSON, sppb.TypeCode_UUID, sppb.TypeCode_INTERVAL:
+		return arrow.BinaryTypes.String, nil
+	case sppb.TypeCode_ARRAY:
+		elem, err := arrowType(typ.GetArrayElementType())
+		if err != nil {
+			return nil, err
+		}
+		return arrow.ListOf(elem), nil
+	case sppb.TypeCode_STRUCT:
+		fields, err := arrowFields(typ.GetStructType().GetFields())
+		if err != nil {
+			return nil, err
+		}
+		return arrow.StructOf(fields...), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", valuefmt.TypeString(typ))
+	}
+}
+
+// appendArrowValue appends v of type typ to a builder created for arrowType(typ).
+func appendArrowValue(b array.Builder, typ *sppb.Type, v *structpb.Value) error {
+	if isNull(v) {
+		b.AppendNull()
+		return nil
+	}
+
+	switch b := b.(type) {
+	case *array.BooleanBuilder:
+		k, ok := v.GetKind().(*structpb.Value_BoolValue)
+		if !ok {
+			return valueKindError(typ, v)
+		}
+		b.Append(k.BoolValue)
+	case *array.Int64Builder:
+		n, err := strconv.ParseInt(v.GetStringValue(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s value: %w", valuefmt.TypeString(typ), err)
+		}
+		b.Append(n)
+	case *array.Float64Builder:
+		f, err := floatValue(typ, v)
+		if err != nil {
+			return err
+		}
+		b.Append(f)
+	case *array.Float32Builder:
+		f, err := floatValue(typ, v)
+		if err != nil {
+			return err
+		}
+		b.Append(float32(f))
+	case *array.Decimal128Builder:
+		n, err := decimal128.FromString(v.GetStringValue(), numericPrecision, numericScale)
+		if err != nil {
+			return fmt.Errorf("invalid NUMERIC value: %w", err)
+		}
+		b.Append(n)
+	case *array.Date32Builder:
+		d, err := civil.ParseDate(v.GetStringValue())
+		if err != nil {
+			return fmt.Errorf("invalid DATE value: %w", err)
+		}
+		b.Append(arrow.Date32FromTime(d.In(time.UTC)))
+	case *array.TimestampBuilder:
+		t, err := time.Parse(time.RFC3339Nano, v.GetStringValue())
+		if err != nil {
+			return fmt.Errorf("invalid TIMESTAMP value: %w", err)
+		}
+		b.Append(arrow.Timestamp(t.UnixMicro()))
+	case *array.BinaryBuilder:
+		raw, err := decodeBytes(typ, v)
+		if err != nil {
+			return err
+		}
+		b.Append(raw)
+	case *array.StringBuilder:
+		k, ok := v.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return valueKindError(typ, v)
+		}
+		b.Append(k.StringValue)
+	case *array.ListBuilder:
+		k, ok := v.GetKind().(*structpb.Value_ListValue)
+		if !ok {
+			return valueKindError(typ, v)
+		}
+		b.Append(true)
+		for _, e := range k.ListValue.GetValues() {
+			if err := appendArrowValue(b.ValueBuilder(), typ.GetArrayElementType(), e); err != nil {
+				return err
+			}
+		}
+	case *array.StructBuilder:
+		k, ok := v.GetKind().(*structpb.Value_ListValue)
+		if !ok {
+			return valueKindError(typ, v)
+		}
+		fields := typ.GetStructType().GetFields()
+		values := k.ListValue.GetValues()
+		if len(fields) != len(values) {
+			return fmt.Errorf("%s has %d fields but value has %d", valuefmt.TypeString(typ), len(fields), len(values))
+		}
+		b.Append(true)
+		for i, e := range values {
+			if err := appendArrowValue(b.FieldBuilder(i), fields[i].GetType(), e); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unexpected builder %T for %s", b, valuefmt.TypeString(typ))
+	}
+	return nil
+}
+
+// floatValue decodes FLOAT32/FLOAT64, which carry NaN and infinities as strings.
+func floatValue(typ *sppb.Type, v *structpb.Value) (float64, error) {
+	switch k := v.GetKind().(type) {
+	case *structpb.Value_NumberValue:
+		return k.NumberValue, nil
+	case *structpb.Value_StringValue:
+		switch k.StringValue {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+	}
+	return 0, valueKindError(typ, v)
+}
+
+// decodeBytes decodes BYTES/PROTO, which are base64 in the wire format.
+func decodeBytes(typ *sppb.Type, v *structpb.Value) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(v.GetStringValue())
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value: %w", valuefmt.TypeString(typ), err)
+	}
+	return raw, nil
+}
+
+func valueKindError(typ *sppb.Type, v *structpb.Value) error {
+	return fmt.Errorf("unexpected %T for %s", v.GetKind(), valuefmt.TypeString(typ))
+}
+
+// arrowBatch accumulates rows into Arrow record batches.
+type arrowBatch struct {
+	fields  []*sppb.StructType_Field
+	schema  *arrow.Schema
+	builder *array.RecordBuilder
+	rows    int
+}
+
+func newArrowBatch(fields []*sppb.StructType_Field, metadata *arrow.Metadata) (*arrowBatch, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("the statement returns no columns")
+	}
+	arrFields, err := arrowFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	schema := arrow.NewSchema(arrFields, metadata)
+	return &arrowBatch{
+		fields:  fields,
+		schema:  schema,
+		builder: array.NewRecordBuilder(memory.DefaultAllocator, schema),
+	}, nil
+}
+
+func (a *arrowBatch) append(values []*structpb.Value) error {
+	if len(a.fields) != len(values) {
+		return fmt.Errorf("row has %d values but row type has %d fields", len(values), len(a.fields))
+	}
+	for i, v := range values {
+		if err := appendArrowValue(a.builder.Field(i), a.fields[i].GetType(), v); err != nil {
+			return fmt.Errorf("column %s: %w", a.schema.Field(i).Name, err)
+		}
+	}
+	a.rows++
+	return nil
+}
+
+// flush returns the buffered rows as a record batch and resets the builder.
+// The caller must Release the record.
+func (a *arrowBatch) flush() arrow.RecordBatch {
+	a.rows = 0
+	return a.builder.NewRecordBatch()
+}
+
+func (a *arrowBatch) release() {
+	a.builder.Release()
+}
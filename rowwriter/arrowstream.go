@@ -0,0 +1,67 @@
+package rowwriter
+
+import (
+	"fmt"
+	"io"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ArrowStream writes rows as an Arrow IPC stream with the type mapping of
+// [arrowFields]. A record batch is written every batchRows rows, so consumers
+// can start reading before the query completes.
+//
+// The SQL text and the read timestamp are recorded in the schema metadata.
+type ArrowStream struct {
+	w         io.Writer
+	sql       string
+	batchRows int
+	batch     *arrowBatch
+	iw        *ipc.Writer
+}
+
+func NewArrowStream(w io.Writer, sql string, batchRows int) *ArrowStream {
+	return &ArrowStream{w: w, sql: sql, batchRows: max(batchRows, 1)}
+}
+
+func (a *ArrowStream) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	md := arrow.NewMetadata(queryMetadata(a.sql, metadata))
+	batch, err := newArrowBatch(metadata.GetRowType().GetFields(), &md)
+	if err != nil {
+		return fmt.Errorf("arrow: %w", err)
+	}
+	a.batch = batch
+	a.iw = ipc.NewWriter(a.w, ipc.WithSchema(batch.schema), ipc.WithAllocator(memory.DefaultAllocator))
+	return nil
+}
+
+func (a *ArrowStream) WriteRow(values []*structpb.Value) error {
+	if err := a.batch.append(values); err != nil {
+		return err
+	}
+	if a.batch.rows >= a.batchRows {
+		return a.writeBatch()
+	}
+	return nil
+}
+
+func (a *ArrowStream) writeBatch() error {
+	rec := a.batch.flush()
+	defer rec.Release()
+	return a.iw.Write(rec)
+}
+
+func (a *ArrowStream) Finish(*sppb.ResultSetStats) error {
+	defer a.batch.release()
+	if a.batch.rows > 0 {
+		if err := a.writeBatch(); err != nil {
+			return err
+		}
+	}
+	// Close writes the end-of-stream marker, and the schema when no batch was written.
+	return a.iw.Close()
+}
@@ -0,0 +1,65 @@
+package rowwriter
+
+import (
+	"bytes"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestArrowStream(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewArrowStream(&buf, "SELECT 1", 2), columnarFixture()); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+
+	rdr, err := ipc.NewReader(&buf, ipc.WithAllocator(memory.DefaultAllocator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Release()
+
+	md := rdr.Schema().Metadata()
+	if got, _ := md.GetValue(sqlMetadataKey); got != "SELECT 1" {
+		t.Errorf("%s = %q, want SELECT 1", sqlMetadataKey, got)
+	}
+	if got, _ := md.GetValue(readTimestampMetadataKey); got != "2024-06-01T00:00:00Z" {
+		t.Errorf("%s = %q, want 2024-06-01T00:00:00Z", readTimestampMetadataKey, got)
+	}
+
+	var batchRows []int64
+	for rdr.Next() {
+		batchRows = append(batchRows, rdr.RecordBatch().NumRows())
+	}
+	if err := rdr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(batchRows) != 2 || batchRows[0] != 2 || batchRows[1] != 1 {
+		t.Errorf("batch rows = %v, want [2 1]", batchRows)
+	}
+}
+
+func TestArrowStreamEmpty(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	rs := resultSet([]string{"x"}, []*sppb.Type{{Code: sppb.TypeCode_INT64}}, nil)
+	if err := WriteResultSet(NewArrowStream(&buf, "SELECT 1 LIMIT 0", 10), rs); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	rdr, err := ipc.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Release()
+	if got := rdr.Schema().Field(0).Name; got != "x" {
+		t.Errorf("field name = %q, want x", got)
+	}
+	if rdr.Next() {
+		t.Error("expected no record batches")
+	}
+}
@@ -0,0 +1,334 @@
+package rowwriter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/jqresult"
+	"github.com/apstndb/execspansql/valuefmt"
+	"github.com/linkedin/goavro/v2"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// avroRecordName is the name of the top-level record; nested STRUCT records
+// are named after their field path, such as Row_st.
+const avroRecordName = "Row"
+
+// avroType is the schema of a Spanner type and the union branch name that
+// goavro uses for it in ["null", T].
+type avroType struct {
+	schema any
+	branch string
+	typ    *sppb.Type
+	// fields and names are set for records, items for arrays.
+	fields []avroType
+	names  []string
+	items  *avroType
+}
+
+type avroField struct {
+	Name    string          `json:"name"`
+	Type    any             `json:"type"`
+	Default json.RawMessage `json:"default"`
+}
+
+// avroSchemaBuilder keeps record names unique within a schema.
+type avroSchemaBuilder struct {
+	used map[string]bool
+}
+
+// avroRecord maps STRUCT fields to an Avro record whose fields are all nullable unions.
+//
+//	BOOL -> boolean, INT64/ENUM -> long, FLOAT32/FLOAT64 -> float/double,
+//	NUMERIC -> bytes decimal(38, 9), DATE -> int date, TIMESTAMP -> long timestamp-micros,
+//	UUID -> string uuid, BYTES/PROTO -> bytes, ARRAY -> array, STRUCT -> record,
+//	other types -> string.
+func (b *avroSchemaBuilder) avroRecord(name string, typ *sppb.Type) (avroType, error) {
+	name = b.uniqueName(name)
+	fields := typ.GetStructType().GetFields()
+	rec := avroType{branch: name, typ: typ, fields: make([]avroType, len(fields)), names: avroFieldNames(fields)}
+	schemaFields := make([]avroField, len(fields))
+	for i, f := range fields {
+		ft, err := b.avroType(name+"_"+rec.names[i], f.GetType())
+		if err != nil {
+			return avroType{}, fmt.Errorf("column %s: %w", rec.names[i], err)
+		}
+		rec.fields[i] = ft
+		schemaFields[i] = avroField{Name: rec.names[i], Type: []any{"null", ft.schema}, Default: json.RawMessage("null")}
+	}
+	rec.schema = map[string]any{"type": "record", "name": name, "fields": schemaFields}
+	return rec, nil
+}
+
+func (b *avroSchemaBuilder) avroType(name string, typ *sppb.Type) (avroType, error) {
+	primitive := func(schema string) (avroType, error) {
+		return avroType{schema: schema, branch: schema, typ: typ}, nil
+	}
+	logical := func(base, logicalType string, branch string) (avroType, error) {
+		return avroType{schema: map[string]any{"type": base, "logicalType": logicalType}, branch: branch, typ: typ}, nil
+	}
+
+	switch typ.GetCode() {
+	case sppb.TypeCode_BOOL:
+		return primitive("boolean")
+	case sppb.TypeCode_INT64, sppb.TypeCode_ENUM:
+		return primitive("long")
+	case sppb.TypeCode_FLOAT64:
+		return primitive("double")
+	case sppb.TypeCode_FLOAT32:
+		return primitive("float")
+	case sppb.TypeCode_NUMERIC:
+		if typ.GetTypeAnnotation() == sppb.TypeAnnotationCode_PG_NUMERIC {
+			// PostgreSQL NUMERIC has arbitrary precision and NaN.
+			return primitive("string")
+		}
+		return avroType{
+			schema: map[string]any{"type": "bytes", "logicalType": "decimal", "precision": numericPrecision, "scale": numericScale},
+			branch: "bytes.decimal",
+			typ:    typ,
+		}, nil
+	case sppb.TypeCode_DATE:
+		return logical("int", "date", "int.date")
+	case sppb.TypeCode_TIMESTAMP:
+		return logical("long", "timestamp-micros", "long.timestamp-micros")
+	case sppb.TypeCode_UUID:
+		// goavro has no codec for uuid and encodes it as a plain string.
+		return logical("string", "uuid", "string")
+	case sppb.TypeCode_BYTES, sppb.TypeCode_PROTO:
+		return primitive("bytes")
+	case sppb.TypeCode_STRING, sppb.TypeCode_JSON, sppb.TypeCode_INTERVAL:
+		return primitive("string")
+	case sppb.TypeCode_ARRAY:
+		elem, err := b.avroType(name, typ.GetArrayElementType())
+		if err != nil {
+			return avroType{}, err
+		}
+		return avroType{
+			schema: map[string]any{"type": "array", "items": []any{"null", elem.schema}},
+			branch: "array",
+			typ:    typ,
+			items:  &elem,
+		}, nil
+	case sppb.TypeCode_STRUCT:
+		return b.avroRecord(name, typ)
+	default:
+		return avroType{}, fmt.Errorf("unsupported type %s", valuefmt.TypeString(typ))
+	}
+}
+
+func (b *avroSchemaBuilder) uniqueName(name string) string {
+	unique := name
+	for i := 2; b.used[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	b.used[unique] = true
+	return unique
+}
+
+// avroFieldNames returns unique field names restricted to [A-Za-z_][A-Za-z0-9_]*,
+// as Avro requires.
+func avroFieldNames(fields []*sppb.StructType_Field) []string {
+	names := jqresult.ObjectKeys(fields)
+	used := make(map[string]bool, len(names))
+	for i, n := range names {
+		n = strings.Map(func(r rune) rune {
+			if r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+				return r
+			}
+			return '_'
+		}, n)
+		if n == "" || '0' <= n[0] && n[0] <= '9' {
+			n = "_" + n
+		}
+		unique := n
+		for j := 2; used[unique]; j++ {
+			unique = n + "_" + strconv.Itoa(j)
+		}
+		used[unique] = true
+		names[i] = unique
+	}
+	return names
+}
+
+// native converts v to the goavro native form of t, wrapped in its nullable union.
+func (t avroType) native(v *structpb.Value) (any, error) {
+	if isNull(v) {
+		return nil, nil
+	}
+	datum, err := t.datum(v)
+	if err != nil {
+		return nil, err
+	}
+	return goavro.Union(t.branch, datum), nil
+}
+
+func (t avroType) datum(v *structpb.Value) (any, error) {
+	switch t.typ.GetCode() {
+	case sppb.TypeCode_BOOL:
+		k, ok := v.GetKind().(*structpb.Value_BoolValue)
+		if !ok {
+			return nil, valueKindError(t.typ, v)
+		}
+		return k.BoolValue, nil
+	case sppb.TypeCode_INT64, sppb.TypeCode_ENUM:
+		n, err := strconv.ParseInt(v.GetStringValue(), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value: %w", valuefmt.TypeString(t.typ), err)
+		}
+		return n, nil
+	case sppb.TypeCode_FLOAT64:
+		return floatValue(t.typ, v)
+	case sppb.TypeCode_FLOAT32:
+		f, err := floatValue(t.typ, v)
+		return float32(f), err
+	case sppb.TypeCode_NUMERIC:
+		if t.branch == "string" {
+			return v.GetStringValue(), nil
+		}
+		r, ok := new(big.Rat).SetString(v.GetStringValue())
+		if !ok {
+			return nil, fmt.Errorf("invalid NUMERIC value %q", v.GetStringValue())
+		}
+		return r, nil
+	case sppb.TypeCode_DATE:
+		d, err := civil.ParseDate(v.GetStringValue())
+		if err != nil {
+			return nil, fmt.Errorf("invalid DATE value: %w", err)
+		}
+		return d.In(time.UTC), nil
+	case sppb.TypeCode_TIMESTAMP:
+		ts, err := time.Parse(time.RFC3339Nano, v.GetStringValue())
+		if err != nil {
+			return nil, fmt.Errorf("invalid TIMESTAMP value: %w", err)
+		}
+		return ts, nil
+	case sppb.TypeCode_BYTES, sppb.TypeCode_PROTO:
+		return decodeBytes(t.typ, v)
+	case sppb.TypeCode_ARRAY:
+		k, ok := v.GetKind().(*structpb.Value_ListValue)
+		if !ok {
+			return nil, valueKindError(t.typ, v)
+		}
+		items := make([]any, len(k.ListValue.GetValues()))
+		for i, e := range k.ListValue.GetValues() {
+			item, err := t.items.native(e)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case sppb.TypeCode_STRUCT:
+		k, ok := v.GetKind().(*structpb.Value_ListValue)
+		if !ok {
+			return nil, valueKindError(t.typ, v)
+		}
+		return t.record(k.ListValue.GetValues())
+	default:
+		k, ok := v.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return nil, valueKindError(t.typ, v)
+		}
+		return k.StringValue, nil
+	}
+}
+
+func (t avroType) record(values []*structpb.Value) (map[string]any, error) {
+	if len(t.fields) != len(values) {
+		return nil, fmt.Errorf("%s has %d fields but value has %d", valuefmt.TypeString(t.typ), len(t.fields), len(values))
+	}
+	rec := make(map[string]any, len(values))
+	for i, v := range values {
+		field, err := t.fields[i].native(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", t.names[i], err)
+		}
+		rec[t.names[i]] = field
+	}
+	return rec, nil
+}
+
+// Avro writes rows as a Snappy-compressed Avro Object Container File. The
+// schema is a record derived from the row type (see [avroSchemaBuilder.avroRecord]),
+// whose fields are ["null", T] unions. Rows are written in blocks of
+// blockRows rows.
+//
+// The SQL text and the read timestamp are recorded in the file metadata.
+type Avro struct {
+	w         io.Writer
+	sql       string
+	blockRows int
+	row       avroType
+	ocf       *goavro.OCFWriter
+	pending   []any
+}
+
+func NewAvro(w io.Writer, sql string, blockRows int) *Avro {
+	return &Avro{w: w, sql: sql, blockRows: max(blockRows, 1)}
+}
+
+func (a *Avro) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	rowType := &sppb.Type{Code: sppb.TypeCode_STRUCT, StructType: metadata.GetRowType()}
+	if len(rowType.GetStructType().GetFields()) == 0 {
+		return fmt.Errorf("avro: the statement returns no columns")
+	}
+	b := avroSchemaBuilder{used: make(map[string]bool)}
+	row, err := b.avroRecord(avroRecordName, rowType)
+	if err != nil {
+		return fmt.Errorf("avro: %w", err)
+	}
+	schema, err := json.Marshal(row.schema)
+	if err != nil {
+		return err
+	}
+
+	keys, values := queryMetadata(a.sql, metadata)
+	md := make(map[string][]byte, len(keys))
+	for i, k := range keys {
+		md[k] = []byte(values[i])
+	}
+	// Hide *os.File, which goavro would otherwise try to append to.
+	ocf, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               struct{ io.Writer }{a.w},
+		Schema:          string(schema),
+		CompressionName: goavro.CompressionSnappyLabel,
+		MetaData:        md,
+	})
+	if err != nil {
+		return err
+	}
+	a.row, a.ocf = row, ocf
+	return nil
+}
+
+func (a *Avro) WriteRow(values []*structpb.Value) error {
+	rec, err := a.row.record(values)
+	if err != nil {
+		return err
+	}
+	a.pending = append(a.pending, rec)
+	if len(a.pending) >= a.blockRows {
+		return a.writeBlock()
+	}
+	return nil
+}
+
+func (a *Avro) writeBlock() error {
+	err := a.ocf.Append(a.pending)
+	a.pending = a.pending[:0]
+	return err
+}
+
+func (a *Avro) Finish(*sppb.ResultSetStats) error {
+	if len(a.pending) > 0 {
+		return a.writeBlock()
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package rowwriter
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestAvroRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewAvro(&buf, "SELECT 1", 2), columnarFixture()); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+
+	ocfr, err := goavro.NewOCFReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := ocfr.MetaData()
+	if got := string(md[sqlMetadataKey]); got != "SELECT 1" {
+		t.Errorf("%s = %q, want SELECT 1", sqlMetadataKey, got)
+	}
+	if got := string(md[readTimestampMetadataKey]); got != "2024-06-01T00:00:00Z" {
+		t.Errorf("%s = %q, want 2024-06-01T00:00:00Z", readTimestampMetadataKey, got)
+	}
+
+	var rows []any
+	for ocfr.Scan() {
+		row, err := ocfr.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+	if err := ocfr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+
+	want := map[string]any{
+		"id":    goavro.Union("long", int64(1)),
+		"n":     goavro.Union("bytes.decimal", big.NewRat(25, 2)),
+		"f":     goavro.Union("double", 1.5),
+		"b":     goavro.Union("boolean", true),
+		"d":     goavro.Union("int.date", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)),
+		"ts":    goavro.Union("long.timestamp-micros", time.Date(2024, 6, 1, 12, 34, 56, 123456000, time.UTC)),
+		"bytes": goavro.Union("bytes", []byte{0x00, 0x01, 0xff}),
+		"json":  goavro.Union("string", `{"a":1}`),
+		"tags":  goavro.Union("array", []any{goavro.Union("string", "x"), nil}),
+		"st": goavro.Union("Row_st", map[string]any{
+			"i":  goavro.Union("long", int64(7)),
+			"_2": goavro.Union("string", "s"),
+		}),
+	}
+	if diff := cmp.Diff(want, rows[0], cmp.Comparer(func(x, y *big.Rat) bool { return x.Cmp(y) == 0 })); diff != "" {
+		t.Errorf("row 0 mismatch (-want +got):\n%s", diff)
+	}
+	if got := rows[1].(map[string]any)["n"]; got != nil {
+		t.Errorf("row 1 n = %v, want nil", got)
+	}
+}
+
+func TestAvroFieldNames(t *testing.T) {
+	t.Parallel()
+
+	fields := []*sppb.StructType_Field{{Name: "a-b"}, {Name: "a_b"}, {Name: "1x"}, {}, {Name: "名前"}}
+	want := []string{"a_b", "a_b_2", "_1x", "_4", "__"}
+	if diff := cmp.Diff(want, avroFieldNames(fields)); diff != "" {
+		t.Errorf("avroFieldNames() mismatch (-want +got):\n%s", diff)
+	}
+}
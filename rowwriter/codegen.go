@@ -0,0 +1,46 @@
+package rowwriter
+
+import (
+	"cmp"
+	"io"
+	"slices"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/codegen"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Codegen writes type declarations of the rows and the query parameters
+// generated by [codegen.Generate] instead of the rows, which are ignored.
+// The parameters are params, the ones given with the query, together with
+// the undeclared parameters whose types the query reports in PLAN mode.
+type Codegen struct {
+	w        io.Writer
+	opts     codegen.Options
+	params   []*sppb.StructType_Field
+	metadata *sppb.ResultSetMetadata
+}
+
+func NewCodegen(w io.Writer, opts codegen.Options, params []*sppb.StructType_Field) *Codegen {
+	return &Codegen{w: w, opts: opts, params: params}
+}
+
+func (c *Codegen) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	c.metadata = metadata
+	return nil
+}
+
+func (c *Codegen) WriteRow([]*structpb.Value) error {
+	return nil
+}
+
+func (c *Codegen) Finish(*sppb.ResultSetStats) error {
+	params := slices.Concat(c.params, c.metadata.GetUndeclaredParameters().GetFields())
+	slices.SortStableFunc(params, func(a, b *sppb.StructType_Field) int { return cmp.Compare(a.GetName(), b.GetName()) })
+	src, err := codegen.Generate(c.opts, c.metadata.GetRowType(), params)
+	if err != nil {
+		return err
+	}
+	_, err = c.w.Write(src)
+	return err
+}
@@ -0,0 +1,158 @@
+package rowwriter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/jqresult"
+	"github.com/apstndb/execspansql/valuefmt"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// CompositeFormat selects how ARRAY and STRUCT values are rendered in a CSV cell.
+type CompositeFormat string
+
+const (
+	// CompositeText renders [a, b] and (v AS name), as experimental_csv does.
+	CompositeText CompositeFormat = "text"
+	// CompositeJSON renders ARRAY as a JSON array and STRUCT as a JSON object.
+	CompositeJSON CompositeFormat = "json"
+	// CompositeSQL renders GoogleSQL literals such as ARRAY<INT64>[1, 2] (see [valuefmt.Literal]).
+	CompositeSQL CompositeFormat = "sql"
+)
+
+func ParseCompositeFormat(s string) (CompositeFormat, error) {
+	switch CompositeFormat(s) {
+	case CompositeText, CompositeJSON, CompositeSQL:
+		return CompositeFormat(s), nil
+	default:
+		return "", fmt.Errorf("csv-composite must be text, json or sql")
+	}
+}
+
+// ParseCSVDelimiter parses a field delimiter: a single character, or \t or "tab" for TSV.
+func ParseCSVDelimiter(s string) (rune, error) {
+	switch s {
+	case `\t`, "tab":
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("csv-delimiter must be a single character other than a quote or newline, got %q", s)
+	}
+	return r, nil
+}
+
+// CSVOptions is the dialect of a [CSV] writer.
+type CSVOptions struct {
+	Delimiter rune
+	// NoHeader omits the column name row (and the type row).
+	NoHeader bool
+	// NullString is written for NULL at any nesting level in text rendering.
+	NullString string
+	// BOM writes a UTF-8 byte order mark first, so that Excel detects the encoding.
+	BOM bool
+	// TypeHeader writes the column types as a second header row.
+	TypeHeader bool
+	Composite  CompositeFormat
+}
+
+// DefaultCSVOptions returns the dialect of experimental_csv.
+func DefaultCSVOptions() CSVOptions {
+	return CSVOptions{Delimiter: ',', NullString: "<null>", Composite: CompositeText}
+}
+
+// CSV writes rows as RFC 4180 delimited text. Scalars are formatted as in
+// experimental_csv; ARRAY and STRUCT follow [CSVOptions.Composite].
+type CSV struct {
+	w      *bufio.Writer
+	cw     *csv.Writer
+	opts   CSVOptions
+	format valuefmt.Config
+	fields []*sppb.StructType_Field
+}
+
+func NewCSV(w io.Writer, opts CSVOptions) *CSV {
+	bw := bufio.NewWriter(w)
+	cw := csv.NewWriter(bw)
+	cw.Comma = opts.Delimiter
+	return &CSV{w: bw, cw: cw, opts: opts, format: valuefmt.Config{Null: opts.NullString}}
+}
+
+func (c *CSV) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	c.fields = metadata.GetRowType().GetFields()
+	if c.opts.BOM {
+		c.w.WriteString("\uFEFF")
+	}
+	if c.opts.NoHeader || len(c.fields) == 0 {
+		return nil
+	}
+	names := make([]string, len(c.fields))
+	types := make([]string, len(c.fields))
+	for i, f := range c.fields {
+		names[i] = f.GetName()
+		types[i] = valuefmt.TypeString(f.GetType())
+	}
+	if err := c.cw.Write(names); err != nil {
+		return err
+	}
+	if c.opts.TypeHeader {
+		return c.cw.Write(types)
+	}
+	return nil
+}
+
+func (c *CSV) WriteRow(values []*structpb.Value) error {
+	if len(c.fields) != len(values) {
+		return fmt.Errorf("row has %d values but row type has %d fields", len(values), len(c.fields))
+	}
+	record := make([]string, len(values))
+	for i, v := range values {
+		s, err := c.formatValue(c.fields[i].GetType(), v)
+		if err != nil {
+			return fmt.Errorf("column %d (%s): %w", i, c.fields[i].GetName(), err)
+		}
+		record[i] = s
+	}
+	return c.cw.Write(record)
+}
+
+func (c *CSV) formatValue(typ *sppb.Type, v *structpb.Value) (string, error) {
+	composite := typ.GetCode() == sppb.TypeCode_ARRAY || typ.GetCode() == sppb.TypeCode_STRUCT
+	if !composite || isNull(v) || c.opts.Composite == CompositeText {
+		return c.format.Format(typ, v)
+	}
+	if c.opts.Composite == CompositeSQL {
+		return valuefmt.Literal(typ, v)
+	}
+	return jsonText(typ, v)
+}
+
+// jsonText renders v as JSON text, with STRUCT as an object keyed by field name.
+func jsonText(typ *sppb.Type, v *structpb.Value) (string, error) {
+	obj, err := jqresult.StructValuesToObject([]*sppb.StructType_Field{{Type: typ}}, []*structpb.Value{v})
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(obj.Values[0]); err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+}
+
+func (c *CSV) Finish(*sppb.ResultSetStats) error {
+	c.cw.Flush()
+	if err := c.cw.Error(); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
@@ -0,0 +1,79 @@
+package rowwriter
+
+import (
+	"bytes"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func compositeFixture() *sppb.ResultSet {
+	structType := &sppb.Type{
+		Code: sppb.TypeCode_STRUCT,
+		StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+			{Name: "i", Type: &sppb.Type{Code: sppb.TypeCode_INT64}},
+			{Name: "s", Type: &sppb.Type{Code: sppb.TypeCode_STRING}},
+		}},
+	}
+	return resultSet(
+		[]string{"a", "st", "n"},
+		[]*sppb.Type{
+			{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: sppb.TypeCode_INT64}},
+			structType,
+			{Code: sppb.TypeCode_STRING},
+		},
+		[][]*structpb.Value{
+			{
+				structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("1"), structpb.NewNullValue()}}),
+				structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("7"), structpb.NewStringValue(`say "hi"`)}}),
+				structpb.NewNullValue(),
+			},
+			{structpb.NewNullValue(), structpb.NewNullValue(), structpb.NewStringValue("tab\there")},
+		},
+	)
+}
+
+func TestCSVGolden(t *testing.T) {
+	withOpts := func(f func(*CSVOptions)) CSVOptions {
+		opts := DefaultCSVOptions()
+		f(&opts)
+		return opts
+	}
+	tests := map[string]struct {
+		rs   *sppb.ResultSet
+		opts CSVOptions
+	}{
+		"singers":           {singersFixture(), DefaultCSVOptions()},
+		"singers_tsv":       {singersFixture(), withOpts(func(o *CSVOptions) { o.Delimiter = '\t'; o.NoHeader = true })},
+		"singers_excel":     {singersFixture(), withOpts(func(o *CSVOptions) { o.BOM = true; o.NullString = ""; o.TypeHeader = true })},
+		"composite_text":    {compositeFixture(), DefaultCSVOptions()},
+		"composite_json":    {compositeFixture(), withOpts(func(o *CSVOptions) { o.Composite = CompositeJSON })},
+		"composite_sql_tsv": {compositeFixture(), withOpts(func(o *CSVOptions) { o.Composite = CompositeSQL; o.Delimiter = '\t' })},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteResultSet(NewCSV(&buf, tc.opts), tc.rs); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			checkGolden(t, "csv", name, buf.Bytes())
+		})
+	}
+}
+
+func TestParseCSVDelimiter(t *testing.T) {
+	t.Parallel()
+
+	for s, want := range map[string]rune{",": ',', ";": ';', "|": '|', `\t`: '\t', "tab": '\t', "\t": '\t'} {
+		got, err := ParseCSVDelimiter(s)
+		if err != nil || got != want {
+			t.Errorf("ParseCSVDelimiter(%q) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", `"`, "\n", ",,"} {
+		if _, err := ParseCSVDelimiter(s); err == nil {
+			t.Errorf("ParseCSVDelimiter(%q) expected error", s)
+		}
+	}
+}
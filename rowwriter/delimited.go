@@ -0,0 +1,60 @@
+package rowwriter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	svwriter "github.com/apstndb/spanvalue/writer"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// delimitedCells renders row values with spanvalue's DelimitedWriter, as
+// experimental_csv does, and reads the cells back. spanvalue only exposes
+// its value formatting through delimited rows.
+type delimitedCells struct {
+	buf bytes.Buffer
+	w   *svwriter.DelimitedWriter
+}
+
+func newDelimitedCells(rowType *sppb.StructType) (*delimitedCells, error) {
+	c := &delimitedCells{}
+	w, err := svwriter.NewCSVWriter(&c.buf)
+	if err != nil {
+		return nil, err
+	}
+	// Write the header up front, so that each row is one record.
+	if err := w.PrepareRowType(rowType); err != nil {
+		return nil, err
+	}
+	c.w = w
+	return c, nil
+}
+
+// cells returns the text of each value of a row.
+func (c *delimitedCells) cells(values []*structpb.Value) ([]string, error) {
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	c.buf.Reset()
+	if err := c.w.WriteStructValues(values); err != nil {
+		return nil, err
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	records, err := csv.NewReader(&c.buf).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(records) == 1 && len(records[0]) == len(values):
+		return records[0], nil
+	case len(records) == 0 && len(values) == 1:
+		// A single empty field is written as an empty line, which csv skips.
+		return []string{""}, nil
+	default:
+		return nil, fmt.Errorf("spanvalue wrote %d records for a row of %d values", len(records), len(values))
+	}
+}
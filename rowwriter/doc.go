@@ -0,0 +1,7 @@
+// Package rowwriter renders Cloud Spanner query results one row at a time.
+//
+// A [Writer] receives result metadata, then row values in row type order, then
+// the final stats. [WriteRowIterator] drives a Writer from an unread
+// [cloud.google.com/go/spanner.RowIterator] without materializing a ResultSet;
+// [WriteResultSet] drives it from an in-memory [sppb.ResultSet].
+package rowwriter
@@ -0,0 +1,108 @@
+package rowwriter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/queryplan"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// errNoProfile is returned by the profile formats for results without
+// execution stats.
+var errNoProfile = errors.New("the result has no execution stats; run the query in PROFILE mode")
+
+// ProfileValue selects the execution stat that [Folded] reports.
+type ProfileValue string
+
+const (
+	ProfileLatency ProfileValue = "latency"
+	ProfileCPU     ProfileValue = "cpu"
+)
+
+// ParseProfileValue validates s as a ProfileValue.
+func ParseProfileValue(s string) (ProfileValue, error) {
+	switch ProfileValue(s) {
+	case ProfileLatency, ProfileCPU:
+		return ProfileValue(s), nil
+	default:
+		return "", fmt.Errorf("profile value must be latency or cpu")
+	}
+}
+
+// Folded renders the execution stats of a PROFILE plan instead of the rows,
+// which are ignored, as folded stacks for flame graph tools such as
+// flamegraph.pl, speedscope and inferno. Each line is the path of operators
+// from the root to a node, separated by semicolons, followed by the time
+// spent in the node itself in microseconds (see [queryplan.Plan.Samples]).
+// Nodes that took less than a microsecond are left out.
+type Folded struct {
+	w     *bufio.Writer
+	value ProfileValue
+}
+
+func NewFolded(w io.Writer, value ProfileValue) *Folded {
+	return &Folded{w: bufio.NewWriter(w), value: value}
+}
+
+func (f *Folded) WriteMetadata(*sppb.ResultSetMetadata) error {
+	return nil
+}
+
+func (f *Folded) WriteRow([]*structpb.Value) error {
+	return nil
+}
+
+func (f *Folded) Finish(stats *sppb.ResultSetStats) error {
+	plan, err := profilePlan(stats)
+	if err != nil {
+		return err
+	}
+	for _, s := range plan.Samples() {
+		d := s.Latency
+		if f.value == ProfileCPU {
+			d = s.CPU
+		}
+		// Integer counts cannot carry less than a microsecond; a zero count
+		// would only add a frame with no width.
+		us := d / time.Microsecond
+		if us <= 0 {
+			continue
+		}
+		frames := make([]string, len(s.Stack))
+		for i, n := range s.Stack {
+			frames[i] = strings.ReplaceAll(frameName(n), ";", ",")
+		}
+		fmt.Fprintf(f.w, "%s %d\n", strings.Join(frames, ";"), us)
+	}
+	return f.w.Flush()
+}
+
+// profilePlan returns the plan of a PROFILE result.
+func profilePlan(stats *sppb.ResultSetStats) (*queryplan.Plan, error) {
+	nodes := stats.GetQueryPlan().GetPlanNodes()
+	if len(nodes) == 0 {
+		return nil, errNoPlan
+	}
+	plan := queryplan.New(nodes)
+	if !plan.HasExecutionStats() {
+		return nil, errNoProfile
+	}
+	return plan, nil
+}
+
+// frameName names a plan node in stack traces by its operator, scan target
+// and ID, such as "Table Scan (Singers) #5". The ID keeps operators of the
+// same name apart.
+func frameName(n *sppb.PlanNode) string {
+	name := queryplan.Operator(n)
+	if target := queryplan.ScanTarget(n); target != "" {
+		name += " (" + target + ")"
+	}
+	return fmt.Sprintf("%s #%d", name, n.GetIndex())
+}
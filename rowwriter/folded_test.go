@@ -0,0 +1,64 @@
+package rowwriter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestFoldedGolden(t *testing.T) {
+	t.Parallel()
+
+	for _, value := range []ProfileValue{ProfileLatency, ProfileCPU} {
+		t.Run(string(value), func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := WriteResultSet(NewFolded(&buf, value), readProfile(t, "albums_by_singer")); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			checkGolden(t, "folded", string(value), buf.Bytes())
+		})
+	}
+}
+
+func TestFoldedSubMicrosecond(t *testing.T) {
+	t.Parallel()
+
+	latency := func(total string) *structpb.Struct {
+		return &structpb.Struct{Fields: map[string]*structpb.Value{
+			"latency": structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{"total": structpb.NewStringValue(total), "unit": structpb.NewStringValue("msecs")}}),
+		}}
+	}
+	rs := singersFixture()
+	rs.Stats.QueryPlan = &sppb.QueryPlan{PlanNodes: []*sppb.PlanNode{
+		{
+			Index: 0, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Serialize Result",
+			ChildLinks:     []*sppb.PlanNode_ChildLink{{ChildIndex: 1}},
+			ExecutionStats: latency("0.5005"),
+		},
+		{Index: 1, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Unit Relation", ExecutionStats: latency("0.5")},
+	}}
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewFolded(&buf, ProfileLatency), rs); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	if got, want := buf.String(), "Serialize Result #0;Unit Relation #1 500\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestFoldedRequiresProfile(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewFolded(&buf, ProfileLatency), planFixture(t)); !errors.Is(err, errNoProfile) {
+		t.Errorf("WriteResultSet(PLAN) error = %v, want %v", err, errNoProfile)
+	}
+	if err := WriteResultSet(NewFolded(&buf, ProfileLatency), singersFixture()); !errors.Is(err, errNoPlan) {
+		t.Errorf("WriteResultSet(NORMAL) error = %v, want %v", err, errNoPlan)
+	}
+}
@@ -0,0 +1,327 @@
+package rowwriter
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/queryplan"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// gcloudUnspecified is the header gcloud prints for columns without a name.
+const gcloudUnspecified = "(Unspecified)"
+
+// gcloudQueryStats are the columns of the aggregate stats box that gcloud
+// prints in PROFILE mode, keyed by their queryStats names.
+var gcloudQueryStats = []struct{ label, key string }{
+	{"TOTAL_ELAPSED_TIME", "elapsed_time"},
+	{"CPU_TIME", "cpu_time"},
+	{"ROWS_RETURNED", "rows_returned"},
+	{"ROWS_SCANNED", "rows_scanned"},
+	{"OPTIMIZER_VERSION", "optimizer_version"},
+}
+
+// Gcloud reproduces the text output of gcloud spanner databases execute-sql,
+// so that scripts written against gcloud keep working:
+//
+//   - Rows are a borderless table of column names and values separated by two
+//     spaces. Values are printed the way gcloud's Python runtime prints them:
+//     strings verbatim, NULL as None, BOOL as True or False, and ARRAY and
+//     STRUCT as Python lists such as ['a', None]. An empty result prints nothing.
+//   - A statement without columns prints "Statement modified N rows" to status.
+//   - With a query plan (PLAN and PROFILE mode) the plan is printed as a tree.
+//     In PROFILE mode it is preceded by a box of aggregate stats, and the rows
+//     go to status, as gcloud does.
+//
+// Column widths depend on every row, so rows are buffered until Finish.
+type Gcloud struct {
+	w      *bufio.Writer
+	status *bufio.Writer
+	fields []*sppb.StructType_Field
+	rows   [][]string
+}
+
+// NewGcloud returns a Gcloud writer. status receives what gcloud writes to
+// stderr; pass w to keep everything in one stream.
+func NewGcloud(w, status io.Writer) *Gcloud {
+	g := &Gcloud{w: bufio.NewWriter(w)}
+	g.status = g.w
+	if status != w {
+		g.status = bufio.NewWriter(status)
+	}
+	return g
+}
+
+func (g *Gcloud) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	g.fields = metadata.GetRowType().GetFields()
+	return nil
+}
+
+func (g *Gcloud) WriteRow(values []*structpb.Value) error {
+	if len(g.fields) != len(values) {
+		return fmt.Errorf("row has %d values but row type has %d fields", len(values), len(g.fields))
+	}
+	cells := make([]string, len(values))
+	for i, v := range values {
+		cells[i] = gcloudValue(v)
+	}
+	g.rows = append(g.rows, cells)
+	return nil
+}
+
+func (g *Gcloud) Finish(stats *sppb.ResultSetStats) error {
+	switch {
+	case len(stats.GetQueryPlan().GetPlanNodes()) > 0:
+		profile := stats.GetQueryStats() != nil
+		if profile {
+			g.writeQueryStats(stats.GetQueryStats().GetFields())
+		}
+		g.writePlan(stats.GetQueryPlan().GetPlanNodes())
+		if profile {
+			g.writeTable(g.status)
+		}
+	case len(g.fields) == 0:
+		switch rc := stats.GetRowCount().(type) {
+		case *sppb.ResultSetStats_RowCountExact:
+			fmt.Fprintf(g.status, "Statement modified %s\n", plural(rc.RowCountExact, "row"))
+		case *sppb.ResultSetStats_RowCountLowerBound:
+			fmt.Fprintf(g.status, "Statement modified %s\n", plural(rc.RowCountLowerBound, "row"))
+		}
+	default:
+		g.writeTable(g.w)
+	}
+	if err := g.w.Flush(); err != nil {
+		return err
+	}
+	return g.status.Flush()
+}
+
+func (g *Gcloud) writeTable(w *bufio.Writer) {
+	if len(g.rows) == 0 || len(g.fields) == 0 {
+		return
+	}
+	names := make([]string, len(g.fields))
+	for i, f := range g.fields {
+		names[i] = cmp.Or(f.GetName(), gcloudUnspecified)
+	}
+	widths := make([]int, len(names))
+	for _, row := range append([][]string{names}, g.rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+	for _, row := range append([][]string{names}, g.rows...) {
+		var sb strings.Builder
+		for i, cell := range row {
+			if i > 0 {
+				sb.WriteString("  ")
+			}
+			sb.WriteString(padRight(cell, widths[i]))
+		}
+		fmt.Fprintln(w, strings.TrimRight(sb.String(), " "))
+	}
+}
+
+func (g *Gcloud) writeQueryStats(queryStats map[string]*structpb.Value) {
+	labels := make([]string, len(gcloudQueryStats))
+	values := make([]string, len(gcloudQueryStats))
+	widths := make([]int, len(gcloudQueryStats))
+	segments := make([]string, len(gcloudQueryStats))
+	for i, s := range gcloudQueryStats {
+		labels[i] = s.label
+		values[i] = "Unknown"
+		if v, ok := queryStats[s.key]; ok {
+			values[i] = gcloudValue(v)
+		}
+		widths[i] = max(displayWidth(labels[i]), displayWidth(values[i]))
+		segments[i] = strings.Repeat(unicodeBorder.horizontal, widths[i]+2)
+	}
+	line := func(cells []string) {
+		var sb strings.Builder
+		sb.WriteString(unicodeBorder.vertical)
+		for i, cell := range cells {
+			sb.WriteString(" " + padRight(cell, widths[i]) + " " + unicodeBorder.vertical)
+		}
+		fmt.Fprintln(g.w, sb.String())
+	}
+
+	fmt.Fprintln(g.w, unicodeBorder.topLeft+strings.Join(segments, unicodeBorder.topMiddle)+unicodeBorder.topRight)
+	line(labels)
+	fmt.Fprintln(g.w, unicodeBorder.middleLeft+strings.Join(segments, unicodeBorder.middleMiddle)+unicodeBorder.middleRight)
+	line(values)
+	fmt.Fprintln(g.w, unicodeBorder.bottomLeft+strings.Join(segments, unicodeBorder.bottomMiddle)+unicodeBorder.bottomRight)
+}
+
+// writePlan prints the plan tree rooted at the first node. Each node shows its
+// kind and display name, then its executions and latency (PROFILE only), its
+// metadata and the description of a scalar node, each on its own line.
+func (g *Gcloud) writePlan(nodes []*sppb.PlanNode) {
+	var walk func(n *sppb.PlanNode, prepend string, isLast, isRoot bool, depth int)
+	walk = func(n *sppb.PlanNode, prepend string, isLast, isRoot bool, depth int) {
+		stub, beneath := "", ""
+		if !isRoot {
+			stub, beneath = "+-", "| "
+			if isLast {
+				stub, beneath = `\-`, "  "
+			}
+		}
+		fmt.Fprintf(g.w, "%s%s %s %s\n", prepend, stub, n.GetKind(), n.GetDisplayName())
+		if s := gcloudExecutionStats(n); s != "" {
+			fmt.Fprintf(g.w, "%s%s (%s)\n", prepend, beneath, s)
+		}
+		if md := n.GetMetadata().GetFields(); len(md) > 0 {
+			props := make([]string, 0, len(md))
+			for k, v := range md {
+				props = append(props, k+": "+gcloudValue(v))
+			}
+			slices.Sort(props)
+			fmt.Fprintf(g.w, "%s%s %s\n", prepend, beneath, strings.Join(props, ", "))
+		}
+		if desc := n.GetShortRepresentation().GetDescription(); desc != "" {
+			fmt.Fprintf(g.w, "%s%s %s\n", prepend, beneath, desc)
+		}
+		if !isRoot {
+			fmt.Fprintf(g.w, "%s%s\n", prepend, beneath)
+		}
+
+		childPrepend := prepend + "|   "
+		if isLast {
+			childPrepend = prepend + "    "
+		}
+		links := n.GetChildLinks()
+		for i, link := range links {
+			// A malformed plan must not send the walk out of range or around a cycle.
+			idx := int(link.GetChildIndex())
+			if idx < 0 || idx >= len(nodes) || depth >= len(nodes) {
+				continue
+			}
+			walk(nodes[idx], childPrepend, i == len(links)-1, false, depth+1)
+		}
+	}
+	walk(nodes[0], "", true, true, 0)
+}
+
+// gcloudExecutionStats summarizes the executions and latency of a plan node,
+// such as "1 execution, 0.5 msecs total latency". It is empty in PLAN mode.
+func gcloudExecutionStats(n *sppb.PlanNode) string {
+	var props []string
+	if s := queryplan.Executions(n); s != "" {
+		if count, err := strconv.ParseInt(s, 10, 64); err == nil {
+			props = append(props, plural(count, "execution"))
+		}
+	}
+	latency := queryplan.ExecutionStat(n, "latency")
+	if latency.Mean != "" {
+		props = append(props, queryplan.Stat{Total: latency.Mean, Unit: latency.Unit}.String()+" average latency")
+	} else if latency.Total != "" {
+		props = append(props, latency.String()+" total latency")
+	}
+	return strings.Join(props, ", ")
+}
+
+// gcloudValue formats a top-level value: strings, which carry INT64, NUMERIC,
+// DATE, TIMESTAMP and the like, are printed verbatim and anything else as its
+// Python representation.
+func gcloudValue(v *structpb.Value) string {
+	if s, ok := v.GetKind().(*structpb.Value_StringValue); ok {
+		return s.StringValue
+	}
+	return pythonRepr(v)
+}
+
+// pythonRepr formats v as Python's repr() formats the value that the JSON
+// decoder of gcloud produces from it.
+func pythonRepr(v *structpb.Value) string {
+	switch k := v.GetKind().(type) {
+	case *structpb.Value_BoolValue:
+		if k.BoolValue {
+			return "True"
+		}
+		return "False"
+	case *structpb.Value_NumberValue:
+		return pythonFloat(k.NumberValue)
+	case *structpb.Value_StringValue:
+		return pythonString(k.StringValue)
+	case *structpb.Value_ListValue:
+		items := make([]string, len(k.ListValue.GetValues()))
+		for i, e := range k.ListValue.GetValues() {
+			items[i] = pythonRepr(e)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *structpb.Value_StructValue:
+		fields := k.StructValue.GetFields()
+		items := make([]string, 0, len(fields))
+		for _, name := range slices.Sorted(maps.Keys(fields)) {
+			items = append(items, pythonString(name)+": "+pythonRepr(fields[name]))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	default:
+		return "None"
+	}
+}
+
+// pythonFloat formats f like Python's float repr: the shortest round-trip
+// digits, in positional notation with at least one fractional digit for
+// decimal exponents from -4 to 15, and in scientific notation otherwise.
+func pythonFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	sci := strconv.FormatFloat(f, 'e', -1, 64)
+	exp, err := strconv.Atoi(sci[strings.IndexByte(sci, 'e')+1:])
+	if err != nil || exp < -4 || exp >= 16 {
+		return sci
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// pythonString formats s like Python's str repr.
+func pythonString(s string) string {
+	quote := '\''
+	if strings.ContainsRune(s, '\'') && !strings.ContainsRune(s, '"') {
+		quote = '"'
+	}
+	var sb strings.Builder
+	sb.WriteRune(quote)
+	for _, r := range s {
+		switch {
+		case r == quote || r == '\\':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case unicode.IsPrint(r):
+			sb.WriteRune(r)
+		case r < 0x100:
+			fmt.Fprintf(&sb, `\x%02x`, r)
+		case r < 0x10000:
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		default:
+			fmt.Fprintf(&sb, `\U%08x`, r)
+		}
+	}
+	sb.WriteRune(quote)
+	return sb.String()
+}
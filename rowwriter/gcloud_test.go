@@ -0,0 +1,122 @@
+package rowwriter
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func profileFixture(t *testing.T) *sppb.ResultSet {
+	t.Helper()
+	return readProfile(t, "singers_limit3")
+}
+
+// readProfile reads a PROFILE ResultSet from ../testdata/profile.
+func readProfile(t *testing.T, name string) *sppb.ResultSet {
+	t.Helper()
+	b, err := os.ReadFile("../testdata/profile/" + name + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rs sppb.ResultSet
+	if err := protojson.Unmarshal(b, &rs); err != nil {
+		t.Fatal(err)
+	}
+	return &rs
+}
+
+func TestGcloudGolden(t *testing.T) {
+	t.Parallel()
+
+	dml := &sppb.ResultSet{
+		Metadata: &sppb.ResultSetMetadata{RowType: &sppb.StructType{}},
+		Stats:    &sppb.ResultSetStats{RowCount: &sppb.ResultSetStats_RowCountExact{RowCountExact: 1}},
+	}
+	typed := resultSet(
+		[]string{"", "b", "f", "st"},
+		[]*sppb.Type{
+			{Code: sppb.TypeCode_INT64},
+			{Code: sppb.TypeCode_BOOL},
+			{Code: sppb.TypeCode_FLOAT64},
+			{Code: sppb.TypeCode_STRUCT, StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+				{Name: "s", Type: &sppb.Type{Code: sppb.TypeCode_STRING}},
+				{Name: "n", Type: &sppb.Type{Code: sppb.TypeCode_INT64}},
+			}}},
+		},
+		[][]*structpb.Value{
+			{
+				structpb.NewStringValue("1"), structpb.NewBoolValue(true), structpb.NewNumberValue(1),
+				structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("it's"), structpb.NewNullValue()}}),
+			},
+			{structpb.NewNullValue(), structpb.NewBoolValue(false), structpb.NewNumberValue(1e-5), structpb.NewNullValue()},
+		},
+	)
+
+	tests := map[string]*sppb.ResultSet{
+		"singers": singersFixture(),
+		"typed":   typed,
+		"dml":     dml,
+		"plan":    planFixture(t),
+		"profile": profileFixture(t),
+	}
+	for name, rs := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := WriteResultSet(NewGcloud(&buf, &buf), rs); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			checkGolden(t, "gcloud", name, buf.Bytes())
+		})
+	}
+}
+
+func TestGcloudStatus(t *testing.T) {
+	t.Parallel()
+
+	var out, status bytes.Buffer
+	if err := WriteResultSet(NewGcloud(&out, &status), profileFixture(t)); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), []byte("┌")) {
+		t.Errorf("stdout does not start with the stats box:\n%s", out.String())
+	}
+	if bytes.Contains(out.Bytes(), []byte("Catalina")) {
+		t.Errorf("PROFILE rows written to stdout:\n%s", out.String())
+	}
+	if want := "SingerId  FirstName\n1         Marc\n2         Catalina\n3         Alice\n"; status.String() != want {
+		t.Errorf("status = %q, want %q", status.String(), want)
+	}
+}
+
+func TestPythonRepr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		v    *structpb.Value
+		want string
+	}{
+		{structpb.NewNumberValue(1), "1.0"},
+		{structpb.NewNumberValue(0.1), "0.1"},
+		{structpb.NewNumberValue(1e16), "1e+16"},
+		{structpb.NewNumberValue(123456789012345.6), "123456789012345.6"},
+		{structpb.NewNumberValue(0.0001), "0.0001"},
+		{structpb.NewNumberValue(0.00001), "1e-05"},
+		{structpb.NewNumberValue(-2.5e-300), "-2.5e-300"},
+		{structpb.NewStringValue(`a'b`), `"a'b"`},
+		{structpb.NewStringValue(`a'"b`), `'a\'"b'`},
+		{structpb.NewStringValue("tab\t\\ 山\x00\u200b"), `'tab\t\\ 山\x00\u200b'`},
+		{structpb.NewNullValue(), "None"},
+		{structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewBoolValue(false), structpb.NewStringValue("1")}}), "[False, '1']"},
+	}
+	for _, tc := range tests {
+		if got := pythonRepr(tc.v); got != tc.want {
+			t.Errorf("pythonRepr(%v) = %s, want %s", tc.v, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package rowwriter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/valuefmt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// HTML renders rows as an HTML table fragment suitable for pasting into documents.
+// Values are formatted by spanvalue as in experimental_csv; NULL cells carry class="null".
+// Rows are written as they arrive.
+type HTML struct {
+	w           *bufio.Writer
+	fields      []*sppb.StructType_Field
+	format      *delimitedCells
+	includePlan bool
+}
+
+// NewHTML returns an HTML writer. When includePlan is true and the stats carry a
+// query plan (PLAN or PROFILE mode), the plan is appended in a collapsible <details> section.
+func NewHTML(w io.Writer, includePlan bool) *HTML {
+	return &HTML{w: bufio.NewWriter(w), includePlan: includePlan}
+}
+
+func (h *HTML) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	h.fields = metadata.GetRowType().GetFields()
+	format, err := newDelimitedCells(metadata.GetRowType())
+	if err != nil {
+		return err
+	}
+	h.format = format
+	h.w.WriteString("<table>\n<thead>\n<tr>")
+	for _, f := range h.fields {
+		fmt.Fprintf(h.w, `<th title="%s">%s</th>`, html.EscapeString(valuefmt.TypeString(f.GetType())), htmlTextEscaper.Replace(f.GetName()))
+	}
+	h.w.WriteString("</tr>\n</thead>\n<tbody>\n")
+	return nil
+}
+
+func (h *HTML) WriteRow(values []*structpb.Value) error {
+	cells, err := h.format.cells(values)
+	if err != nil {
+		return err
+	}
+	h.w.WriteString("<tr>")
+	for i, v := range values {
+		switch {
+		case isNull(v):
+			h.w.WriteString(`<td class="null">NULL</td>`)
+		case isNumericType(h.fields[i].GetType()):
+			h.w.WriteString(`<td align="right">` + htmlCell(cells[i]) + "</td>")
+		default:
+			h.w.WriteString("<td>" + htmlCell(cells[i]) + "</td>")
+		}
+	}
+	h.w.WriteString("</tr>\n")
+	return nil
+}
+
+func (h *HTML) Finish(stats *sppb.ResultSetStats) error {
+	h.w.WriteString("</tbody>\n</table>\n")
+	if h.includePlan && stats.GetQueryPlan() != nil {
+		b, err := protojson.Marshal(stats.GetQueryPlan())
+		if err != nil {
+			return err
+		}
+		// Re-indent because protojson output is deliberately unstable.
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, b, "", "  "); err != nil {
+			return err
+		}
+		h.w.WriteString("<details>\n<summary>Query plan</summary>\n<pre>")
+		h.w.WriteString(htmlTextEscaper.Replace(indented.String()))
+		h.w.WriteString("</pre>\n</details>\n")
+	}
+	return h.w.Flush()
+}
+
+// htmlTextEscaper escapes text content; quotes only need escaping in attribute values.
+var htmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// htmlLineBreaks turns line breaks in a cell into <br>, as in Markdown.
+var htmlLineBreaks = strings.NewReplacer("\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+func htmlCell(s string) string {
+	return htmlLineBreaks.Replace(htmlTextEscaper.Replace(s))
+}
@@ -0,0 +1,48 @@
+package rowwriter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+func TestHTMLGolden(t *testing.T) {
+	withPlan := singersFixture()
+	withPlan.Stats.QueryPlan = &sppb.QueryPlan{PlanNodes: []*sppb.PlanNode{
+		{Index: 0, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Serialize Result <root>"},
+	}}
+
+	tests := map[string]struct {
+		rs          *sppb.ResultSet
+		includePlan bool
+	}{
+		"singers":  {singersFixture(), false},
+		"escaping": {escapingFixture(), false},
+		"plan":     {withPlan, true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteResultSet(NewHTML(&buf, tc.includePlan), tc.rs); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			checkGolden(t, "html", name, buf.Bytes())
+		})
+	}
+}
+
+func TestHTMLOmitsPlanUnlessRequested(t *testing.T) {
+	t.Parallel()
+
+	rs := singersFixture()
+	rs.Stats.QueryPlan = &sppb.QueryPlan{PlanNodes: []*sppb.PlanNode{{DisplayName: "Scan"}}}
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewHTML(&buf, false), rs); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "<details>") {
+		t.Fatalf("plan section rendered without includePlan:\n%s", buf.String())
+	}
+}
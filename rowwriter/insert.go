@@ -0,0 +1,188 @@
+package rowwriter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/valuefmt"
+	"github.com/cloudspannerecosystem/memefish/token"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// insertColumns returns the column names of a row type that can be written
+// back to a table: named, unique and free of STRUCT, which tables cannot store.
+func insertColumns(fields []*sppb.StructType_Field) ([]string, error) {
+	seen := make(map[string]bool, len(fields))
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		name := f.GetName()
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("column %d has no name; alias it to insert", i)
+		case seen[name]:
+			return nil, fmt.Errorf("column %s appears more than once", name)
+		case containsStruct(f.GetType()):
+			return nil, fmt.Errorf("column %s has type %s; STRUCT cannot be inserted", name, valuefmt.TypeString(f.GetType()))
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	return columns, nil
+}
+
+func containsStruct(typ *sppb.Type) bool {
+	switch typ.GetCode() {
+	case sppb.TypeCode_STRUCT:
+		return true
+	case sppb.TypeCode_ARRAY:
+		return containsStruct(typ.GetArrayElementType())
+	default:
+		return false
+	}
+}
+
+// quoteTablePath quotes each dot-separated part of a possibly schema-qualified table name.
+func quoteTablePath(table string) string {
+	parts := strings.Split(table, ".")
+	for i, p := range parts {
+		parts[i] = token.QuoteSQLIdent(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// SQLInsert writes rows as GoogleSQL INSERT statements with up to batchSize
+// rows each. Values are [valuefmt.Literal] expressions, so they keep their
+// Spanner type when read back as statements or --param values.
+type SQLInsert struct {
+	w         *bufio.Writer
+	table     string
+	batchSize int
+	fields    []*sppb.StructType_Field
+	header    string
+	pending   int
+}
+
+func NewSQLInsert(w io.Writer, table string, batchSize int) *SQLInsert {
+	return &SQLInsert{w: bufio.NewWriter(w), table: table, batchSize: max(batchSize, 1)}
+}
+
+func (s *SQLInsert) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	s.fields = metadata.GetRowType().GetFields()
+	columns, err := insertColumns(s.fields)
+	if err != nil {
+		return err
+	}
+	for i, c := range columns {
+		columns[i] = token.QuoteSQLIdent(c)
+	}
+	s.header = fmt.Sprintf("INSERT INTO %s (%s) VALUES\n", quoteTablePath(s.table), strings.Join(columns, ", "))
+	return nil
+}
+
+func (s *SQLInsert) WriteRow(values []*structpb.Value) error {
+	if len(s.fields) != len(values) {
+		return fmt.Errorf("row has %d values but row type has %d fields", len(values), len(s.fields))
+	}
+	literals := make([]string, len(values))
+	for i, v := range values {
+		if isNull(v) {
+			literals[i] = "NULL"
+			continue
+		}
+		lit, err := valuefmt.Literal(s.fields[i].GetType(), v)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", s.fields[i].GetName(), err)
+		}
+		literals[i] = lit
+	}
+
+	if s.pending == 0 {
+		s.w.WriteString(s.header)
+	} else {
+		s.w.WriteString(",\n")
+	}
+	s.w.WriteString("  (" + strings.Join(literals, ", ") + ")")
+	s.pending++
+	if s.pending == s.batchSize {
+		s.w.WriteString(";\n")
+		s.pending = 0
+	}
+	return nil
+}
+
+func (s *SQLInsert) Finish(*sppb.ResultSetStats) error {
+	if s.pending > 0 {
+		s.w.WriteString(";\n")
+	}
+	return s.w.Flush()
+}
+
+// MutationsJSON writes rows as insert Mutations in the protojson form of the
+// Commit API, one compact JSON object per line with up to batchSize rows each.
+type MutationsJSON struct {
+	w         *bufio.Writer
+	table     string
+	batchSize int
+	columns   []string
+	pending   []*structpb.ListValue
+}
+
+func NewMutationsJSON(w io.Writer, table string, batchSize int) *MutationsJSON {
+	return &MutationsJSON{w: bufio.NewWriter(w), table: table, batchSize: max(batchSize, 1)}
+}
+
+func (m *MutationsJSON) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	columns, err := insertColumns(metadata.GetRowType().GetFields())
+	if err != nil {
+		return err
+	}
+	m.columns = columns
+	return nil
+}
+
+func (m *MutationsJSON) WriteRow(values []*structpb.Value) error {
+	if len(m.columns) != len(values) {
+		return fmt.Errorf("row has %d values but row type has %d fields", len(values), len(m.columns))
+	}
+	m.pending = append(m.pending, &structpb.ListValue{Values: values})
+	if len(m.pending) == m.batchSize {
+		return m.flushBatch()
+	}
+	return nil
+}
+
+func (m *MutationsJSON) flushBatch() error {
+	mutation := &sppb.Mutation{Operation: &sppb.Mutation_Insert{Insert: &sppb.Mutation_Write{
+		Table:   m.table,
+		Columns: m.columns,
+		Values:  m.pending,
+	}}}
+	m.pending = nil
+	b, err := protojson.Marshal(mutation)
+	if err != nil {
+		return err
+	}
+	// protojson output is deliberately unstable; compact it for deterministic lines.
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err = m.w.Write(buf.Bytes())
+	return err
+}
+
+func (m *MutationsJSON) Finish(*sppb.ResultSetStats) error {
+	if len(m.pending) > 0 {
+		if err := m.flushBatch(); err != nil {
+			return err
+		}
+	}
+	return m.w.Flush()
+}
@@ -0,0 +1,84 @@
+package rowwriter
+
+import (
+	"bytes"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func typedColumnsFixture() *sppb.ResultSet {
+	return resultSet(
+		[]string{"Id", "Amount", "Payload", "Doc", "At", "Score", "Order"},
+		[]*sppb.Type{
+			{Code: sppb.TypeCode_INT64},
+			{Code: sppb.TypeCode_NUMERIC},
+			{Code: sppb.TypeCode_BYTES},
+			{Code: sppb.TypeCode_JSON},
+			{Code: sppb.TypeCode_TIMESTAMP},
+			{Code: sppb.TypeCode_FLOAT64},
+			{Code: sppb.TypeCode_DATE},
+		},
+		[][]*structpb.Value{{
+			structpb.NewStringValue("1"),
+			structpb.NewStringValue("12.50"),
+			structpb.NewStringValue("AAH/"),
+			structpb.NewStringValue(`{"a":"b"}`),
+			structpb.NewStringValue("2024-06-01T12:34:56.789Z"),
+			structpb.NewNumberValue(2),
+			structpb.NewStringValue("2024-06-01"),
+		}},
+	)
+}
+
+func TestSQLInsertGolden(t *testing.T) {
+	tests := map[string]struct {
+		rs        *sppb.ResultSet
+		table     string
+		batchSize int
+	}{
+		"singers_batch1": {singersFixture(), "Singers", 1},
+		"singers":        {singersFixture(), "Singers", 100},
+		"typed_columns":  {typedColumnsFixture(), "sch.Table", 100},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteResultSet(NewSQLInsert(&buf, tc.table, tc.batchSize), tc.rs); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			checkGolden(t, "sql_insert", name, buf.Bytes())
+		})
+	}
+}
+
+func TestMutationsJSONGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewMutationsJSON(&buf, "Singers", 1), singersFixture()); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	checkGolden(t, "mutations_json", "singers_batch1", buf.Bytes())
+}
+
+func TestInsertColumnsErrors(t *testing.T) {
+	t.Parallel()
+
+	structType := &sppb.Type{Code: sppb.TypeCode_STRUCT, StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+		{Name: "x", Type: &sppb.Type{Code: sppb.TypeCode_INT64}},
+	}}}
+	tests := map[string]*sppb.ResultSet{
+		"anonymous":    resultSet([]string{""}, []*sppb.Type{{Code: sppb.TypeCode_INT64}}, nil),
+		"duplicate":    resultSet([]string{"a", "a"}, []*sppb.Type{{Code: sppb.TypeCode_INT64}, {Code: sppb.TypeCode_INT64}}, nil),
+		"struct":       resultSet([]string{"s"}, []*sppb.Type{structType}, nil),
+		"array_struct": resultSet([]string{"s"}, []*sppb.Type{{Code: sppb.TypeCode_ARRAY, ArrayElementType: structType}}, nil),
+	}
+	for name, rs := range tests {
+		if err := WriteResultSet(NewSQLInsert(&discard{}, "T", 1), rs); err == nil {
+			t.Errorf("%s: SQLInsert expected error", name)
+		}
+		if err := WriteResultSet(NewMutationsJSON(&discard{}, "T", 1), rs); err == nil {
+			t.Errorf("%s: MutationsJSON expected error", name)
+		}
+	}
+}
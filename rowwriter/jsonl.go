@@ -0,0 +1,44 @@
+package rowwriter
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/jqresult"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// JSONLObjects writes one compact JSON object per row, keyed by column name
+// with keys in row type order (see [jqresult.ObjectKeys]) and values rendered by render.
+type JSONLObjects struct {
+	w      *bufio.Writer
+	enc    *json.Encoder
+	render jqresult.RenderOptions
+	fields []*sppb.StructType_Field
+}
+
+func NewJSONLObjects(w io.Writer, render jqresult.RenderOptions) *JSONLObjects {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	return &JSONLObjects{w: bw, enc: enc, render: render}
+}
+
+func (j *JSONLObjects) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	j.fields = metadata.GetRowType().GetFields()
+	return nil
+}
+
+func (j *JSONLObjects) WriteRow(values []*structpb.Value) error {
+	obj, err := j.render.StructValuesToObject(j.fields, values)
+	if err != nil {
+		return err
+	}
+	return j.enc.Encode(obj)
+}
+
+func (j *JSONLObjects) Finish(*sppb.ResultSetStats) error {
+	return j.w.Flush()
+}
@@ -0,0 +1,16 @@
+package rowwriter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apstndb/execspansql/jqresult"
+)
+
+func TestJSONLObjectsGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewJSONLObjects(&buf, jqresult.RenderOptions{}), singersFixture()); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	checkGolden(t, "jsonl_objects", "singers", buf.Bytes())
+}
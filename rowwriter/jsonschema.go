@@ -0,0 +1,51 @@
+package rowwriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/jqresult"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// JSONSchema writes a JSON Schema of the rows, derived from the row type by
+// [jqresult.RenderOptions.RowSchema], instead of the rows themselves. Only the
+// metadata is needed, so the statement can run in PLAN mode; rows are ignored.
+type JSONSchema struct {
+	w       io.Writer
+	render  jqresult.RenderOptions
+	shape   jqresult.RowShape
+	compact bool
+	schema  *jqresult.Object
+}
+
+func NewJSONSchema(w io.Writer, render jqresult.RenderOptions, shape jqresult.RowShape, compact bool) *JSONSchema {
+	return &JSONSchema{w: w, render: render, shape: shape, compact: compact}
+}
+
+func (j *JSONSchema) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	j.schema = j.render.RowSchema(metadata.GetRowType(), j.shape)
+	return nil
+}
+
+func (j *JSONSchema) WriteRow([]*structpb.Value) error {
+	return nil
+}
+
+func (j *JSONSchema) Finish(*sppb.ResultSetStats) error {
+	b, err := j.schema.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if j.compact {
+		buf.Write(b)
+	} else if err := json.Indent(&buf, b, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err = buf.WriteTo(j.w)
+	return err
+}
@@ -0,0 +1,34 @@
+package rowwriter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apstndb/execspansql/jqresult"
+)
+
+func TestJSONSchemaGolden(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	// Rows are ignored, so a result with rows gives the same schema as PLAN mode.
+	if err := WriteResultSet(NewJSONSchema(&buf, jqresult.RenderOptions{}, jqresult.RowShapeObject, false), singersFixture()); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	checkGolden(t, "json_schema", "singers", buf.Bytes())
+}
+
+func TestJSONSchemaCompact(t *testing.T) {
+	t.Parallel()
+
+	rs := singersFixture()
+	rs.Metadata.RowType.Fields = rs.Metadata.RowType.Fields[:1]
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewJSONSchema(&buf, jqresult.RenderOptions{TypedNumbers: true}, jqresult.RowShapeArray, true), rs); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	want := `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"array","prefixItems":[{"type":["integer","null"],"description":"INT64","title":"SingerId"}],"minItems":1,"maxItems":1}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %s, want %s", buf.String(), want)
+	}
+}
@@ -0,0 +1,102 @@
+package rowwriter
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// markdownEscaper escapes characters that GitHub Flavored Markdown would
+// interpret inside a table cell. Line breaks become <br> to keep one row per line.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"|", `\|`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	">", `\>`,
+	"\r\n", "<br>",
+	"\n", "<br>",
+	"\r", "<br>",
+)
+
+// Markdown renders rows as a GitHub Flavored Markdown table.
+// Values are formatted by spanvalue as in experimental_csv; NULL is rendered as an emphasized *NULL*
+// so that it is distinguishable from the string "NULL". Rows are written as they arrive.
+type Markdown struct {
+	w      *bufio.Writer
+	fields []*sppb.StructType_Field
+	format *delimitedCells
+}
+
+func NewMarkdown(w io.Writer) *Markdown {
+	return &Markdown{w: bufio.NewWriter(w)}
+}
+
+func (m *Markdown) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	m.fields = metadata.GetRowType().GetFields()
+	format, err := newDelimitedCells(metadata.GetRowType())
+	if err != nil {
+		return err
+	}
+	m.format = format
+	if len(m.fields) == 0 {
+		return nil
+	}
+	names := make([]string, len(m.fields))
+	aligns := make([]string, len(m.fields))
+	for i, f := range m.fields {
+		names[i] = markdownEscaper.Replace(f.GetName())
+		aligns[i] = "---"
+		if isNumericType(f.GetType()) {
+			aligns[i] = "---:"
+		}
+	}
+	m.writeLine(names)
+	m.writeLine(aligns)
+	return nil
+}
+
+func (m *Markdown) WriteRow(values []*structpb.Value) error {
+	cells, err := m.format.cells(values)
+	if err != nil {
+		return err
+	}
+	for i, v := range values {
+		if isNull(v) {
+			cells[i] = "*NULL*"
+			continue
+		}
+		cells[i] = markdownEscaper.Replace(cells[i])
+	}
+	m.writeLine(cells)
+	return nil
+}
+
+func (m *Markdown) Finish(*sppb.ResultSetStats) error {
+	return m.w.Flush()
+}
+
+func (m *Markdown) writeLine(cells []string) {
+	m.w.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+}
+
+func isNull(v *structpb.Value) bool {
+	_, ok := v.GetKind().(*structpb.Value_NullValue)
+	return ok
+}
+
+func isNumericType(typ *sppb.Type) bool {
+	switch typ.GetCode() {
+	case sppb.TypeCode_INT64, sppb.TypeCode_FLOAT64, sppb.TypeCode_FLOAT32, sppb.TypeCode_NUMERIC:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,37 @@
+package rowwriter
+
+import (
+	"bytes"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func escapingFixture() *sppb.ResultSet {
+	return resultSet(
+		[]string{"s", "n"},
+		[]*sppb.Type{{Code: sppb.TypeCode_STRING}, {Code: sppb.TypeCode_NUMERIC}},
+		[][]*structpb.Value{
+			{structpb.NewStringValue("a|b <i>*x*</i>\nnext & `code`"), structpb.NewStringValue("1.5")},
+			{structpb.NewStringValue("NULL"), structpb.NewNullValue()},
+			{structpb.NewStringValue("carriage\rreturn"), structpb.NewStringValue("-2")},
+		},
+	)
+}
+
+func TestMarkdownGolden(t *testing.T) {
+	tests := map[string]*sppb.ResultSet{
+		"singers":  singersFixture(),
+		"escaping": escapingFixture(),
+	}
+	for name, rs := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteResultSet(NewMarkdown(&buf), rs); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			checkGolden(t, "markdown", name, buf.Bytes())
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package rowwriter
+
+import (
+	"fmt"
+	"io"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Parquet writes rows as a Snappy-compressed Parquet file using the Arrow type
+// mapping of [arrowFields]: ARRAY becomes LIST, STRUCT a group, NUMERIC
+// DECIMAL(38, 9) and TIMESTAMP INT64 microseconds. Rows are written in row
+// groups of rowGroupRows, so at most one row group is held in memory.
+//
+// The SQL text and, when Spanner returns it, the read timestamp are recorded
+// in the file key-value metadata.
+type Parquet struct {
+	w            io.Writer
+	sql          string
+	rowGroupRows int
+	batch        *arrowBatch
+	fw           *pqarrow.FileWriter
+}
+
+func NewParquet(w io.Writer, sql string, rowGroupRows int) *Parquet {
+	return &Parquet{w: w, sql: sql, rowGroupRows: max(rowGroupRows, 1)}
+}
+
+func (p *Parquet) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	batch, err := newArrowBatch(metadata.GetRowType().GetFields(), nil)
+	if err != nil {
+		return fmt.Errorf("parquet: %w", err)
+	}
+	props := parquet.NewWriterProperties(
+		parquet.WithCompression(compress.Codecs.Snappy),
+		parquet.WithCreatedBy("execspansql"),
+	)
+	// Hide Close of the destination; the caller owns it.
+	fw, err := pqarrow.NewFileWriter(batch.schema, struct{ io.Writer }{p.w}, props, pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+	if err != nil {
+		batch.release()
+		return err
+	}
+	p.batch, p.fw = batch, fw
+
+	keys, values := queryMetadata(p.sql, metadata)
+	for i, k := range keys {
+		if err := fw.AppendKeyValueMetadata(k, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Parquet) WriteRow(values []*structpb.Value) error {
+	if err := p.batch.append(values); err != nil {
+		return err
+	}
+	if p.batch.rows >= p.rowGroupRows {
+		return p.writeRowGroup()
+	}
+	return nil
+}
+
+func (p *Parquet) writeRowGroup() error {
+	rec := p.batch.flush()
+	defer rec.Release()
+	return p.fw.Write(rec)
+}
+
+func (p *Parquet) Finish(*sppb.ResultSetStats) error {
+	defer p.batch.release()
+	if p.batch.rows > 0 {
+		if err := p.writeRowGroup(); err != nil {
+			return err
+		}
+	}
+	return p.fw.Close()
+}
@@ -0,0 +1,155 @@
+package rowwriter
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// columnarFixture covers the Arrow type mapping, including nested and NULL values.
+func columnarFixture() *sppb.ResultSet {
+	structType := &sppb.Type{
+		Code: sppb.TypeCode_STRUCT,
+		StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+			{Name: "i", Type: &sppb.Type{Code: sppb.TypeCode_INT64}},
+			{Type: &sppb.Type{Code: sppb.TypeCode_STRING}},
+		}},
+	}
+	rs := resultSet(
+		[]string{"id", "n", "f", "b", "d", "ts", "bytes", "json", "tags", "st"},
+		[]*sppb.Type{
+			{Code: sppb.TypeCode_INT64},
+			{Code: sppb.TypeCode_NUMERIC},
+			{Code: sppb.TypeCode_FLOAT64},
+			{Code: sppb.TypeCode_BOOL},
+			{Code: sppb.TypeCode_DATE},
+			{Code: sppb.TypeCode_TIMESTAMP},
+			{Code: sppb.TypeCode_BYTES},
+			{Code: sppb.TypeCode_JSON},
+			{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: sppb.TypeCode_STRING}},
+			structType,
+		},
+		[][]*structpb.Value{
+			{
+				structpb.NewStringValue("1"), structpb.NewStringValue("12.5"), structpb.NewNumberValue(1.5),
+				structpb.NewBoolValue(true), structpb.NewStringValue("2024-06-01"),
+				structpb.NewStringValue("2024-06-01T12:34:56.123456789Z"), structpb.NewStringValue("AAH/"),
+				structpb.NewStringValue(`{"a":1}`),
+				structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("x"), structpb.NewNullValue()}}),
+				structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("7"), structpb.NewStringValue("s")}}),
+			},
+			{
+				structpb.NewStringValue("2"), structpb.NewNullValue(), structpb.NewStringValue("NaN"),
+				structpb.NewNullValue(), structpb.NewNullValue(), structpb.NewNullValue(), structpb.NewNullValue(),
+				structpb.NewNullValue(), structpb.NewNullValue(), structpb.NewNullValue(),
+			},
+			{
+				structpb.NewStringValue("3"), structpb.NewStringValue("-0.000000001"), structpb.NewNumberValue(0),
+				structpb.NewBoolValue(false), structpb.NewStringValue("0001-01-01"),
+				structpb.NewStringValue("1970-01-01T00:00:00Z"), structpb.NewStringValue(""),
+				structpb.NewStringValue("null"), structpb.NewListValue(&structpb.ListValue{}),
+				structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewNullValue(), structpb.NewNullValue()}}),
+			},
+		},
+	)
+	rs.Metadata.Transaction = &sppb.Transaction{ReadTimestamp: timestamppb.New(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))}
+	return rs
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewParquet(&buf, "SELECT 1", 2), columnarFixture()); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+
+	rdr, err := file.NewParquetReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Close()
+	if got := rdr.NumRowGroups(); got != 2 {
+		t.Errorf("NumRowGroups() = %d, want 2", got)
+	}
+	kv := rdr.MetaData().KeyValueMetadata()
+	if got := kv.FindValue(sqlMetadataKey); got == nil || *got != "SELECT 1" {
+		t.Errorf("%s = %v, want SELECT 1", sqlMetadataKey, got)
+	}
+	if got := kv.FindValue(readTimestampMetadataKey); got == nil || *got != "2024-06-01T00:00:00Z" {
+		t.Errorf("%s = %v, want 2024-06-01T00:00:00Z", readTimestampMetadataKey, got)
+	}
+
+	schema := rdr.MetaData().Schema
+	for col, want := range map[string]string{
+		"n":  "Decimal(precision=38, scale=9)",
+		"ts": "Timestamp(isAdjustedToUTC=true, timeUnit=microseconds, is_from_converted_type=false, force_set_converted_type=false)",
+		"d":  "Date",
+	} {
+		idx := schema.ColumnIndexByName(col)
+		if idx < 0 {
+			t.Fatalf("column %s not found", col)
+		}
+		if got := schema.Column(idx).LogicalType().String(); got != want {
+			t.Errorf("column %s logical type = %s, want %s", col, got, want)
+		}
+	}
+
+	fr, err := pqarrow.NewFileReader(rdr, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl, err := fr.ReadTable(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tbl.Release()
+	if got := tbl.NumRows(); got != 3 {
+		t.Fatalf("NumRows() = %d, want 3", got)
+	}
+	wantTypes := []struct {
+		id          arrow.Type
+		spannerType string
+	}{
+		{arrow.INT64, "INT64"},
+		{arrow.DECIMAL128, "NUMERIC"},
+		{arrow.FLOAT64, "FLOAT64"},
+		{arrow.BOOL, "BOOL"},
+		{arrow.DATE32, "DATE"},
+		{arrow.TIMESTAMP, "TIMESTAMP"},
+		{arrow.BINARY, "BYTES"},
+		{arrow.STRING, "JSON"},
+		{arrow.LIST, "ARRAY<STRING>"},
+		{arrow.STRUCT, "STRUCT<i INT64, STRING>"},
+	}
+	fields := tbl.Schema().Fields()
+	if len(fields) != len(wantTypes) {
+		t.Fatalf("got %d fields, want %d", len(fields), len(wantTypes))
+	}
+	for i, f := range fields {
+		if got := f.Type.ID(); got != wantTypes[i].id {
+			t.Errorf("field %s type = %s, want %s", f.Name, got, wantTypes[i].id)
+		}
+		if got, _ := f.Metadata.GetValue(arrowTypeMetadataKey); got != wantTypes[i].spannerType {
+			t.Errorf("field %s %s = %q, want %q", f.Name, arrowTypeMetadataKey, got, wantTypes[i].spannerType)
+		}
+	}
+}
+
+func TestParquetNoColumns(t *testing.T) {
+	t.Parallel()
+
+	rs := resultSet(nil, nil, nil)
+	if err := WriteResultSet(NewParquet(&discard{}, "UPDATE T SET x = 1 WHERE true", 1), rs); err == nil {
+		t.Fatal("WriteResultSet() expected error for a result without columns")
+	}
+}
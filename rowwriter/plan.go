@@ -0,0 +1,200 @@
+package rowwriter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/queryplan"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// errNoPlan is returned by the plan formats for results without a query plan.
+var errNoPlan = errors.New("the result has no query plan; run the query in PLAN or PROFILE mode")
+
+// hotMarker marks the nodes of the hot path in [Plan] output.
+const hotMarker = ">"
+
+// Plan renders the query plan instead of the rows, which are ignored, as a
+// table with one line per operator of the tree walked by [queryplan.Plan.Walk]:
+//
+//   - ID is the node index, starred when the node has predicates, which are
+//     listed below the table.
+//   - Operator is the qualified operator name drawn as a tree, with the child
+//     link type in brackets and the remaining metadata in parentheses.
+//   - In PROFILE mode, Rows, Execs, Latency and CPU Time are the totals of
+//     the node's execution stats, and the hot path, which follows the child
+//     with the highest latency from the root, is marked with ">".
+type Plan struct {
+	w *bufio.Writer
+}
+
+func NewPlan(w io.Writer) *Plan {
+	return &Plan{w: bufio.NewWriter(w)}
+}
+
+func (p *Plan) WriteMetadata(*sppb.ResultSetMetadata) error {
+	return nil
+}
+
+func (p *Plan) WriteRow([]*structpb.Value) error {
+	return nil
+}
+
+func (p *Plan) Finish(stats *sppb.ResultSetStats) error {
+	nodes := stats.GetQueryPlan().GetPlanNodes()
+	if len(nodes) == 0 {
+		return errNoPlan
+	}
+	writePlanTree(p.w, queryplan.New(nodes))
+	return p.w.Flush()
+}
+
+func writePlanTree(w io.Writer, plan *queryplan.Plan) {
+	profile := plan.HasExecutionStats()
+	hot := plan.HotPath()
+	walk := plan.Walk()
+
+	header := []string{"ID", "Operator"}
+	if profile {
+		header = append(header, "Rows", "Execs", "Latency", "CPU Time")
+	}
+	rows := make([][]string, len(walk))
+	idWidth := 0
+	var indent treeIndent
+	for i, r := range walk {
+		id := strconv.Itoa(int(r.Node.GetIndex()))
+		if len(plan.Predicates(r.Node)) > 0 {
+			id = "*" + id
+		}
+		idWidth = max(idWidth, len(id))
+
+		row := []string{id, indent.prefix(r.Depth, r.Last) + operatorText(r.LinkType, r.Node)}
+		if profile {
+			row = append(row,
+				queryplan.ExecutionStat(r.Node, "rows").String(),
+				queryplan.Executions(r.Node),
+				queryplan.ExecutionStat(r.Node, "latency").String(),
+				queryplan.ExecutionStat(r.Node, "cpu_time").String(),
+			)
+		}
+		rows[i] = row
+	}
+	for i, r := range walk {
+		id := padLeft(rows[i][0], idWidth)
+		if hot != nil {
+			marker := " "
+			if hot[r.Node.GetIndex()] {
+				marker = hotMarker
+			}
+			id = marker + " " + id
+		}
+		rows[i][0] = id
+	}
+
+	// Stats are right-aligned; the ID is already padded.
+	writeASCIITable(w, header, rows, func(col int) bool { return col >= 2 })
+
+	var preds []string
+	for _, n := range plan.Nodes() {
+		for i, pred := range plan.Predicates(n) {
+			prefix := ""
+			if i == 0 {
+				prefix = strconv.Itoa(int(n.GetIndex())) + ":"
+			}
+			preds = append(preds, fmt.Sprintf("%s %s: %s", padLeft(prefix, idWidth+1), pred.Type, pred.Description))
+		}
+	}
+	if len(preds) > 0 {
+		fmt.Fprintln(w, "Predicates(identified by ID):")
+		for _, s := range preds {
+			fmt.Fprintln(w, s)
+		}
+	}
+	if hot != nil {
+		fmt.Fprintln(w, hotMarker+" marks the hot path: from the root down, the child with the highest latency.")
+	}
+}
+
+// operatorText describes a node of the plan tree reached by a link of
+// linkType: the link type in brackets, the operator and the remaining
+// metadata in parentheses.
+func operatorText(linkType string, n *sppb.PlanNode) string {
+	var sb strings.Builder
+	if linkType != "" {
+		sb.WriteString("[" + linkType + "] ")
+	}
+	sb.WriteString(queryplan.Operator(n))
+	if props := queryplan.Properties(n); len(props) > 0 {
+		sb.WriteString(" (" + strings.Join(props, ", ") + ")")
+	}
+	return sb.String()
+}
+
+// treeIndent draws the branches of a tree visited in depth-first order.
+type treeIndent struct {
+	// more[d] reports whether the node last seen at depth d has siblings to come.
+	more []bool
+}
+
+// prefix returns the branches drawn before the next node, which is at depth
+// and is the last child of its parent if last.
+func (t *treeIndent) prefix(depth int, last bool) string {
+	var sb strings.Builder
+	if depth > 0 {
+		for _, m := range t.more[1:depth] {
+			if m {
+				sb.WriteString("|  ")
+			} else {
+				sb.WriteString("   ")
+			}
+		}
+		sb.WriteString("+- ")
+	}
+	t.more = append(t.more[:depth], !last)
+	return sb.String()
+}
+
+// writeASCIITable writes header and rows in a table with ASCII borders. The
+// cells of the columns for which alignRight is true are right-aligned, except
+// in the header.
+func writeASCIITable(w io.Writer, header []string, rows [][]string, alignRight func(col int) bool) {
+	widths := make([]int, len(header))
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+	rule := func() {
+		segments := make([]string, len(widths))
+		for i, n := range widths {
+			segments[i] = strings.Repeat(asciiBorder.horizontal, n+2)
+		}
+		fmt.Fprintln(w, asciiBorder.topLeft+strings.Join(segments, asciiBorder.middleMiddle)+asciiBorder.topRight)
+	}
+	line := func(row []string, alignRight func(col int) bool) {
+		var sb strings.Builder
+		sb.WriteString(asciiBorder.vertical)
+		for i, cell := range row {
+			if alignRight(i) {
+				cell = padLeft(cell, widths[i])
+			} else {
+				cell = padRight(cell, widths[i])
+			}
+			sb.WriteString(" " + cell + " " + asciiBorder.vertical)
+		}
+		fmt.Fprintln(w, sb.String())
+	}
+
+	rule()
+	line(header, func(int) bool { return false })
+	rule()
+	for _, row := range rows {
+		line(row, alignRight)
+	}
+	rule()
+}
@@ -0,0 +1,50 @@
+package rowwriter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+// planFixture is profileFixture as returned in PLAN mode: no rows and no stats
+// other than the plan.
+func planFixture(t *testing.T) *sppb.ResultSet {
+	t.Helper()
+	rs := profileFixture(t)
+	rs.Rows, rs.Stats.QueryStats = nil, nil
+	for _, n := range rs.GetStats().GetQueryPlan().GetPlanNodes() {
+		n.ExecutionStats = nil
+	}
+	return rs
+}
+
+func TestPlanGolden(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]*sppb.ResultSet{
+		"plan":    planFixture(t),
+		"profile": profileFixture(t),
+	}
+	for name, rs := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := WriteResultSet(NewPlan(&buf), rs); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			checkGolden(t, "plan", name, buf.Bytes())
+		})
+	}
+}
+
+func TestPlanWithoutPlan(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewPlan(&buf), singersFixture()); !errors.Is(err, errNoPlan) {
+		t.Fatalf("WriteResultSet() error = %v, want %v", err, errNoPlan)
+	}
+}
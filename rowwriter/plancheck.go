@@ -0,0 +1,60 @@
+package rowwriter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/plancheck"
+	"github.com/apstndb/execspansql/queryplan"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// PlanCheck lints the query plan with [plancheck.Check] instead of writing
+// the rows, which are ignored, and writes one compact JSON object per
+// [plancheck.Finding]. Finish fails with [plancheck.ErrViolations] after
+// writing the findings if any of them is an error.
+type PlanCheck struct {
+	w      *bufio.Writer
+	config plancheck.Config
+}
+
+func NewPlanCheck(w io.Writer, config plancheck.Config) *PlanCheck {
+	return &PlanCheck{w: bufio.NewWriter(w), config: config}
+}
+
+func (c *PlanCheck) WriteMetadata(*sppb.ResultSetMetadata) error {
+	return nil
+}
+
+func (c *PlanCheck) WriteRow([]*structpb.Value) error {
+	return nil
+}
+
+func (c *PlanCheck) Finish(stats *sppb.ResultSetStats) error {
+	nodes := stats.GetQueryPlan().GetPlanNodes()
+	if len(nodes) == 0 {
+		return errNoPlan
+	}
+	findings := plancheck.Check(queryplan.New(nodes), c.config)
+	enc := json.NewEncoder(c.w)
+	enc.SetEscapeHTML(false)
+	errs := 0
+	for _, f := range findings {
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+		if f.Severity == plancheck.Error {
+			errs++
+		}
+	}
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+	if errs > 0 {
+		return fmt.Errorf("%w: %d of %d findings are errors", plancheck.ErrViolations, errs, len(findings))
+	}
+	return nil
+}
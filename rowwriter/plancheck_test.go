@@ -0,0 +1,49 @@
+package rowwriter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/plancheck"
+)
+
+func TestPlanCheckGolden(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]*sppb.ResultSet{
+		"singers": planFixture(t),
+		"albums":  readProfile(t, "albums_by_singer"),
+	}
+	for name, rs := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			err := WriteResultSet(NewPlanCheck(&buf, plancheck.DefaultConfig()), rs)
+			if wantErr := bytes.Contains(buf.Bytes(), []byte(`"severity":"error"`)); errors.Is(err, plancheck.ErrViolations) != wantErr {
+				t.Fatalf("WriteResultSet() error = %v with findings %q", err, buf.String())
+			}
+			checkGolden(t, "plan_check", name, buf.Bytes())
+		})
+	}
+}
+
+func TestPlanCheckWarnings(t *testing.T) {
+	t.Parallel()
+
+	config := plancheck.Config{Rules: []plancheck.RuleConfig{{Rule: plancheck.FullScan, Severity: plancheck.Warning}}}
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewPlanCheck(&buf, config), planFixture(t)); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	want := `{"rule":"full_scan","severity":"warning","node":5,"operator":"Table Scan","message":"full scan of Singers"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	if err := WriteResultSet(NewPlanCheck(&buf, config), singersFixture()); !errors.Is(err, errNoPlan) {
+		t.Fatalf("WriteResultSet() error = %v, want %v", err, errNoPlan)
+	}
+}
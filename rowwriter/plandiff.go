@@ -0,0 +1,199 @@
+package rowwriter
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/queryplan"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// diffMarkers mark the rows of [PlanDiff] output by how they changed.
+var diffMarkers = map[queryplan.Change]string{
+	queryplan.Same:    " ",
+	queryplan.Added:   "+",
+	queryplan.Removed: "-",
+	queryplan.Changed: "~",
+}
+
+// PlanDiff renders the differences between the query plan of a base result
+// and the plan of the result written to it, whose rows are ignored. The
+// trees merged by [queryplan.Diff] are drawn as a table like that of [Plan]:
+//
+//   - The first column marks added (+), removed (-) and changed (~) operators.
+//   - Base and ID are the node indexes in the base plan and in the other.
+//   - In PROFILE mode, Rows, Latency and CPU Time show the totals of both
+//     plans, as in "3 -> 10 (+233.3%)", or one total if they are equal or
+//     the operator is only in one plan.
+//
+// The table is followed by the changes of the changed operators and by the
+// query stats of both results.
+type PlanDiff struct {
+	w              *bufio.Writer
+	base           *sppb.ResultSetStats
+	baseName, name string
+}
+
+// NewPlanDiff returns a PlanDiff comparing against base, the stats of a
+// result labeled baseName; name labels the result written to it.
+func NewPlanDiff(w io.Writer, base *sppb.ResultSetStats, baseName, name string) *PlanDiff {
+	return &PlanDiff{w: bufio.NewWriter(w), base: base, baseName: baseName, name: name}
+}
+
+func (d *PlanDiff) WriteMetadata(*sppb.ResultSetMetadata) error {
+	return nil
+}
+
+func (d *PlanDiff) WriteRow([]*structpb.Value) error {
+	return nil
+}
+
+func (d *PlanDiff) Finish(stats *sppb.ResultSetStats) error {
+	baseNodes, nodes := d.base.GetQueryPlan().GetPlanNodes(), stats.GetQueryPlan().GetPlanNodes()
+	if len(baseNodes) == 0 {
+		return fmt.Errorf("%s: %w", d.baseName, errNoPlan)
+	}
+	if len(nodes) == 0 {
+		return errNoPlan
+	}
+	fmt.Fprintln(d.w, "--- "+d.baseName)
+	fmt.Fprintln(d.w, "+++ "+d.name)
+	writePlanDiff(d.w, queryplan.New(baseNodes), queryplan.New(nodes))
+	writeQueryStatsDiff(d.w, d.base.GetQueryStats(), stats.GetQueryStats())
+	return d.w.Flush()
+}
+
+func writePlanDiff(w io.Writer, base, plan *queryplan.Plan) {
+	profile := base.HasExecutionStats() || plan.HasExecutionStats()
+	diff := queryplan.Diff(base, plan)
+
+	header := []string{"", "Base", "ID", "Operator"}
+	if profile {
+		header = append(header, "Rows", "Latency", "CPU Time")
+	}
+	index := func(n *sppb.PlanNode) string {
+		if n == nil {
+			return ""
+		}
+		return strconv.Itoa(int(n.GetIndex()))
+	}
+	rows := make([][]string, len(diff))
+	counts := make(map[queryplan.Change]int)
+	var changes []string
+	var indent treeIndent
+	for i, r := range diff {
+		counts[r.Change]++
+		n := r.Node
+		if n == nil {
+			n = r.Base
+		}
+		row := []string{diffMarkers[r.Change], index(r.Base), index(r.Node), indent.prefix(r.Depth, r.Last) + operatorText(r.LinkType, n)}
+		if profile {
+			for _, name := range []string{"rows", "latency", "cpu_time"} {
+				if r.Base == nil || r.Node == nil {
+					row = append(row, queryplan.ExecutionStat(n, name).String())
+				} else {
+					row = append(row, statChange(queryplan.ExecutionStat(r.Base, name), queryplan.ExecutionStat(r.Node, name)))
+				}
+			}
+		}
+		rows[i] = row
+		for j, detail := range r.Details {
+			prefix := ""
+			if j == 0 {
+				prefix = index(r.Node) + ":"
+			}
+			changes = append(changes, fmt.Sprintf("%s %s", prefix, detail))
+		}
+	}
+	// The node indexes and stats are right-aligned.
+	writeASCIITable(w, header, rows, func(col int) bool { return col == 1 || col == 2 || col >= 4 })
+
+	if len(changes) > 0 {
+		fmt.Fprintln(w, "Changes(identified by ID):")
+		width := 0
+		for _, s := range changes {
+			prefix, _, _ := strings.Cut(s, " ")
+			width = max(width, len(prefix))
+		}
+		for _, s := range changes {
+			prefix, detail, _ := strings.Cut(s, " ")
+			fmt.Fprintf(w, "%s %s\n", padLeft(prefix, width), detail)
+		}
+	}
+	fmt.Fprintf(w, "Operators: %d changed, %d added, %d removed\n",
+		counts[queryplan.Changed], counts[queryplan.Added], counts[queryplan.Removed])
+}
+
+// writeQueryStatsDiff lists the query stats of both results, leaving out the
+// query text.
+func writeQueryStatsDiff(w io.Writer, base, stats *structpb.Struct) {
+	baseFields, fields := base.GetFields(), stats.GetFields()
+	var keys []string
+	for key := range baseFields {
+		keys = append(keys, key)
+	}
+	for key := range fields {
+		if _, ok := baseFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	keys = slices.DeleteFunc(keys, func(key string) bool { return key == "query_text" })
+	if len(keys) == 0 {
+		return
+	}
+	slices.Sort(keys)
+	fmt.Fprintln(w, "Query stats:")
+	for _, key := range keys {
+		fmt.Fprintf(w, "  %s: %s\n", key, statChange(queryStat(baseFields[key]), queryStat(fields[key])))
+	}
+}
+
+// queryStat splits a query stat such as "7.2 msecs" into its total and unit.
+func queryStat(v *structpb.Value) queryplan.Stat {
+	total, unit, _ := strings.Cut(queryplan.ValueText(v), " ")
+	return queryplan.Stat{Total: total, Unit: unit}
+}
+
+// statChange formats the totals of a stat in the base result and in the
+// other as "base -> other", followed by the relative change if both are
+// numbers in the same unit or durations. Equal totals are formatted once and
+// a missing total as "-".
+func statChange(base, s queryplan.Stat) string {
+	if base.Total == s.Total && base.Unit == s.Unit {
+		return s.String()
+	}
+	if base.Total == "" || s.Total == "" {
+		return cmp.Or(base.String(), "-") + " -> " + cmp.Or(s.String(), "-")
+	}
+	text := base.String() + " -> " + s.String()
+	var from, to float64
+	if bd, ok := base.Duration(); ok {
+		d, ok := s.Duration()
+		if !ok {
+			return text
+		}
+		from, to = float64(bd), float64(d)
+	} else {
+		if base.Unit != s.Unit {
+			return text
+		}
+		var err error
+		if from, err = strconv.ParseFloat(base.Total, 64); err != nil {
+			return text
+		}
+		if to, err = strconv.ParseFloat(s.Total, 64); err != nil {
+			return text
+		}
+	}
+	if from == 0 {
+		return text
+	}
+	return fmt.Sprintf("%s (%+.1f%%)", text, (to-from)/from*100)
+}
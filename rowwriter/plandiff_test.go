@@ -0,0 +1,95 @@
+package rowwriter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// indexScanFixture is profileFixture as if the scan of Singers had used an
+// index and taken longer.
+func indexScanFixture(t *testing.T) *sppb.ResultSet {
+	t.Helper()
+	rs := profileFixture(t)
+	for _, n := range rs.GetStats().GetQueryPlan().GetPlanNodes() {
+		md := n.GetMetadata().GetFields()
+		if n.GetIndex() == 5 {
+			md["scan_type"] = structpb.NewStringValue("IndexScan")
+			md["scan_target"] = structpb.NewStringValue("SingersByFirstLastName")
+		}
+		if latency := n.GetExecutionStats().GetFields()["latency"].GetStructValue().GetFields(); latency != nil && n.GetIndex() >= 2 {
+			latency["total"] = structpb.NewStringValue("1.5")
+		}
+	}
+	rs.GetStats().GetQueryStats().GetFields()["elapsed_time"] = structpb.NewStringValue("9.1 msecs")
+	return rs
+}
+
+func TestPlanDiffGolden(t *testing.T) {
+	t.Parallel()
+
+	plan := func(t *testing.T, rs *sppb.ResultSet) *sppb.ResultSet {
+		rs.Rows, rs.Stats.QueryStats = nil, nil
+		for _, n := range rs.GetStats().GetQueryPlan().GetPlanNodes() {
+			n.ExecutionStats = nil
+		}
+		return rs
+	}
+	tests := map[string]struct {
+		base, rs *sppb.ResultSet
+	}{
+		"same":    {profileFixture(t), profileFixture(t)},
+		"plan":    {planFixture(t), plan(t, indexScanFixture(t))},
+		"profile": {profileFixture(t), indexScanFixture(t)},
+		"albums":  {profileFixture(t), readProfile(t, "albums_by_singer")},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := WriteResultSet(NewPlanDiff(&buf, tt.base.GetStats(), "base.pb", "current"), tt.rs); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			checkGolden(t, "plan_diff", name, buf.Bytes())
+		})
+	}
+}
+
+func TestPlanDiffWithoutPlan(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewPlanDiff(&buf, singersFixture().GetStats(), "base.pb", "current"), profileFixture(t)); !errors.Is(err, errNoPlan) {
+		t.Fatalf("WriteResultSet() error = %v, want %v", err, errNoPlan)
+	}
+	if err := WriteResultSet(NewPlanDiff(&buf, profileFixture(t).GetStats(), "base.pb", "current"), singersFixture()); !errors.Is(err, errNoPlan) {
+		t.Fatalf("WriteResultSet() error = %v, want %v", err, errNoPlan)
+	}
+}
+
+func TestStatChange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		base, stat string
+		want       string
+	}{
+		{"3", "3", "3"},
+		{"3", "10", "3 -> 10 (+233.3%)"},
+		{"7.2 msecs", "900 usecs", "7.2 msecs -> 900 usecs (-87.5%)"},
+		{"0 msecs", "1 msecs", "0 msecs -> 1 msecs"},
+		{"8", "9", "8 -> 9 (+12.5%)"},
+		{"auto_1", "auto_2", "auto_1 -> auto_2"},
+		{"", "1 msecs", "- -> 1 msecs"},
+	}
+	for _, tt := range tests {
+		got := statChange(queryStat(structpb.NewStringValue(tt.base)), queryStat(structpb.NewStringValue(tt.stat)))
+		if got != tt.want {
+			t.Errorf("statChange(%q, %q) = %q, want %q", tt.base, tt.stat, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package rowwriter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/queryplan"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// PlanGraphFormat selects the diagram language of a [PlanGraph].
+type PlanGraphFormat string
+
+const (
+	PlanGraphDOT     PlanGraphFormat = "dot"
+	PlanGraphMermaid PlanGraphFormat = "mermaid"
+)
+
+// Edge widths of PROFILE plan graphs range from minEdgeWidth for no rows to
+// maxEdgeWidth for the edge carrying the most rows.
+const (
+	minEdgeWidth = 1.0
+	maxEdgeWidth = 8.0
+)
+
+// PlanGraph renders the query plan instead of the rows, which are ignored, as
+// a Graphviz DOT or Mermaid flowchart of the operators walked by
+// [queryplan.Plan.Walk], including scalar subqueries. Each parent links to its
+// children, with the child link type as the edge label.
+//
+// A node is labelled with its ID, operator and metadata, its predicates, and
+// in PROFILE mode its rows, executions, latency and CPU time. In PROFILE mode
+// the width of each edge is proportional to the rows its child produces.
+type PlanGraph struct {
+	w      *bufio.Writer
+	format PlanGraphFormat
+}
+
+func NewPlanGraph(w io.Writer, format PlanGraphFormat) *PlanGraph {
+	return &PlanGraph{w: bufio.NewWriter(w), format: format}
+}
+
+func (g *PlanGraph) WriteMetadata(*sppb.ResultSetMetadata) error {
+	return nil
+}
+
+func (g *PlanGraph) WriteRow([]*structpb.Value) error {
+	return nil
+}
+
+func (g *PlanGraph) Finish(stats *sppb.ResultSetStats) error {
+	nodes := stats.GetQueryPlan().GetPlanNodes()
+	if len(nodes) == 0 {
+		return errNoPlan
+	}
+	graph := newPlanGraphData(queryplan.New(nodes))
+	switch g.format {
+	case PlanGraphMermaid:
+		graph.writeMermaid(g.w)
+	default:
+		graph.writeDOT(g.w)
+	}
+	return g.w.Flush()
+}
+
+type planGraphNode struct {
+	id    int32
+	lines []string
+}
+
+type planGraphEdge struct {
+	from, to int32
+	label    string
+	// width is 0 in PLAN mode.
+	width float64
+}
+
+type planGraphData struct {
+	nodes []planGraphNode
+	edges []planGraphEdge
+}
+
+func newPlanGraphData(plan *queryplan.Plan) planGraphData {
+	var g planGraphData
+	walk := plan.Walk()
+	maxRows := 0.0
+	for _, r := range walk {
+		maxRows = max(maxRows, queryplan.ExecutionStat(r.Node, "rows").Value())
+	}
+	profile := plan.HasExecutionStats()
+	for _, r := range walk {
+		n := r.Node
+		lines := []string{fmt.Sprintf("%d: %s", n.GetIndex(), queryplan.Operator(n))}
+		lines = append(lines, queryplan.Properties(n)...)
+		for _, pred := range plan.Predicates(n) {
+			lines = append(lines, pred.Type+": "+pred.Description)
+		}
+		if profile {
+			lines = append(lines, planStatsLines(n)...)
+		}
+		g.nodes = append(g.nodes, planGraphNode{id: n.GetIndex(), lines: lines})
+
+		if r.Parent < 0 {
+			continue
+		}
+		e := planGraphEdge{from: r.Parent, to: n.GetIndex(), label: r.LinkType}
+		if profile {
+			e.width = minEdgeWidth
+			if maxRows > 0 {
+				e.width += (maxEdgeWidth - minEdgeWidth) * queryplan.ExecutionStat(n, "rows").Value() / maxRows
+			}
+		}
+		g.edges = append(g.edges, e)
+	}
+	return g
+}
+
+// planStatsLines summarizes the execution stats of n in up to two lines.
+func planStatsLines(n *sppb.PlanNode) []string {
+	join := func(kv ...string) string {
+		var parts []string
+		for i := 0; i < len(kv); i += 2 {
+			if kv[i+1] != "" {
+				parts = append(parts, kv[i]+": "+kv[i+1])
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+	var lines []string
+	for _, line := range []string{
+		join("rows", queryplan.ExecutionStat(n, "rows").String(), "executions", queryplan.Executions(n)),
+		join("latency", queryplan.ExecutionStat(n, "latency").String(), "cpu", queryplan.ExecutionStat(n, "cpu_time").String()),
+	} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func (g planGraphData) writeDOT(w io.Writer) {
+	fmt.Fprintln(w, "digraph plan {")
+	fmt.Fprintln(w, `  node [shape=box, fontname="monospace"];`)
+	for _, n := range g.nodes {
+		// \l left-justifies each line.
+		label := ""
+		for _, line := range n.lines {
+			label += dotEscape(line) + `\l`
+		}
+		fmt.Fprintf(w, "  n%d [label=\"%s\"];\n", n.id, label)
+	}
+	for _, e := range g.edges {
+		var attrs []string
+		if e.label != "" {
+			attrs = append(attrs, fmt.Sprintf("label=\"%s\"", dotEscape(e.label)))
+		}
+		if e.width > 0 {
+			attrs = append(attrs, "penwidth="+strconv.FormatFloat(e.width, 'f', 1, 64))
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(w, "  n%d -> n%d [%s];\n", e.from, e.to, strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(w, "  n%d -> n%d;\n", e.from, e.to)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// dotEscape escapes s for a double-quoted DOT string, where a backslash
+// starts an escape sequence such as \n.
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func (g planGraphData) writeMermaid(w io.Writer) {
+	fmt.Fprintln(w, "flowchart TD")
+	for _, n := range g.nodes {
+		lines := make([]string, len(n.lines))
+		for i, line := range n.lines {
+			lines[i] = mermaidEscape(line)
+		}
+		fmt.Fprintf(w, "  n%d[\"%s\"]\n", n.id, strings.Join(lines, "<br/>"))
+	}
+	for _, e := range g.edges {
+		if e.label != "" {
+			fmt.Fprintf(w, "  n%d -->|\"%s\"| n%d\n", e.from, mermaidEscape(e.label), e.to)
+		} else {
+			fmt.Fprintf(w, "  n%d --> n%d\n", e.from, e.to)
+		}
+	}
+	// Mermaid has no per-edge width attribute; links are styled by their order.
+	for i, e := range g.edges {
+		if e.width > 0 {
+			fmt.Fprintf(w, "  linkStyle %d stroke-width:%spx\n", i, strconv.FormatFloat(e.width, 'f', 1, 64))
+		}
+	}
+}
+
+// mermaidEscape replaces the characters that end or are interpreted in a
+// quoted Mermaid label with entity codes.
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`#`, "#35;", `"`, "#quot;", `<`, "#lt;", `>`, "#gt;", "\n", " ").Replace(s)
+}
@@ -0,0 +1,49 @@
+package rowwriter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+func TestPlanGraphGolden(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []PlanGraphFormat{PlanGraphDOT, PlanGraphMermaid} {
+		tests := map[string]*sppb.ResultSet{
+			"plan":    planFixture(t),
+			"profile": profileFixture(t),
+			"albums":  readProfile(t, "albums_by_singer"),
+		}
+		for name, rs := range tests {
+			t.Run(string(format)+"/"+name, func(t *testing.T) {
+				t.Parallel()
+
+				var buf bytes.Buffer
+				if err := WriteResultSet(NewPlanGraph(&buf, format), rs); err != nil {
+					t.Fatalf("WriteResultSet() error = %v", err)
+				}
+				checkGolden(t, "plan_"+string(format), name, buf.Bytes())
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewPlanGraph(&buf, PlanGraphDOT), singersFixture()); !errors.Is(err, errNoPlan) {
+		t.Fatalf("WriteResultSet() error = %v, want %v", err, errNoPlan)
+	}
+}
+
+func TestPlanGraphEscape(t *testing.T) {
+	t.Parallel()
+
+	const s = `($a > "x\y") #1`
+	if got, want := dotEscape(s), `($a > \"x\\y\") #1`; got != want {
+		t.Errorf("dotEscape() = %q, want %q", got, want)
+	}
+	if got, want := mermaidEscape(s), `($a #gt; #quot;x\y#quot;) #35;1`; got != want {
+		t.Errorf("mermaidEscape() = %q, want %q", got, want)
+	}
+}
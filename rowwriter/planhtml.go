@@ -0,0 +1,129 @@
+package rowwriter
+
+import (
+	"bufio"
+	_ "embed"
+	"html/template"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/queryplan"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+//go:embed planhtml.tmpl
+var planHTMLText string
+
+var planHTMLTemplate = template.Must(template.New("plan").Parse(planHTMLText))
+
+// PlanHTML renders the query plan instead of the rows, which are ignored, as
+// a self-contained HTML page for sharing a PLAN or PROFILE without Spanner
+// access. The page shows the SQL, the parameters and the query stats, and the
+// operators walked by [queryplan.Plan.Walk] as a tree of collapsible
+// <details> elements with their predicates, metadata and execution stats.
+// Scripts and styles are inline, so the page works offline; the tree is
+// usable without JavaScript.
+type PlanHTML struct {
+	w      *bufio.Writer
+	sql    string
+	params map[string]string
+}
+
+// NewPlanHTML returns a PlanHTML writer for the result of sql executed with
+// params, given as --param values.
+func NewPlanHTML(w io.Writer, sql string, params map[string]string) *PlanHTML {
+	return &PlanHTML{w: bufio.NewWriter(w), sql: sql, params: params}
+}
+
+func (p *PlanHTML) WriteMetadata(*sppb.ResultSetMetadata) error {
+	return nil
+}
+
+func (p *PlanHTML) WriteRow([]*structpb.Value) error {
+	return nil
+}
+
+func (p *PlanHTML) Finish(stats *sppb.ResultSetStats) error {
+	nodes := stats.GetQueryPlan().GetPlanNodes()
+	if len(nodes) == 0 {
+		return errNoPlan
+	}
+	plan := queryplan.New(nodes)
+	page := planHTMLPage{
+		SQL:     p.sql,
+		Profile: plan.HasExecutionStats(),
+		Root:    planHTMLTree(plan),
+	}
+	for _, name := range slices.Sorted(maps.Keys(p.params)) {
+		page.Params = append(page.Params, queryplan.Property{Key: name, Value: p.params[name]})
+	}
+	queryStats := stats.GetQueryStats().GetFields()
+	for _, name := range slices.Sorted(maps.Keys(queryStats)) {
+		page.QueryStats = append(page.QueryStats, queryplan.Property{Key: name, Value: queryplan.ValueText(queryStats[name])})
+	}
+	if err := planHTMLTemplate.Execute(p.w, page); err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+type planHTMLPage struct {
+	SQL        string
+	Params     []queryplan.Property
+	QueryStats []queryplan.Property
+	Profile    bool
+	Root       *planHTMLNode
+}
+
+type planHTMLNode struct {
+	ID         int32
+	LinkType   string
+	Operator   string
+	Summary    string
+	Hot        bool
+	Predicates []queryplan.Predicate
+	Metadata   []queryplan.Property
+	Stats      []planHTMLStat
+	Children   []*planHTMLNode
+}
+
+type planHTMLStat struct {
+	Name string
+	queryplan.Stat
+}
+
+// planHTMLTree builds the tree of the nodes walked from the root.
+func planHTMLTree(plan *queryplan.Plan) *planHTMLNode {
+	hot := plan.HotPath()
+	byIndex := make(map[int32]*planHTMLNode)
+	var root *planHTMLNode
+	for _, r := range plan.Walk() {
+		n := r.Node
+		node := &planHTMLNode{
+			ID:         n.GetIndex(),
+			LinkType:   r.LinkType,
+			Operator:   queryplan.Operator(n),
+			Hot:        hot[n.GetIndex()],
+			Predicates: plan.Predicates(n),
+			Metadata:   queryplan.Metadata(n),
+		}
+		node.Summary = strings.Join(planStatsLines(n), "; ")
+		if s := queryplan.Executions(n); s != "" {
+			node.Stats = append(node.Stats, planHTMLStat{Name: "executions", Stat: queryplan.Stat{Total: s}})
+		}
+		for _, name := range queryplan.StatNames(n) {
+			node.Stats = append(node.Stats, planHTMLStat{Name: name, Stat: queryplan.ExecutionStat(n, name)})
+		}
+
+		byIndex[n.GetIndex()] = node
+		if parent, ok := byIndex[r.Parent]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			root = node
+		}
+	}
+	return root
+}
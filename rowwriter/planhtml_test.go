@@ -0,0 +1,57 @@
+package rowwriter
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+func TestPlanHTMLGolden(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		rs     *sppb.ResultSet
+		sql    string
+		params map[string]string
+	}{
+		"plan":    {rs: planFixture(t)},
+		"profile": {rs: profileFixture(t), sql: "SELECT SingerId, FirstName FROM Singers ORDER BY SingerId LIMIT 3"},
+		"albums": {
+			rs:     readProfile(t, "albums_by_singer"),
+			sql:    "SELECT SingerId, AlbumId, AlbumTitle FROM Albums WHERE SingerId = @sid ORDER BY AlbumId LIMIT 2",
+			params: map[string]string{"sid": "2"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := WriteResultSet(NewPlanHTML(&buf, tc.sql, tc.params), tc.rs); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			checkGolden(t, "plan_html", name, buf.Bytes())
+		})
+	}
+}
+
+func TestPlanHTMLOffline(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewPlanHTML(&buf, "", nil), profileFixture(t)); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	for _, external := range []string{"<script src", "<link", "http://", "https://"} {
+		if strings.Contains(buf.String(), external) {
+			t.Errorf("output references an external resource: %q", external)
+		}
+	}
+
+	if err := WriteResultSet(NewPlanHTML(&buf, "", nil), singersFixture()); !errors.Is(err, errNoPlan) {
+		t.Fatalf("WriteResultSet() error = %v, want %v", err, errNoPlan)
+	}
+}
@@ -0,0 +1,164 @@
+package rowwriter
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/queryplan"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// planRowsStats are the execution stats that have columns in [PlanRows]
+// output; all stats are in the execution_stats column.
+var planRowsStats = []string{"rows", "latency", "cpu_time"}
+
+// PlanRows writes one row per plan node, as flattened by
+// [queryplan.Plan.Flatten], to another Writer instead of the rows of the
+// query, which are ignored. The columns are the same for every plan, so that
+// the rows of many queries can be appended to one table:
+//
+//   - query_hash, the first 16 hex digits of the SHA-256 of the SQL text,
+//     and query_text, to tell the queries apart, or NULL if the SQL text is
+//     not known;
+//   - index, parent_index, depth, kind, display_name, link_type and
+//     scan_target of the node, with parent_index NULL for the root;
+//   - metadata, all of the node's metadata as a JSON object;
+//   - executions, and the total, mean, std_deviation and unit of rows,
+//     latency and cpu_time as <stat>_total and so on;
+//   - execution_stats, all of the node's execution stats as a JSON object.
+//
+// The inner writer receives the query stats of the result.
+type PlanRows struct {
+	inner Writer
+	sql   string
+}
+
+// NewPlanRows returns a PlanRows for the plan of sql. An empty sql is taken
+// from the query_text query stat.
+func NewPlanRows(inner Writer, sql string) *PlanRows {
+	return &PlanRows{inner: inner, sql: sql}
+}
+
+func (p *PlanRows) WriteMetadata(*sppb.ResultSetMetadata) error {
+	return nil
+}
+
+func (p *PlanRows) WriteRow([]*structpb.Value) error {
+	return nil
+}
+
+func (p *PlanRows) Finish(stats *sppb.ResultSetStats) error {
+	nodes := stats.GetQueryPlan().GetPlanNodes()
+	if len(nodes) == 0 {
+		return errNoPlan
+	}
+	sql := cmp.Or(p.sql, stats.GetQueryStats().GetFields()["query_text"].GetStringValue())
+	rs, err := planRowsResultSet(queryplan.New(nodes), sql)
+	if err != nil {
+		return err
+	}
+	rs.Stats = &sppb.ResultSetStats{QueryStats: stats.GetQueryStats()}
+	return WriteResultSet(p.inner, rs)
+}
+
+func planRowsResultSet(plan *queryplan.Plan, sql string) (*sppb.ResultSet, error) {
+	typ := func(code sppb.TypeCode) *sppb.Type { return &sppb.Type{Code: code} }
+	var fields []*sppb.StructType_Field
+	field := func(name string, code sppb.TypeCode) {
+		fields = append(fields, &sppb.StructType_Field{Name: name, Type: typ(code)})
+	}
+	field("query_hash", sppb.TypeCode_STRING)
+	field("query_text", sppb.TypeCode_STRING)
+	field("index", sppb.TypeCode_INT64)
+	field("parent_index", sppb.TypeCode_INT64)
+	field("depth", sppb.TypeCode_INT64)
+	field("kind", sppb.TypeCode_STRING)
+	field("display_name", sppb.TypeCode_STRING)
+	field("link_type", sppb.TypeCode_STRING)
+	field("scan_target", sppb.TypeCode_STRING)
+	field("metadata", sppb.TypeCode_JSON)
+	field("executions", sppb.TypeCode_INT64)
+	for _, name := range planRowsStats {
+		field(name+"_total", sppb.TypeCode_FLOAT64)
+		field(name+"_mean", sppb.TypeCode_FLOAT64)
+		field(name+"_std_deviation", sppb.TypeCode_FLOAT64)
+		field(name+"_unit", sppb.TypeCode_STRING)
+	}
+	field("execution_stats", sppb.TypeCode_JSON)
+
+	null := structpb.NewNullValue()
+	str := func(s string) *structpb.Value {
+		if s == "" {
+			return null
+		}
+		return structpb.NewStringValue(s)
+	}
+	num := func(s string) *structpb.Value {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return null
+		}
+		return structpb.NewNumberValue(f)
+	}
+	int64Value := func(n int64) *structpb.Value { return structpb.NewStringValue(strconv.FormatInt(n, 10)) }
+	jsonValue := func(s *structpb.Struct) (*structpb.Value, error) {
+		if len(s.GetFields()) == 0 {
+			return null, nil
+		}
+		b, err := json.Marshal(s.AsMap())
+		if err != nil {
+			return nil, err
+		}
+		return structpb.NewStringValue(string(b)), nil
+	}
+	queryHash := null
+	if sql != "" {
+		hash := sha256.Sum256([]byte(sql))
+		queryHash = structpb.NewStringValue(hex.EncodeToString(hash[:8]))
+	}
+
+	rs := &sppb.ResultSet{Metadata: &sppb.ResultSetMetadata{RowType: &sppb.StructType{Fields: fields}}}
+	for _, r := range plan.Flatten() {
+		n := r.Node
+		parent := null
+		if r.Parent >= 0 {
+			parent = int64Value(int64(r.Parent))
+		}
+		metadata, err := jsonValue(n.GetMetadata())
+		if err != nil {
+			return nil, err
+		}
+		executionStats, err := jsonValue(n.GetExecutionStats())
+		if err != nil {
+			return nil, err
+		}
+		executions := null
+		if s := queryplan.Executions(n); s != "" {
+			executions = structpb.NewStringValue(s)
+		}
+		values := []*structpb.Value{
+			queryHash,
+			str(sql),
+			int64Value(int64(n.GetIndex())),
+			parent,
+			int64Value(int64(r.Depth)),
+			structpb.NewStringValue(n.GetKind().String()),
+			str(n.GetDisplayName()),
+			str(r.LinkType),
+			str(queryplan.ScanTarget(n)),
+			metadata,
+			executions,
+		}
+		for _, name := range planRowsStats {
+			s := queryplan.ExecutionStat(n, name)
+			values = append(values, num(s.Total), num(s.Mean), num(s.StdDev), str(s.Unit))
+		}
+		values = append(values, executionStats)
+		rs.Rows = append(rs.Rows, &structpb.ListValue{Values: values})
+	}
+	return rs, nil
+}
@@ -0,0 +1,49 @@
+package rowwriter
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+func TestPlanRowsGolden(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]*sppb.ResultSet{
+		"plan":    planFixture(t),
+		"profile": profileFixture(t),
+	}
+	for name, rs := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := WriteResultSet(NewPlanRows(NewCSV(&buf, CSVOptions{Delimiter: ','}), ""), rs); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			checkGolden(t, "plan_rows", name, buf.Bytes())
+		})
+	}
+
+	// PLAN and PROFILE rows share the header, so they can go in one table.
+	var headers []string
+	for _, rs := range []*sppb.ResultSet{planFixture(t), profileFixture(t), readProfile(t, "albums_by_singer")} {
+		var buf bytes.Buffer
+		if err := WriteResultSet(NewPlanRows(NewCSV(&buf, CSVOptions{Delimiter: ','}), "SELECT 1"), rs); err != nil {
+			t.Fatalf("WriteResultSet() error = %v", err)
+		}
+		header, _, _ := strings.Cut(buf.String(), "\n")
+		headers = append(headers, header)
+	}
+	if headers[0] != headers[1] || headers[0] != headers[2] {
+		t.Errorf("headers differ:\n%s", strings.Join(headers, "\n"))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewPlanRows(NewCSV(&buf, CSVOptions{Delimiter: ','}), ""), singersFixture()); !errors.Is(err, errNoPlan) {
+		t.Fatalf("WriteResultSet() error = %v, want %v", err, errNoPlan)
+	}
+}
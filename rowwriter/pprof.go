@@ -0,0 +1,100 @@
+package rowwriter
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/queryplan"
+	"github.com/google/pprof/profile"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Pprof renders the execution stats of a PROFILE plan instead of the rows,
+// which are ignored, as a gzipped pprof profile for go tool pprof. Each plan
+// node is a function whose samples hold the CPU time and latency spent in the
+// node itself (see [queryplan.Plan.Samples]) and whose stack is the path of
+// operators from the root. CPU time is the default sample type.
+type Pprof struct {
+	w   io.Writer
+	sql string
+}
+
+// NewPprof returns a Pprof writer. sql is recorded as a comment of the profile.
+func NewPprof(w io.Writer, sql string) *Pprof {
+	return &Pprof{w: w, sql: sql}
+}
+
+func (p *Pprof) WriteMetadata(*sppb.ResultSetMetadata) error {
+	return nil
+}
+
+func (p *Pprof) WriteRow([]*structpb.Value) error {
+	return nil
+}
+
+func (p *Pprof) Finish(stats *sppb.ResultSetStats) error {
+	plan, err := profilePlan(stats)
+	if err != nil {
+		return err
+	}
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "cpu", Unit: "nanoseconds"},
+			{Type: "latency", Unit: "nanoseconds"},
+		},
+		DefaultSampleType: "cpu",
+	}
+	if p.sql != "" {
+		prof.Comments = append(prof.Comments, p.sql)
+	}
+	if elapsed, ok := parseStatDuration(stats.GetQueryStats().GetFields()["elapsed_time"].GetStringValue()); ok {
+		prof.DurationNanos = elapsed.Nanoseconds()
+	}
+
+	locations := make(map[int32]*profile.Location)
+	location := func(n *sppb.PlanNode) *profile.Location {
+		if loc, ok := locations[n.GetIndex()]; ok {
+			return loc
+		}
+		fn := &profile.Function{
+			ID:         uint64(len(prof.Function) + 1),
+			Name:       frameName(n),
+			SystemName: queryplan.Operator(n),
+			Filename:   queryplan.ScanTarget(n),
+		}
+		prof.Function = append(prof.Function, fn)
+		loc := &profile.Location{ID: uint64(len(prof.Location) + 1), Line: []profile.Line{{Function: fn}}}
+		prof.Location = append(prof.Location, loc)
+		locations[n.GetIndex()] = loc
+		return loc
+	}
+	for _, s := range plan.Samples() {
+		if s.CPU <= 0 && s.Latency <= 0 {
+			continue
+		}
+		// pprof stacks start at the leaf.
+		stack := make([]*profile.Location, len(s.Stack))
+		for i, n := range s.Stack {
+			stack[len(s.Stack)-1-i] = location(n)
+		}
+		prof.Sample = append(prof.Sample, &profile.Sample{
+			Location: stack,
+			Value:    []int64{s.CPU.Nanoseconds(), s.Latency.Nanoseconds()},
+		})
+	}
+	if err := prof.CheckValid(); err != nil {
+		return err
+	}
+	return prof.Write(p.w)
+}
+
+// parseStatDuration parses a query stat such as "20.6 msecs".
+func parseStatDuration(s string) (time.Duration, bool) {
+	total, unit, ok := strings.Cut(s, " ")
+	if !ok {
+		return 0, false
+	}
+	return queryplan.Stat{Total: total, Unit: unit}.Duration()
+}
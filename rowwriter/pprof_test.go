@@ -0,0 +1,60 @@
+package rowwriter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/pprof/profile"
+)
+
+func TestPprof(t *testing.T) {
+	t.Parallel()
+
+	const sql = "SELECT SingerId, FirstName FROM Singers ORDER BY SingerId LIMIT 3"
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewPprof(&buf, sql), profileFixture(t)); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	prof, err := profile.Parse(&buf)
+	if err != nil {
+		t.Fatalf("profile.Parse() error = %v", err)
+	}
+
+	if diff := cmp.Diff([]string{sql}, prof.Comments); diff != "" {
+		t.Errorf("comments mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := prof.DurationNanos, int64(20_600_000); got != want {
+		t.Errorf("DurationNanos = %d, want %d", got, want)
+	}
+
+	// Stacks are leaf first; values are CPU time and latency in nanoseconds.
+	// Local Limit #3 spends no time of its own and has no sample.
+	type sample struct {
+		Stack  []string
+		Values []int64
+	}
+	var got []sample
+	for _, s := range prof.Sample {
+		var stack []string
+		for _, loc := range s.Location {
+			stack = append(stack, loc.Line[0].Function.Name)
+		}
+		got = append(got, sample{stack, s.Value})
+	}
+	want := []sample{
+		{[]string{"Global Limit #0"}, []int64{10_000, 0}},
+		{[]string{"Distributed Union #1", "Global Limit #0"}, []int64{590_000, 7_090_000}},
+		{[]string{"Serialize Result #2", "Distributed Union #1", "Global Limit #0"}, []int64{10_000, 10_000}},
+		{[]string{"Local Distributed Union #4", "Local Limit #3", "Serialize Result #2", "Distributed Union #1", "Global Limit #0"}, []int64{10_000, 10_000}},
+		{[]string{"Table Scan (Singers) #5", "Local Distributed Union #4", "Local Limit #3", "Serialize Result #2", "Distributed Union #1", "Global Limit #0"}, []int64{90_000, 90_000}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("samples mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := WriteResultSet(NewPprof(&buf, sql), planFixture(t)); !errors.Is(err, errNoProfile) {
+		t.Errorf("WriteResultSet(PLAN) error = %v, want %v", err, errNoProfile)
+	}
+}
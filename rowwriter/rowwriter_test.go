@@ -0,0 +1,106 @@
+package rowwriter
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "rewrite testdata/*/*.golden")
+
+func resultSet(names []string, types []*sppb.Type, rows [][]*structpb.Value) *sppb.ResultSet {
+	fields := make([]*sppb.StructType_Field, len(names))
+	for i, name := range names {
+		fields[i] = &sppb.StructType_Field{Name: name, Type: types[i]}
+	}
+	listRows := make([]*structpb.ListValue, len(rows))
+	for i, values := range rows {
+		listRows[i] = &structpb.ListValue{Values: values}
+	}
+	return &sppb.ResultSet{
+		Metadata: &sppb.ResultSetMetadata{RowType: &sppb.StructType{Fields: fields}},
+		Rows:     listRows,
+	}
+}
+
+func withElapsed(rs *sppb.ResultSet, elapsed string) *sppb.ResultSet {
+	rs.Stats = &sppb.ResultSetStats{QueryStats: &structpb.Struct{Fields: map[string]*structpb.Value{
+		"elapsed_time": structpb.NewStringValue(elapsed),
+	}}}
+	return rs
+}
+
+// singersFixture is a small multi-type result shared by the writer golden tests.
+func singersFixture() *sppb.ResultSet {
+	return withElapsed(resultSet(
+		[]string{"SingerId", "Name", "Tags", "Note"},
+		[]*sppb.Type{
+			{Code: sppb.TypeCode_INT64},
+			{Code: sppb.TypeCode_STRING},
+			{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: sppb.TypeCode_STRING}},
+			{Code: sppb.TypeCode_STRING},
+		},
+		[][]*structpb.Value{
+			{
+				structpb.NewStringValue("1"), structpb.NewStringValue("Marc"),
+				structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("rock"), structpb.NewStringValue("pop")}}),
+				structpb.NewNullValue(),
+			},
+			{
+				structpb.NewStringValue("2"), structpb.NewStringValue("山田太郎"),
+				structpb.NewListValue(&structpb.ListValue{}),
+				structpb.NewStringValue("multi\nline"),
+			},
+		},
+	), "1.23 msecs")
+}
+
+// fixedClock makes the measured elapsed time deterministic.
+func fixedClock(d time.Duration) (time.Time, func() time.Time) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	return start, func() time.Time { return start.Add(d) }
+}
+
+func checkGolden(t *testing.T, dir, name string, got []byte) {
+	t.Helper()
+	goldenPath := filepath.Join("testdata", dir, name+".golden")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		t.Logf("updated %s", goldenPath)
+		return
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("ReadFile(%q) error = %v (run: go test -update-golden ./rowwriter)", goldenPath, err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("output mismatch for %s\n\ngot:\n%s\n\nwant:\n%s", goldenPath, got, want)
+	}
+}
+
+func TestWriteResultSetInvalidMetadata(t *testing.T) {
+	t.Parallel()
+
+	if err := WriteResultSet(NewTable(&discard{}, TableStyleASCII), &sppb.ResultSet{}); err == nil {
+		t.Fatal("WriteResultSet() expected error for missing metadata")
+	}
+	rs := resultSet([]string{"id"}, []*sppb.Type{{Code: sppb.TypeCode_INT64}}, nil)
+	rs.Rows = []*structpb.ListValue{nil}
+	if err := WriteResultSet(NewTable(&discard{}, TableStyleASCII), rs); err == nil {
+		t.Fatal("WriteResultSet() expected error for nil row")
+	}
+}
+
+type discard struct{}
+
+func (*discard) Write(p []byte) (int, error) { return len(p), nil }
@@ -0,0 +1,289 @@
+package rowwriter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/jqresult"
+	"github.com/apstndb/execspansql/valuefmt"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	// Registers the pure-Go "sqlite" driver.
+	_ "modernc.org/sqlite"
+)
+
+// sqliteColumnType returns the declared column type, which determines the
+// SQLite type affinity. NUMERIC, DATE and TIMESTAMP are TEXT so that values
+// keep their precision and stay usable with the SQLite date functions;
+// ARRAY and STRUCT are JSON text for the SQLite JSON functions.
+func sqliteColumnType(typ *sppb.Type) string {
+	switch typ.GetCode() {
+	case sppb.TypeCode_BOOL, sppb.TypeCode_INT64, sppb.TypeCode_ENUM:
+		return "INTEGER"
+	case sppb.TypeCode_FLOAT32, sppb.TypeCode_FLOAT64:
+		return "REAL"
+	case sppb.TypeCode_BYTES, sppb.TypeCode_PROTO:
+		return "BLOB"
+	default:
+		return "TEXT"
+	}
+}
+
+// sqliteValue converts v to an argument for a column of sqliteColumnType(typ).
+// SQLite stores NaN as NULL.
+func sqliteValue(typ *sppb.Type, v *structpb.Value) (any, error) {
+	if isNull(v) {
+		return nil, nil
+	}
+	switch typ.GetCode() {
+	case sppb.TypeCode_BOOL:
+		k, ok := v.GetKind().(*structpb.Value_BoolValue)
+		if !ok {
+			return nil, valueKindError(typ, v)
+		}
+		if k.BoolValue {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case sppb.TypeCode_INT64, sppb.TypeCode_ENUM:
+		n, err := strconv.ParseInt(v.GetStringValue(), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value: %w", valuefmt.TypeString(typ), err)
+		}
+		return n, nil
+	case sppb.TypeCode_FLOAT32, sppb.TypeCode_FLOAT64:
+		return floatValue(typ, v)
+	case sppb.TypeCode_BYTES, sppb.TypeCode_PROTO:
+		return decodeBytes(typ, v)
+	case sppb.TypeCode_ARRAY, sppb.TypeCode_STRUCT:
+		return jsonText(typ, v)
+	default:
+		k, ok := v.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return nil, valueKindError(typ, v)
+		}
+		return k.StringValue, nil
+	}
+}
+
+func quoteSQLiteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// SQLite writes rows into a table of a SQLite database file, committing a
+// transaction every batchSize rows. The table is created from the row type
+// with column names made unique by [jqresult.ObjectKeys].
+//
+// Rows are written to a staging database next to path, which Finish moves
+// into place, so a failed query leaves path as it was. Unless appendTo is
+// set, an existing file at path is replaced. With appendTo, the staged rows
+// are copied into the existing database and the table is created only when
+// it does not exist yet, so several queries can land in one database.
+type SQLite struct {
+	path      string
+	table     string
+	appendTo  bool
+	batchSize int
+	fields    []*sppb.StructType_Field
+	defs      []string
+	columns   []string
+	staging   string
+	db        *sql.DB
+	insert    *sql.Stmt
+	tx        *sql.Tx
+	pending   int
+}
+
+func NewSQLite(path, table string, appendTo bool, batchSize int) *SQLite {
+	return &SQLite{path: path, table: table, appendTo: appendTo, batchSize: max(batchSize, 1)}
+}
+
+func (s *SQLite) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	s.fields = metadata.GetRowType().GetFields()
+	if len(s.fields) == 0 {
+		return fmt.Errorf("sqlite: the statement returns no columns")
+	}
+	if err := s.openStaging(); err != nil {
+		s.abort()
+		return err
+	}
+	return nil
+}
+
+// openStaging creates the staging database with the table and the INSERT statement.
+func (s *SQLite) openStaging() error {
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	s.staging = f.Name()
+	// CreateTemp creates the file readable only by the owner, and Finish
+	// renames it into place.
+	if err := f.Chmod(0o644); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite", s.staging)
+	if err != nil {
+		return err
+	}
+	s.db = db
+
+	names := jqresult.ObjectKeys(s.fields)
+	s.columns = make([]string, len(names))
+	placeholders := make([]string, len(names))
+	for i, name := range names {
+		s.columns[i] = quoteSQLiteIdent(name)
+		placeholders[i] = "?"
+	}
+	s.defs = make([]string, len(names))
+	for i, f := range s.fields {
+		s.defs[i] = s.columns[i] + " " + sqliteColumnType(f.GetType())
+	}
+	if _, err := db.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", quoteSQLiteIdent(s.table), strings.Join(s.defs, ", "))); err != nil {
+		return err
+	}
+	s.insert, err = db.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteSQLiteIdent(s.table), strings.Join(s.columns, ", "), strings.Join(placeholders, ", ")))
+	return err
+}
+
+func (s *SQLite) WriteRow(values []*structpb.Value) error {
+	if err := s.writeRow(values); err != nil {
+		s.abort()
+		return err
+	}
+	return nil
+}
+
+func (s *SQLite) writeRow(values []*structpb.Value) error {
+	if len(s.fields) != len(values) {
+		return fmt.Errorf("row has %d values but row type has %d fields", len(values), len(s.fields))
+	}
+	args := make([]any, len(values))
+	for i, v := range values {
+		arg, err := sqliteValue(s.fields[i].GetType(), v)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", s.fields[i].GetName(), err)
+		}
+		args[i] = arg
+	}
+
+	if s.tx == nil {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		s.tx = tx
+	}
+	if _, err := s.tx.Stmt(s.insert).Exec(args...); err != nil {
+		return err
+	}
+	s.pending++
+	if s.pending == s.batchSize {
+		return s.commit()
+	}
+	return nil
+}
+
+func (s *SQLite) commit() error {
+	tx := s.tx
+	s.tx, s.pending = nil, 0
+	return tx.Commit()
+}
+
+func (s *SQLite) Finish(*sppb.ResultSetStats) error {
+	if err := s.finish(); err != nil {
+		s.abort()
+		return err
+	}
+	return nil
+}
+
+func (s *SQLite) finish() error {
+	if s.tx != nil {
+		if err := s.commit(); err != nil {
+			return err
+		}
+	}
+	if err := s.insert.Close(); err != nil {
+		return err
+	}
+	db := s.db
+	s.db = nil
+	if err := db.Close(); err != nil {
+		return err
+	}
+	if !s.appendTo {
+		return os.Rename(s.staging, s.path)
+	}
+	if err := s.copyStaged(); err != nil {
+		return err
+	}
+	return os.Remove(s.staging)
+}
+
+// copyStaged adds the staged rows to the table of the database at path in
+// one transaction.
+func (s *SQLite) copyStaged() (err error) {
+	db, err := sql.Open("sqlite", s.path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := db.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	// ATTACH applies to one connection.
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), "ATTACH DATABASE ? AS staged", s.staging); err != nil {
+		return err
+	}
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	table, columns := quoteSQLiteIdent(s.table), strings.Join(s.columns, ", ")
+	for _, stmt := range []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS main.%s (%s)", table, strings.Join(s.defs, ", ")),
+		fmt.Sprintf("INSERT INTO main.%s (%s) SELECT %s FROM staged.%s", table, columns, columns, table),
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(context.Background(), "DETACH DATABASE staged")
+	return err
+}
+
+// abort rolls back and closes the staging database and removes it.
+func (s *SQLite) abort() {
+	if s.tx != nil {
+		_ = s.tx.Rollback()
+		s.tx = nil
+	}
+	if s.db != nil {
+		_ = s.db.Close()
+		s.db = nil
+	}
+	if s.staging != "" {
+		_ = os.Remove(s.staging)
+	}
+}
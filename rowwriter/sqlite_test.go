@@ -0,0 +1,148 @@
+package rowwriter
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSQLite(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "out.db")
+	if err := WriteResultSet(NewSQLite(path, "Columnar", false, 2), columnarFixture()); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	if fi, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0o644 {
+		t.Errorf("mode = %v, want %v", fi.Mode().Perm(), os.FileMode(0o644))
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var id, b int64
+	var n, d, ts, js, tags, st string
+	var f float64
+	var raw []byte
+	err = db.QueryRow(`SELECT id, n, f, b, d, ts, bytes, json, tags, st FROM Columnar WHERE id = 1`).
+		Scan(&id, &n, &f, &b, &d, &ts, &raw, &js, &tags, &st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []any{id, n, f, b, d, ts, raw, js, tags, st}
+	want := []any{
+		int64(1), "12.5", 1.5, int64(1), "2024-06-01", "2024-06-01T12:34:56.123456789Z",
+		[]byte{0x00, 0x01, 0xff}, `{"a":1}`, `["x",null]`, `{"i":"7","_2":"s"}`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("row 1 mismatch (-want +got):\n%s", diff)
+	}
+
+	var types string
+	err = db.QueryRow(`SELECT group_concat(type, ',') FROM pragma_table_info('Columnar')`).Scan(&types)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "INTEGER,TEXT,REAL,INTEGER,TEXT,TEXT,BLOB,TEXT,TEXT,TEXT"; types != want {
+		t.Errorf("column types = %s, want %s", types, want)
+	}
+
+	var nulls int
+	if err := db.QueryRow(`SELECT count(*) FROM Columnar WHERE id = 2 AND n IS NULL AND tags IS NULL`).Scan(&nulls); err != nil {
+		t.Fatal(err)
+	}
+	if nulls != 1 {
+		t.Errorf("NULL row count = %d, want 1", nulls)
+	}
+}
+
+func TestSQLiteAppend(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "out.db")
+	writes := []struct {
+		table    string
+		appendTo bool
+	}{
+		{"Singers", false},
+		{"Singers", true},
+		{"Columnar", true},
+	}
+	for _, w := range writes {
+		rs := singersFixture()
+		if w.table == "Columnar" {
+			rs = columnarFixture()
+		}
+		if err := WriteResultSet(NewSQLite(path, w.table, w.appendTo, 100), rs); err != nil {
+			t.Fatalf("WriteResultSet(%s) error = %v", w.table, err)
+		}
+	}
+
+	var singers, columnar int
+	queryRow(t, path, `SELECT (SELECT count(*) FROM Singers), (SELECT count(*) FROM Columnar)`, &singers, &columnar)
+	if singers != 4 || columnar != 3 {
+		t.Errorf("row counts = (%d, %d), want (4, 3)", singers, columnar)
+	}
+
+	// Without append, the file is replaced.
+	if err := WriteResultSet(NewSQLite(path, "Singers", false, 100), singersFixture()); err != nil {
+		t.Fatal(err)
+	}
+	var tables int
+	queryRow(t, path, `SELECT count(*) FROM sqlite_schema WHERE type = 'table'`, &tables)
+	if tables != 1 {
+		t.Errorf("tables after replace = %d, want 1", tables)
+	}
+}
+
+func TestSQLiteFailure(t *testing.T) {
+	t.Parallel()
+
+	for _, appendTo := range []bool{false, true} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "out.db")
+		if err := WriteResultSet(NewSQLite(path, "Singers", false, 100), singersFixture()); err != nil {
+			t.Fatal(err)
+		}
+
+		// The second row does not match the row type.
+		rs := singersFixture()
+		rs.Rows[1].Values = rs.Rows[1].Values[:1]
+		if err := WriteResultSet(NewSQLite(path, "Singers", appendTo, 1), rs); err == nil {
+			t.Fatalf("WriteResultSet(append=%v) expected an error", appendTo)
+		}
+
+		var singers int
+		queryRow(t, path, `SELECT count(*) FROM Singers`, &singers)
+		if singers != 2 {
+			t.Errorf("append=%v: row count = %d, want the 2 rows written before", appendTo, singers)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("append=%v: %d files left in the directory, want only out.db", appendTo, len(entries))
+		}
+	}
+}
+
+func queryRow(t *testing.T, path, query string, dest ...any) {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.QueryRow(query).Scan(dest...); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,205 @@
+package rowwriter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/valuefmt"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// TableStyle selects the layout of a [Table].
+type TableStyle string
+
+const (
+	TableStyleASCII    TableStyle = "ascii"
+	TableStyleUnicode  TableStyle = "unicode"
+	TableStyleVertical TableStyle = "vertical"
+)
+
+// ParseTableStyle validates s as a TableStyle.
+func ParseTableStyle(s string) (TableStyle, error) {
+	switch TableStyle(s) {
+	case TableStyleASCII, TableStyleUnicode, TableStyleVertical:
+		return TableStyle(s), nil
+	default:
+		return "", fmt.Errorf("table style must be ascii, unicode or vertical")
+	}
+}
+
+// tableValueFormat renders NULL the way interactive Spanner clients do.
+var tableValueFormat = valuefmt.Config{Null: "NULL"}
+
+type tableBorder struct {
+	horizontal, vertical                  string
+	topLeft, topMiddle, topRight          string
+	middleLeft, middleMiddle, middleRight string
+	bottomLeft, bottomMiddle, bottomRight string
+}
+
+var (
+	asciiBorder = tableBorder{
+		horizontal: "-", vertical: "|",
+		topLeft: "+", topMiddle: "+", topRight: "+",
+		middleLeft: "+", middleMiddle: "+", middleRight: "+",
+		bottomLeft: "+", bottomMiddle: "+", bottomRight: "+",
+	}
+	unicodeBorder = tableBorder{
+		horizontal: "─", vertical: "│",
+		topLeft: "┌", topMiddle: "┬", topRight: "┐",
+		middleLeft: "├", middleMiddle: "┼", middleRight: "┤",
+		bottomLeft: "└", bottomMiddle: "┴", bottomRight: "┘",
+	}
+)
+
+// Table renders rows as a bordered text table with the row type in the header,
+// or as one record per row in vertical style, followed by a rows/elapsed footer.
+//
+// Column widths depend on every row, so the bordered styles buffer formatted
+// cells until Finish. The vertical style writes each row as it arrives.
+type Table struct {
+	w      *bufio.Writer
+	style  TableStyle
+	fields []*sppb.StructType_Field
+	rows   [][]string
+	count  int64
+
+	start time.Time
+	now   func() time.Time
+}
+
+// NewTable returns a Table writing to w. Elapsed time in the footer is measured
+// from this call unless the stats carry queryStats.elapsed_time.
+func NewTable(w io.Writer, style TableStyle) *Table {
+	return &Table{w: bufio.NewWriter(w), style: style, start: time.Now(), now: time.Now}
+}
+
+func (t *Table) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	t.fields = metadata.GetRowType().GetFields()
+	return nil
+}
+
+func (t *Table) WriteRow(values []*structpb.Value) error {
+	cells, err := tableValueFormat.FormatRow(t.fields, values)
+	if err != nil {
+		return err
+	}
+	t.count++
+	if t.style == TableStyleVertical {
+		return t.writeVerticalRow(cells)
+	}
+	t.rows = append(t.rows, cells)
+	return nil
+}
+
+func (t *Table) Finish(stats *sppb.ResultSetStats) error {
+	if t.style != TableStyleVertical && len(t.fields) > 0 {
+		t.writeTable()
+	}
+	fmt.Fprintln(t.w, t.footer(stats))
+	return t.w.Flush()
+}
+
+func (t *Table) writeVerticalRow(cells []string) error {
+	nameWidth := 0
+	for _, f := range t.fields {
+		nameWidth = max(nameWidth, displayWidth(f.GetName()))
+	}
+	stars := strings.Repeat("*", 27)
+	fmt.Fprintf(t.w, "%s %d. row %s\n", stars, t.count, stars)
+	for i, f := range t.fields {
+		fmt.Fprintf(t.w, "%s: %s\n", padLeft(f.GetName(), nameWidth), cells[i])
+	}
+	return nil
+}
+
+func (t *Table) writeTable() {
+	border := asciiBorder
+	if t.style == TableStyleUnicode {
+		border = unicodeBorder
+	}
+
+	names := make([]string, len(t.fields))
+	types := make([]string, len(t.fields))
+	for i, f := range t.fields {
+		names[i] = f.GetName()
+		types[i] = valuefmt.TypeString(f.GetType())
+	}
+
+	widths := make([]int, len(t.fields))
+	for _, row := range append([][]string{names, types}, t.rows...) {
+		for i, cell := range row {
+			for _, line := range strings.Split(cell, "\n") {
+				widths[i] = max(widths[i], displayWidth(line))
+			}
+		}
+	}
+
+	rule := func(left, middle, right string) {
+		segments := make([]string, len(widths))
+		for i, w := range widths {
+			segments[i] = strings.Repeat(border.horizontal, w+2)
+		}
+		fmt.Fprintln(t.w, left+strings.Join(segments, middle)+right)
+	}
+	line := func(row []string) {
+		cellLines := make([][]string, len(row))
+		height := 1
+		for i, cell := range row {
+			cellLines[i] = strings.Split(cell, "\n")
+			height = max(height, len(cellLines[i]))
+		}
+		for l := range height {
+			var sb strings.Builder
+			sb.WriteString(border.vertical)
+			for i, lines := range cellLines {
+				var s string
+				if l < len(lines) {
+					s = lines[l]
+				}
+				sb.WriteString(" " + padRight(s, widths[i]) + " " + border.vertical)
+			}
+			fmt.Fprintln(t.w, sb.String())
+		}
+	}
+
+	rule(border.topLeft, border.topMiddle, border.topRight)
+	line(names)
+	line(types)
+	rule(border.middleLeft, border.middleMiddle, border.middleRight)
+	for _, row := range t.rows {
+		line(row)
+	}
+	rule(border.bottomLeft, border.bottomMiddle, border.bottomRight)
+}
+
+func (t *Table) footer(stats *sppb.ResultSetStats) string {
+	elapsed := stats.GetQueryStats().GetFields()["elapsed_time"].GetStringValue()
+	if elapsed == "" {
+		elapsed = t.now().Sub(t.start).Round(time.Microsecond).String()
+	}
+
+	if len(t.fields) == 0 {
+		switch rc := stats.GetRowCount().(type) {
+		case *sppb.ResultSetStats_RowCountExact:
+			return fmt.Sprintf("Query OK, %s affected (%s)", plural(rc.RowCountExact, "row"), elapsed)
+		case *sppb.ResultSetStats_RowCountLowerBound:
+			return fmt.Sprintf("Query OK, at least %s affected (%s)", plural(rc.RowCountLowerBound, "row"), elapsed)
+		}
+	}
+	if t.count == 0 {
+		return fmt.Sprintf("Empty set (%s)", elapsed)
+	}
+	return fmt.Sprintf("%s in set (%s)", plural(t.count, "row"), elapsed)
+}
+
+func plural(n int64, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,84 @@
+package rowwriter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestTableGolden(t *testing.T) {
+	dml := &sppb.ResultSet{
+		Metadata: &sppb.ResultSetMetadata{RowType: &sppb.StructType{}},
+		Stats:    &sppb.ResultSetStats{RowCount: &sppb.ResultSetStats_RowCountExact{RowCountExact: 3}},
+	}
+	empty := resultSet([]string{"id"}, []*sppb.Type{{Code: sppb.TypeCode_INT64}}, nil)
+
+	tests := map[string]struct {
+		style TableStyle
+		rs    *sppb.ResultSet
+	}{
+		"ascii":    {TableStyleASCII, singersFixture()},
+		"unicode":  {TableStyleUnicode, singersFixture()},
+		"vertical": {TableStyleVertical, singersFixture()},
+		"empty":    {TableStyleASCII, empty},
+		"dml":      {TableStyleASCII, dml},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tbl := NewTable(&buf, tc.style)
+			tbl.start, tbl.now = fixedClock(1500 * time.Microsecond)
+			if err := WriteResultSet(tbl, tc.rs); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			checkGolden(t, "table", name, buf.Bytes())
+		})
+	}
+}
+
+func TestTableRowValueMismatch(t *testing.T) {
+	t.Parallel()
+
+	rs := resultSet([]string{"id", "name"},
+		[]*sppb.Type{{Code: sppb.TypeCode_INT64}, {Code: sppb.TypeCode_STRING}},
+		[][]*structpb.Value{{structpb.NewStringValue("1")}},
+	)
+	if err := WriteResultSet(NewTable(&bytes.Buffer{}, TableStyleASCII), rs); err == nil {
+		t.Fatal("WriteResultSet() expected row value count mismatch error")
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]int{
+		"abc":      3,
+		"山田":       4,
+		"ｱｲ":       2,
+		"e\u0301":  1,
+		"":         0,
+		"한국어":      6,
+		"Ａ１":       4,
+		"\u200bab": 2,
+		"─│":       2,
+	}
+	for s, want := range tests {
+		if got := displayWidth(s); got != want {
+			t.Errorf("displayWidth(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestParseTableStyle(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParseTableStyle("unicode"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseTableStyle("markdown"); err == nil {
+		t.Fatal("ParseTableStyle() expected error for unknown style")
+	}
+}
@@ -0,0 +1,269 @@
+package rowwriter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"cloud.google.com/go/civil"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/jqresult"
+	"github.com/apstndb/execspansql/valuefmt"
+	"github.com/cloudspannerecosystem/memefish/token"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Names of the templates executed before the first row and after the last.
+const (
+	TemplateHeader = "header"
+	TemplateFooter = "footer"
+)
+
+// TemplateResult is the data of the header and footer templates.
+type TemplateResult struct {
+	// Columns are the row keys in row type order (see [jqresult.ObjectKeys]).
+	Columns []string
+	// Types are the column types in GoogleSQL syntax, such as ARRAY<INT64>.
+	Types []string
+	// Metadata and Stats are the protojson objects of the result, as in jq.
+	// Stats is nil in the header and when the result has no stats.
+	Metadata map[string]any
+	Stats    map[string]any
+	// RowCount is the number of rows written; it is zero in the header.
+	RowCount int64
+}
+
+// TemplateFuncs are the functions available to templates in addition to the
+// text/template builtins.
+var TemplateFuncs = template.FuncMap{
+	"sqlQuote":   sqlQuote,
+	"sqlLiteral": func(any) (string, error) { return "", errNoTemplateRow },
+	"json":       templateJSON,
+	"base64":     templateBase64,
+	"dateFormat": dateFormat,
+}
+
+// ParseTemplate parses the row template text with [TemplateFuncs]. The header
+// and footer templates may be defined in text with {{define "header"}} and
+// {{define "footer"}}, or given separately; non-empty header and footer
+// arguments take precedence.
+func ParseTemplate(text, header, footer string) (*template.Template, error) {
+	tmpl, err := template.New("row").Funcs(TemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range []struct{ name, text string }{{TemplateHeader, header}, {TemplateFooter, footer}} {
+		if t.text == "" {
+			continue
+		}
+		if _, err := tmpl.New(t.name).Parse(t.text); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
+
+// Template executes a text/template for each row, with the row as a map keyed
+// by column name (see [jqresult.ObjectKeys]) and values rendered by render, so
+// NULL is nil and INT64 a string unless render says otherwise. The header and
+// footer templates, if defined, receive a [TemplateResult].
+//
+// sqlLiteral renders a column of the current row by name or index as a
+// literal of the column type. It reads the value as Spanner returned it, so
+// it does not depend on render.
+//
+// A newline is added after each execution unless its output is empty or
+// already ends with one, so a one-line template prints one line per row and
+// {{if}} can skip rows. Rows are written as they arrive.
+type Template struct {
+	w       *bufio.Writer
+	tmpl    *template.Template
+	err     error
+	render  jqresult.RenderOptions
+	fields  []*sppb.StructType_Field
+	columns map[string]int
+	values  []*structpb.Value
+	result  TemplateResult
+	buf     bytes.Buffer
+}
+
+func NewTemplate(w io.Writer, tmpl *template.Template, render jqresult.RenderOptions) *Template {
+	t := &Template{w: bufio.NewWriter(w), render: render}
+	// sqlLiteral reads the current row of this writer, so each writer binds
+	// it in its own copy of tmpl.
+	t.tmpl, t.err = tmpl.Clone()
+	if t.err == nil {
+		t.tmpl.Funcs(template.FuncMap{"sqlLiteral": t.sqlLiteral})
+	}
+	return t
+}
+
+func (t *Template) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	if t.err != nil {
+		return t.err
+	}
+	t.fields = metadata.GetRowType().GetFields()
+	t.result.Columns = jqresult.ObjectKeys(t.fields)
+	t.columns = make(map[string]int, len(t.result.Columns))
+	for i, name := range t.result.Columns {
+		t.columns[name] = i
+	}
+	t.result.Types = make([]string, len(t.fields))
+	for i, f := range t.fields {
+		t.result.Types[i] = valuefmt.TypeString(f.GetType())
+	}
+	md, err := jqresult.MetadataMapFromMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	t.result.Metadata = md
+	if t.tmpl.Lookup(TemplateHeader) == nil {
+		return nil
+	}
+	return t.execute(TemplateHeader, t.result)
+}
+
+func (t *Template) WriteRow(values []*structpb.Value) error {
+	obj, err := t.render.StructValuesToObject(t.fields, values)
+	if err != nil {
+		return err
+	}
+	t.result.RowCount++
+	t.values = values
+	defer func() { t.values = nil }()
+	return t.execute(t.tmpl.Name(), obj.Map())
+}
+
+func (t *Template) Finish(stats *sppb.ResultSetStats) error {
+	if t.err != nil {
+		return t.err
+	}
+	if t.tmpl.Lookup(TemplateFooter) != nil {
+		if stats != nil {
+			m, err := jqresult.ProtoToMap(stats)
+			if err != nil {
+				return err
+			}
+			t.result.Stats = m
+		}
+		if err := t.execute(TemplateFooter, t.result); err != nil {
+			return err
+		}
+	}
+	return t.w.Flush()
+}
+
+func (t *Template) execute(name string, data any) error {
+	t.buf.Reset()
+	if err := t.tmpl.ExecuteTemplate(&t.buf, name, data); err != nil {
+		return err
+	}
+	if t.buf.Len() == 0 {
+		return nil
+	}
+	if !bytes.HasSuffix(t.buf.Bytes(), []byte("\n")) {
+		t.buf.WriteByte('\n')
+	}
+	_, err := t.w.Write(t.buf.Bytes())
+	return err
+}
+
+// sqlQuote renders a row value as a GoogleSQL literal: a string literal for
+// strings, NULL for nil, and numbers and booleans as they are.
+func sqlQuote(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return token.QuoteSQLString(v), nil
+	case bool:
+		return strings.ToUpper(fmt.Sprint(v)), nil
+	case json.Number, float64, int, int64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("sqlQuote: unsupported value of type %T", v)
+	}
+}
+
+var errNoTemplateRow = errors.New("sqlLiteral: no current row")
+
+// sqlLiteral renders the column of the current row given by name or index as
+// a GoogleSQL literal of the column type with [valuefmt.Literal], such as 1,
+// NUMERIC "1.5", b"hi" or JSON '{"a":1}'; NULL is CAST(NULL AS T).
+func (t *Template) sqlLiteral(column any) (string, error) {
+	if t.values == nil {
+		return "", errNoTemplateRow
+	}
+	var i int
+	switch c := column.(type) {
+	case string:
+		n, ok := t.columns[c]
+		if !ok {
+			return "", fmt.Errorf("sqlLiteral: no column %q", c)
+		}
+		i = n
+	case int:
+		if c < 0 || c >= len(t.fields) {
+			return "", fmt.Errorf("sqlLiteral: column index %d out of range", c)
+		}
+		i = c
+	default:
+		return "", fmt.Errorf("sqlLiteral: column must be a name or an index, not %T", column)
+	}
+	s, err := valuefmt.Literal(t.fields[i].GetType(), t.values[i])
+	if err != nil {
+		return "", fmt.Errorf("sqlLiteral: %w", err)
+	}
+	return s, nil
+}
+
+// templateJSON encodes v as compact JSON without HTML escaping.
+func templateJSON(v any) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func templateBase64(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return base64.StdEncoding.EncodeToString([]byte(v)), nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	default:
+		return "", fmt.Errorf("base64: unsupported value of type %T", v)
+	}
+}
+
+// dateFormat formats a TIMESTAMP (RFC 3339) or DATE (YYYY-MM-DD) value with a
+// Go time layout. NULL formats as the empty string.
+func dateFormat(layout string, v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case time.Time:
+		return v.Format(layout), nil
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t.Format(layout), nil
+		}
+		d, err := civil.ParseDate(v)
+		if err != nil {
+			return "", fmt.Errorf("dateFormat: %q is neither a TIMESTAMP nor a DATE", v)
+		}
+		return d.In(time.UTC).Format(layout), nil
+	default:
+		return "", fmt.Errorf("dateFormat: unsupported value of type %T", v)
+	}
+}
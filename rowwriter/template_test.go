@@ -0,0 +1,235 @@
+package rowwriter
+
+import (
+	"bytes"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/jqresult"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                 string
+		text, header, footer string
+		render               jqresult.RenderOptions
+		want                 string
+	}{
+		{
+			name: "one line per row",
+			text: `{{.SingerId}}: {{.Name}}`,
+			want: "1: Marc\n2: 山田太郎\n",
+		},
+		{
+			name: "funcs",
+			text: `INSERT INTO T VALUES ({{sqlLiteral "SingerId"}}, {{sqlQuote .Name}}, {{sqlQuote .Note}}, {{json .Tags | sqlQuote}});`,
+			want: `INSERT INTO T VALUES (1, "Marc", NULL, '["rock","pop"]');` + "\n" +
+				`INSERT INTO T VALUES (2, "山田太郎", "multi\nline", "[]");` + "\n",
+		},
+		{
+			name:   "typed numbers",
+			text:   `{{sqlQuote .SingerId}}`,
+			render: jqresult.RenderOptions{TypedNumbers: true},
+			want:   "1\n2\n",
+		},
+		{
+			name: "typed values compare as strings",
+			text: `{{if eq .SingerId "2"}}{{printf "%s=%q" .SingerId .Name}}{{end}}`,
+			want: "2=\"山田太郎\"\n",
+		},
+		{
+			name: "skip rows",
+			text: `{{if .Note}}{{.SingerId}}{{end}}`,
+			want: "2\n",
+		},
+		{
+			name:   "header and footer",
+			text:   "{{.SingerId}}\n",
+			header: `#{{range .Columns}} {{.}}{{end}} {{index .Types 2}}`,
+			footer: `# {{.RowCount}} rows in {{.Stats.queryStats.elapsed_time}}`,
+			want:   "# SingerId Name Tags Note ARRAY<STRING>\n1\n2\n# 2 rows in 1.23 msecs\n",
+		},
+		{
+			name: "defined header",
+			text: `{{define "header"}}{{len .Metadata.rowType.fields}} columns{{end}}{{.Name}}`,
+			want: "4 columns\nMarc\n山田太郎\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, err := ParseTemplate(tc.text, tc.header, tc.footer)
+			if err != nil {
+				t.Fatalf("ParseTemplate() error = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := WriteResultSet(NewTemplate(&buf, tmpl, tc.render), singersFixture()); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			if buf.String() != tc.want {
+				t.Errorf("got %q, want %q", buf.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	t.Parallel()
+
+	rs := resultSet(
+		[]string{"ts", "d", "b"},
+		[]*sppb.Type{{Code: sppb.TypeCode_TIMESTAMP}, {Code: sppb.TypeCode_DATE}, {Code: sppb.TypeCode_BYTES}},
+		[][]*structpb.Value{
+			{structpb.NewStringValue("2024-06-01T12:34:56.5Z"), structpb.NewStringValue("2024-06-01"), structpb.NewStringValue("aGk=")},
+			{structpb.NewNullValue(), structpb.NewNullValue(), structpb.NewNullValue()},
+		},
+	)
+	tmpl, err := ParseTemplate(`{{dateFormat "Jan 2 15:04" .ts}}|{{dateFormat "2006/01/02" .d}}|{{.b}}|{{base64 "hi"}}`, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewTemplate(&buf, tmpl, jqresult.RenderOptions{Bytes: jqresult.BytesUTF8}), rs); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	if want := "Jun 1 12:34|2024/06/01|hi|aGk=\n||<no value>|aGk=\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTemplateSQLLiteral(t *testing.T) {
+	t.Parallel()
+
+	types := []*sppb.Type{
+		{Code: sppb.TypeCode_INT64},
+		{Code: sppb.TypeCode_NUMERIC},
+		{Code: sppb.TypeCode_FLOAT64},
+		{Code: sppb.TypeCode_DATE},
+		{Code: sppb.TypeCode_BYTES},
+		{Code: sppb.TypeCode_JSON},
+		{Code: sppb.TypeCode_INTERVAL},
+		{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: sppb.TypeCode_INT64}},
+		{Code: sppb.TypeCode_STRING},
+	}
+	rs := resultSet(
+		[]string{"i", "n", "f", "d", "b", "j", "iv", "a", "s"},
+		types,
+		[][]*structpb.Value{
+			{
+				structpb.NewStringValue("1"),
+				structpb.NewStringValue("1.5"),
+				structpb.NewStringValue("NaN"),
+				structpb.NewStringValue("2024-06-01"),
+				structpb.NewStringValue("aGk="),
+				structpb.NewStringValue(`{"a":1}`),
+				structpb.NewStringValue("P1Y2M3D"),
+				structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("1"), structpb.NewNullValue()}}),
+				structpb.NewStringValue("1"),
+			},
+			{
+				structpb.NewNullValue(), structpb.NewNullValue(), structpb.NewNullValue(),
+				structpb.NewNullValue(), structpb.NewNullValue(), structpb.NewNullValue(),
+				structpb.NewNullValue(), structpb.NewNullValue(), structpb.NewNullValue(),
+			},
+		},
+	)
+	const text = `{{sqlLiteral "i"}}, {{sqlLiteral "n"}}, {{sqlLiteral "f"}}, {{sqlLiteral "d"}}, {{sqlLiteral "b"}}, ` +
+		`{{sqlLiteral "j"}}, {{sqlLiteral "iv"}}, {{sqlLiteral "a"}}, {{sqlLiteral 8}}`
+	want := `1, NUMERIC "1.5", CAST("nan" AS FLOAT64), DATE "2024-06-01", b"hi", JSON '{"a":1}', CAST("P1Y2M3D" AS INTERVAL), ARRAY<INT64>[1, NULL], "1"` + "\n" +
+		`CAST(NULL AS INT64), CAST(NULL AS NUMERIC), CAST(NULL AS FLOAT64), CAST(NULL AS DATE), CAST(NULL AS BYTES), ` +
+		`CAST(NULL AS JSON), CAST(NULL AS INTERVAL), CAST(NULL AS ARRAY<INT64>), CAST(NULL AS STRING)` + "\n"
+	tests := []struct {
+		name   string
+		render jqresult.RenderOptions
+	}{
+		{name: "protojson"},
+		{
+			name: "rendered",
+			render: jqresult.RenderOptions{
+				TypedNumbers: true,
+				Bytes:        jqresult.BytesHex,
+				DateLayout:   "01/02/2006",
+				Interval:     jqresult.IntervalObject,
+				ExpandJSON:   true,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, err := ParseTemplate(text, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := WriteResultSet(NewTemplate(&buf, tmpl, tc.render), rs); err != nil {
+				t.Fatalf("WriteResultSet() error = %v", err)
+			}
+			if buf.String() != want {
+				t.Errorf("got %q, want %q", buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestTemplateSharedParse(t *testing.T) {
+	t.Parallel()
+
+	// Writers built from one parsed template each read their own rows.
+	tmpl, err := ParseTemplate(`{{sqlLiteral "SingerId"}}`, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first, second bytes.Buffer
+	w1, w2 := NewTemplate(&first, tmpl, jqresult.RenderOptions{}), NewTemplate(&second, tmpl, jqresult.RenderOptions{})
+	if err := WriteResultSet(w2, singersFixture()); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	if err := WriteResultSet(w1, singersFixture()); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+	if first.String() != "1\n2\n" || second.String() != "1\n2\n" {
+		t.Errorf("got %q and %q, want both %q", first.String(), second.String(), "1\n2\n")
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParseTemplate(`{{.a`, "", ""); err == nil {
+		t.Error("ParseTemplate() expected a syntax error")
+	}
+	if _, err := ParseTemplate(`{{.a}}`, `{{undefined}}`, ""); err == nil {
+		t.Error("ParseTemplate() expected an error for an undefined function")
+	}
+
+	rs := resultSet([]string{"a"}, []*sppb.Type{{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: sppb.TypeCode_INT64}}},
+		[][]*structpb.Value{{structpb.NewListValue(&structpb.ListValue{})}})
+	tmpl, err := ParseTemplate(`{{sqlQuote .a}}`, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteResultSet(NewTemplate(&bytes.Buffer{}, tmpl, jqresult.RenderOptions{}), rs); err == nil {
+		t.Error("WriteResultSet() expected sqlQuote to reject an array")
+	}
+
+	for _, tc := range []struct{ text, header string }{
+		{text: `{{sqlLiteral "b"}}`},
+		{text: `{{sqlLiteral 1}}`},
+		{text: `{{sqlLiteral 1.5}}`},
+		{text: `{{.a}}`, header: `{{sqlLiteral "a"}}`},
+	} {
+		tmpl, err := ParseTemplate(tc.text, tc.header, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteResultSet(NewTemplate(&bytes.Buffer{}, tmpl, jqresult.RenderOptions{}), rs); err == nil {
+			t.Errorf("WriteResultSet(%s, %s) expected an error", tc.text, tc.header)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package rowwriter
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// displayWidth returns the number of terminal cells s occupies.
+// East Asian wide and fullwidth runes take two cells; ambiguous runes take one.
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+func runeWidth(r rune) int {
+	if unicode.IsControl(r) || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// padRight pads s with spaces to n display cells.
+func padRight(s string, n int) string {
+	if w := displayWidth(s); w < n {
+		return s + strings.Repeat(" ", n-w)
+	}
+	return s
+}
+
+// padLeft right-aligns s in n display cells.
+func padLeft(s string, n int) string {
+	if w := displayWidth(s); w < n {
+		return strings.Repeat(" ", n-w) + s
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package rowwriter
+
+import (
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/resultset"
+	"github.com/apstndb/spaniter"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Writer receives a query result incrementally.
+type Writer interface {
+	// WriteMetadata is called once before any row.
+	WriteMetadata(metadata *sppb.ResultSetMetadata) error
+	// WriteRow is called for each row with values in row type order.
+	WriteRow(values []*structpb.Value) error
+	// Finish is called after the last row. stats is nil when the result carries no stats.
+	Finish(stats *sppb.ResultSetStats) error
+}
+
+// WriteRowIterator streams rowIter to w without materializing a ResultSet.
+// rowIter must not have been read yet; WriteRowIterator owns and stops it.
+// When redact is true, metadata and stats are written but rows are not.
+// Stats encoding is configured via spaniter options such as WithStatsEncoding.
+func WriteRowIterator(w Writer, rowIter *spanner.RowIterator, redact bool, opts ...spaniter.Option) error {
+	if rowIter == nil {
+		return errors.New("nil row iterator")
+	}
+	var result spaniter.RowIteratorResult
+	allOpts := append(append([]spaniter.Option(nil), opts...), spaniter.WithResult(&result))
+
+	metadataWritten := false
+	for row, err := range spaniter.RowIteratorSeq(rowIter, allOpts...) {
+		if err != nil {
+			abort(w)
+			return err
+		}
+		if !metadataWritten {
+			if err := writeMetadata(w, result.Metadata); err != nil {
+				return err
+			}
+			metadataWritten = true
+		}
+		if redact {
+			continue
+		}
+		if err := w.WriteRow(resultset.RowToListValue(row).GetValues()); err != nil {
+			return err
+		}
+	}
+	if !metadataWritten {
+		if err := writeMetadata(w, result.Metadata); err != nil {
+			return err
+		}
+	}
+	stats, err := result.StatsProto()
+	if err != nil {
+		abort(w)
+		return err
+	}
+	return w.Finish(stats)
+}
+
+// WriteResultSet feeds a materialized ResultSet to w.
+func WriteResultSet(w Writer, rs *sppb.ResultSet) error {
+	if err := writeMetadata(w, rs.GetMetadata()); err != nil {
+		return err
+	}
+	for _, row := range rs.GetRows() {
+		if row == nil {
+			return fmt.Errorf("nil row in result set")
+		}
+		if err := w.WriteRow(row.GetValues()); err != nil {
+			return err
+		}
+	}
+	return w.Finish(rs.GetStats())
+}
+
+// aborter is implemented by writers that release a file or a transaction
+// when the result fails before Finish; their own errors release it already.
+type aborter interface {
+	abort()
+}
+
+func abort(w Writer) {
+	if a, ok := w.(aborter); ok {
+		a.abort()
+	}
+}
+
+func writeMetadata(w Writer, metadata *sppb.ResultSetMetadata) error {
+	if metadata == nil || metadata.GetRowType() == nil {
+		return errors.New("result set metadata is missing or invalid")
+	}
+	return w.WriteMetadata(metadata)
+}
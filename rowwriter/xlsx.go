@@ -0,0 +1,286 @@
+package rowwriter
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/civil"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/apstndb/execspansql/queryplan"
+	"github.com/apstndb/execspansql/valuefmt"
+	"github.com/xuri/excelize/v2"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Sheet names of the XLSX workbook.
+const (
+	xlsxResultsSheet = "Results"
+	xlsxStatsSheet   = "Stats"
+)
+
+// Excel stores numbers as IEEE 754 doubles, which represent integers exactly up to 2^53.
+const xlsxMaxExactInt = 1 << 53
+
+// Excel dates start in 1900; earlier DATE and TIMESTAMP values are written as text.
+const xlsxMinYear = 1900
+
+type xlsxStyles struct {
+	name, typ, date, timestamp int
+}
+
+// XLSX writes rows to the Results sheet of an Excel workbook. The first row
+// holds the column names and the second their Spanner types. BOOL, INT64,
+// FLOAT and NUMERIC become native boolean and number cells, DATE and TIMESTAMP
+// (in UTC) date cells, ARRAY and STRUCT JSON text, and other types text.
+// Numbers that a double cannot hold exactly, NaN, infinities and dates before
+// 1900 are written as text so that no digit is lost.
+//
+// Rows are streamed through a temporary file, so the result is not held in
+// memory; the workbook is written to w by Finish. When includeStats is true
+// and the result has stats, a Stats sheet lists the query stats and the plan
+// nodes with their row counts and latencies.
+type XLSX struct {
+	w            io.Writer
+	includeStats bool
+	file         *excelize.File
+	sheet        *excelize.StreamWriter
+	styles       xlsxStyles
+	fields       []*sppb.StructType_Field
+	row          int
+}
+
+func NewXLSX(w io.Writer, includeStats bool) *XLSX {
+	return &XLSX{w: w, includeStats: includeStats}
+}
+
+func (x *XLSX) WriteMetadata(metadata *sppb.ResultSetMetadata) error {
+	if err := x.writeMetadata(metadata); err != nil {
+		x.abort()
+		return err
+	}
+	return nil
+}
+
+func (x *XLSX) writeMetadata(metadata *sppb.ResultSetMetadata) error {
+	x.fields = metadata.GetRowType().GetFields()
+	x.file = excelize.NewFile()
+	if err := x.file.SetSheetName("Sheet1", xlsxResultsSheet); err != nil {
+		return err
+	}
+	if err := x.newStyles(); err != nil {
+		return err
+	}
+	sw, err := x.file.NewStreamWriter(xlsxResultsSheet)
+	if err != nil {
+		return err
+	}
+	x.sheet = sw
+	if len(x.fields) == 0 {
+		return nil
+	}
+
+	// Keep the header visible while scrolling; panes must precede the rows.
+	if err := sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 2, TopLeftCell: "A3", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+	names := make([]any, len(x.fields))
+	types := make([]any, len(x.fields))
+	for i, f := range x.fields {
+		names[i] = excelize.Cell{StyleID: x.styles.name, Value: f.GetName()}
+		types[i] = excelize.Cell{StyleID: x.styles.typ, Value: valuefmt.TypeString(f.GetType())}
+	}
+	if err := x.setRow(names); err != nil {
+		return err
+	}
+	return x.setRow(types)
+}
+
+func (x *XLSX) newStyles() error {
+	dateFormat, timestampFormat := "yyyy-mm-dd", "yyyy-mm-dd hh:mm:ss.000"
+	for _, s := range []struct {
+		id    *int
+		style *excelize.Style
+	}{
+		{&x.styles.name, &excelize.Style{Font: &excelize.Font{Bold: true}}},
+		{&x.styles.typ, &excelize.Style{Font: &excelize.Font{Italic: true, Color: "808080"}}},
+		{&x.styles.date, &excelize.Style{CustomNumFmt: &dateFormat}},
+		{&x.styles.timestamp, &excelize.Style{CustomNumFmt: &timestampFormat}},
+	} {
+		id, err := x.file.NewStyle(s.style)
+		if err != nil {
+			return err
+		}
+		*s.id = id
+	}
+	return nil
+}
+
+func (x *XLSX) setRow(values []any) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.sheet.SetRow(cell, values)
+}
+
+func (x *XLSX) WriteRow(values []*structpb.Value) error {
+	if err := x.writeRow(values); err != nil {
+		x.abort()
+		return err
+	}
+	return nil
+}
+
+func (x *XLSX) writeRow(values []*structpb.Value) error {
+	if len(x.fields) != len(values) {
+		return fmt.Errorf("row has %d values but row type has %d fields", len(values), len(x.fields))
+	}
+	cells := make([]any, len(values))
+	for i, v := range values {
+		cell, err := x.cell(x.fields[i].GetType(), v)
+		if err != nil {
+			return fmt.Errorf("column %d (%s): %w", i, x.fields[i].GetName(), err)
+		}
+		cells[i] = cell
+	}
+	return x.setRow(cells)
+}
+
+func (x *XLSX) cell(typ *sppb.Type, v *structpb.Value) (any, error) {
+	if isNull(v) {
+		return nil, nil
+	}
+	switch typ.GetCode() {
+	case sppb.TypeCode_BOOL:
+		k, ok := v.GetKind().(*structpb.Value_BoolValue)
+		if !ok {
+			return nil, valueKindError(typ, v)
+		}
+		return k.BoolValue, nil
+	case sppb.TypeCode_INT64, sppb.TypeCode_ENUM:
+		s := v.GetStringValue()
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value: %w", valuefmt.TypeString(typ), err)
+		}
+		if n > xlsxMaxExactInt || n < -xlsxMaxExactInt {
+			return s, nil
+		}
+		return n, nil
+	case sppb.TypeCode_FLOAT32, sppb.TypeCode_FLOAT64:
+		f, err := floatValue(typ, v)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return v.GetStringValue(), nil
+		}
+		return f, nil
+	case sppb.TypeCode_NUMERIC:
+		s := v.GetStringValue()
+		if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && strconv.FormatFloat(f, 'f', -1, 64) == s {
+			return f, nil
+		}
+		return s, nil
+	case sppb.TypeCode_DATE:
+		d, err := civil.ParseDate(v.GetStringValue())
+		if err != nil {
+			return nil, fmt.Errorf("invalid DATE value: %w", err)
+		}
+		if d.Year < xlsxMinYear {
+			return d.String(), nil
+		}
+		return excelize.Cell{StyleID: x.styles.date, Value: d.In(time.UTC)}, nil
+	case sppb.TypeCode_TIMESTAMP:
+		t, err := time.Parse(time.RFC3339Nano, v.GetStringValue())
+		if err != nil {
+			return nil, fmt.Errorf("invalid TIMESTAMP value: %w", err)
+		}
+		if t.Year() < xlsxMinYear {
+			return v.GetStringValue(), nil
+		}
+		return excelize.Cell{StyleID: x.styles.timestamp, Value: t.UTC()}, nil
+	case sppb.TypeCode_ARRAY, sppb.TypeCode_STRUCT:
+		return jsonText(typ, v)
+	default:
+		k, ok := v.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return nil, valueKindError(typ, v)
+		}
+		return k.StringValue, nil
+	}
+}
+
+func (x *XLSX) Finish(stats *sppb.ResultSetStats) error {
+	defer x.file.Close()
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	if x.includeStats && stats != nil {
+		if err := x.writeStats(stats); err != nil {
+			return err
+		}
+	}
+	return x.file.Write(x.w)
+}
+
+// abort closes the workbook, which removes the temporary files of its
+// stream writers.
+func (x *XLSX) abort() {
+	if x.file != nil {
+		_ = x.file.Close()
+		x.file = nil
+	}
+}
+
+// writeStats writes the query stats as name/value rows, followed by one row per plan node.
+func (x *XLSX) writeStats(stats *sppb.ResultSetStats) error {
+	if _, err := x.file.NewSheet(xlsxStatsSheet); err != nil {
+		return err
+	}
+	sw, err := x.file.NewStreamWriter(xlsxStatsSheet)
+	if err != nil {
+		return err
+	}
+	x.sheet, x.row = sw, 0
+
+	bold := func(s string) excelize.Cell { return excelize.Cell{StyleID: x.styles.name, Value: s} }
+	if err := x.setRow([]any{bold("Stat"), bold("Value")}); err != nil {
+		return err
+	}
+	queryStats := stats.GetQueryStats().GetFields()
+	for _, k := range slices.Sorted(maps.Keys(queryStats)) {
+		if err := x.setRow([]any{k, queryplan.ValueText(queryStats[k])}); err != nil {
+			return err
+		}
+	}
+	switch c := stats.GetRowCount().(type) {
+	case *sppb.ResultSetStats_RowCountExact:
+		err = x.setRow([]any{"row_count_exact", c.RowCountExact})
+	case *sppb.ResultSetStats_RowCountLowerBound:
+		err = x.setRow([]any{"row_count_lower_bound", c.RowCountLowerBound})
+	}
+	if err != nil {
+		return err
+	}
+
+	if nodes := stats.GetQueryPlan().GetPlanNodes(); len(nodes) > 0 {
+		x.row++ // blank separator row
+		if err := x.setRow([]any{bold("Index"), bold("Kind"), bold("Display name"), bold("Rows"), bold("Latency")}); err != nil {
+			return err
+		}
+		for _, n := range nodes {
+			row := []any{n.GetIndex(), n.GetKind().String(), n.GetDisplayName(), queryplan.ExecutionStat(n, "rows").String(), queryplan.ExecutionStat(n, "latency").String()}
+			if err := x.setRow(row); err != nil {
+				return err
+			}
+		}
+	}
+	return sw.Flush()
+}
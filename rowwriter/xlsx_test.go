@@ -0,0 +1,158 @@
+package rowwriter
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/xuri/excelize/v2"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestXLSX(t *testing.T) {
+	t.Parallel()
+
+	rs := columnarFixture()
+	rs.Rows = append(rs.Rows, &structpb.ListValue{Values: []*structpb.Value{
+		structpb.NewStringValue("9007199254740993"), structpb.NewStringValue("0.1"), structpb.NewStringValue("Infinity"),
+		structpb.NewNullValue(), structpb.NewNullValue(), structpb.NewNullValue(), structpb.NewNullValue(),
+		structpb.NewNullValue(), structpb.NewNullValue(), structpb.NewNullValue(),
+	}})
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewXLSX(&buf, false), rs); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got := f.GetSheetList(); !cmp.Equal(got, []string{xlsxResultsSheet}) {
+		t.Errorf("GetSheetList() = %v, want [%s]", got, xlsxResultsSheet)
+	}
+
+	rows, err := f.GetRows(xlsxResultsSheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"id", "n", "f", "b", "d", "ts", "bytes", "json", "tags", "st"},
+		{"INT64", "NUMERIC", "FLOAT64", "BOOL", "DATE", "TIMESTAMP", "BYTES", "JSON", "ARRAY<STRING>", "STRUCT<i INT64, STRING>"},
+		{"1", "12.5", "1.5", "TRUE", "2024-06-01", "2024-06-01 12:34:56.000", "AAH/", `{"a":1}`, `["x",null]`, `{"i":"7","_2":"s"}`},
+		{"2", "", "NaN"},
+		{"3", "-0.000000001", "0", "FALSE", "0001-01-01", "1970-01-01 00:00:00.000", "", "null", "[]", `{"i":null,"_2":null}`},
+		{"9007199254740993", "0.1", "Infinity"},
+	}
+	if diff := cmp.Diff(want, rows); diff != "" {
+		t.Errorf("GetRows() mismatch (-want +got):\n%s", diff)
+	}
+
+	// excelize formats without fractional seconds, but the serial number keeps them.
+	raw, err := f.GetCellValue(xlsxResultsSheet, "F3", excelize.Options{RawCellValue: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTS := time.Date(2024, 6, 1, 12, 34, 56, 123000000, time.UTC)
+	wantSerial := wantTS.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24
+	if math.Abs(serial-wantSerial)*24*float64(time.Hour) > float64(time.Millisecond) {
+		t.Errorf("F3 = %v, want %v (%v)", serial, wantSerial, wantTS)
+	}
+
+	// Numbers have no cell type attribute; text is written as inline strings.
+	for cell, want := range map[string]excelize.CellType{
+		"A3": excelize.CellTypeUnset,
+		"B3": excelize.CellTypeUnset,
+		"D3": excelize.CellTypeBool,
+		"I3": excelize.CellTypeInlineString,
+		"C4": excelize.CellTypeInlineString,
+		"E5": excelize.CellTypeInlineString,
+		"A6": excelize.CellTypeInlineString,
+		"B6": excelize.CellTypeUnset,
+	} {
+		got, err := f.GetCellType(xlsxResultsSheet, cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("GetCellType(%s) = %v, want %v", cell, got, want)
+		}
+	}
+}
+
+func TestXLSXStats(t *testing.T) {
+	t.Parallel()
+
+	rs := singersFixture()
+	rs.Stats.RowCount = &sppb.ResultSetStats_RowCountExact{RowCountExact: 2}
+	rs.Stats.QueryPlan = &sppb.QueryPlan{PlanNodes: []*sppb.PlanNode{
+		{
+			Index: 0, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Serialize Result",
+			ExecutionStats: &structpb.Struct{Fields: map[string]*structpb.Value{
+				"rows":    structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{"total": structpb.NewStringValue("2"), "unit": structpb.NewStringValue("rows")}}),
+				"latency": structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{"total": structpb.NewStringValue("0.5"), "unit": structpb.NewStringValue("msecs")}}),
+			}},
+		},
+		{Index: 1, Kind: sppb.PlanNode_SCALAR, DisplayName: "Reference"},
+	}}
+	var buf bytes.Buffer
+	if err := WriteResultSet(NewXLSX(&buf, true), rs); err != nil {
+		t.Fatalf("WriteResultSet() error = %v", err)
+	}
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := f.GetRows(xlsxStatsSheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Stat", "Value"},
+		{"elapsed_time", "1.23 msecs"},
+		{"row_count_exact", "2"},
+		nil,
+		{"Index", "Kind", "Display name", "Rows", "Latency"},
+		{"0", "RELATIONAL", "Serialize Result", "2", "0.5 msecs"},
+		{"1", "SCALAR", "Reference"},
+	}
+	if diff := cmp.Diff(want, rows); diff != "" {
+		t.Errorf("GetRows(%s) mismatch (-want +got):\n%s", xlsxStatsSheet, diff)
+	}
+}
+
+func TestXLSXFailure(t *testing.T) {
+	t.Parallel()
+
+	rs := columnarFixture()
+	x := NewXLSX(&bytes.Buffer{}, false)
+	if err := x.WriteMetadata(rs.GetMetadata()); err != nil {
+		t.Fatalf("WriteMetadata() error = %v", err)
+	}
+	if err := x.WriteRow(rs.GetRows()[0].GetValues()[:1]); err == nil {
+		t.Fatal("WriteRow() expected an error for a short row")
+	}
+	if x.file != nil {
+		t.Error("WriteRow() left the workbook open after an error")
+	}
+
+	// WriteRowIterator aborts the writer when the query fails.
+	x = NewXLSX(&bytes.Buffer{}, false)
+	if err := x.WriteMetadata(rs.GetMetadata()); err != nil {
+		t.Fatalf("WriteMetadata() error = %v", err)
+	}
+	abort(x)
+	if x.file != nil {
+		t.Error("abort() left the workbook open")
+	}
+}
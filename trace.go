@@ -7,13 +7,14 @@ import (
 	"time"
 
 	"github.com/apstndb/spannerotel/tracing"
+	"go.opentelemetry.io/otel"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 const traceServiceName = "execspansql"
 
 func tracingEnabled(o opts) bool {
-	return o.TraceStdout || o.TraceProject != "" || o.TraceOTLP
+	return o.TraceStdout || o.TraceProject != "" || o.TraceOTLP || o.TraceFile != ""
 }
 
 func traceConfig(o opts) (tracing.Config, error) {
@@ -27,8 +28,11 @@ func traceConfig(o opts) (tracing.Config, error) {
 	if o.TraceProject != "" {
 		n++
 	}
+	if o.TraceFile != "" {
+		n++
+	}
 	if n != 1 {
-		return tracing.Config{}, fmt.Errorf("exactly one of --experimental-trace-otlp, --experimental-trace-stdout, or --experimental-trace-project must be set")
+		return tracing.Config{}, fmt.Errorf("exactly one of --experimental-trace-otlp, --experimental-trace-stdout, --experimental-trace-project, or --experimental-trace-file must be set")
 	}
 	switch {
 	case o.TraceOTLP:
@@ -51,6 +55,9 @@ func traceConfig(o opts) (tracing.Config, error) {
 			ServiceName:       traceServiceName,
 			CloudTraceProject: o.TraceProject,
 		}, nil
+	case o.TraceFile != "":
+		// Spans are written by chromeTraceExporter instead of a spannerotel exporter.
+		return tracing.Config{ServiceName: traceServiceName}, nil
 	default:
 		return tracing.Config{}, fmt.Errorf("tracing is not configured")
 	}
@@ -64,8 +71,12 @@ func enableTracing(ctx context.Context, o opts) (context.Context, *sdktrace.Trac
 	if err != nil {
 		return ctx, nil, nil, err
 	}
-	tp, err := tracing.NewTracerProvider(cfg)
-	if err != nil {
+	var tp *sdktrace.TracerProvider
+	if o.TraceFile != "" {
+		// The Spanner client and the interceptor trace with the global provider.
+		tp = sdktrace.NewTracerProvider(sdktrace.WithSyncer(&chromeTraceExporter{name: o.TraceFile}))
+		otel.SetTracerProvider(tp)
+	} else if tp, err = tracing.NewTracerProvider(cfg); err != nil {
 		return ctx, nil, nil, err
 	}
 
@@ -106,6 +106,9 @@ func TestTracingEnabled(t *testing.T) {
 	if !tracingEnabled(opts{TraceOTLP: true}) {
 		t.Fatal("otlp should enable tracing")
 	}
+	if !tracingEnabled(opts{TraceFile: "trace.json"}) {
+		t.Fatal("file should enable tracing")
+	}
 	if tracingEnabled(opts{}) {
 		t.Fatal("expected tracing disabled by default")
 	}
@@ -0,0 +1,7 @@
+// Package valuefmt renders Cloud Spanner values and types as display text.
+//
+// Values are taken in their wire representation (a [sppb.Type] paired with a
+// [structpb.Value]), so the same formatting applies to rows read from a
+// [cloud.google.com/go/spanner.RowIterator] and rows of a materialized
+// ResultSet.
+package valuefmt
@@ -0,0 +1,127 @@
+package valuefmt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Config controls how values are rendered as text.
+type Config struct {
+	// Null is rendered for NULL values at any nesting level.
+	Null string
+}
+
+// Simple matches the value rendering of the experimental_csv format:
+// NULL as <null>, ARRAY as [a, b], STRUCT as (v AS name) and BYTES as raw text.
+var Simple = Config{Null: "<null>"}
+
+// Format renders v of type typ as text.
+func (c Config) Format(typ *sppb.Type, v *structpb.Value) (string, error) {
+	if v == nil {
+		return "", fmt.Errorf("nil value for type %s", TypeString(typ))
+	}
+	if _, ok := v.GetKind().(*structpb.Value_NullValue); ok {
+		return c.Null, nil
+	}
+
+	switch typ.GetCode() {
+	case sppb.TypeCode_BOOL:
+		b, ok := v.GetKind().(*structpb.Value_BoolValue)
+		if !ok {
+			return "", kindError(typ, v)
+		}
+		return strconv.FormatBool(b.BoolValue), nil
+	case sppb.TypeCode_FLOAT64, sppb.TypeCode_FLOAT32:
+		switch k := v.GetKind().(type) {
+		case *structpb.Value_NumberValue:
+			bitSize := 64
+			if typ.GetCode() == sppb.TypeCode_FLOAT32 {
+				bitSize = 32
+			}
+			return strconv.FormatFloat(k.NumberValue, 'g', -1, bitSize), nil
+		case *structpb.Value_StringValue:
+			// NaN and infinities are encoded as strings.
+			return k.StringValue, nil
+		default:
+			return "", kindError(typ, v)
+		}
+	case sppb.TypeCode_BYTES, sppb.TypeCode_PROTO:
+		s, ok := v.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return "", kindError(typ, v)
+		}
+		b, err := base64.StdEncoding.DecodeString(s.StringValue)
+		if err != nil {
+			return "", fmt.Errorf("decode %s: %w", TypeString(typ), err)
+		}
+		return string(b), nil
+	case sppb.TypeCode_ARRAY:
+		l, ok := v.GetKind().(*structpb.Value_ListValue)
+		if !ok {
+			return "", kindError(typ, v)
+		}
+		elems := make([]string, len(l.ListValue.GetValues()))
+		for i, e := range l.ListValue.GetValues() {
+			s, err := c.Format(typ.GetArrayElementType(), e)
+			if err != nil {
+				return "", err
+			}
+			elems[i] = s
+		}
+		return "[" + strings.Join(elems, ", ") + "]", nil
+	case sppb.TypeCode_STRUCT:
+		l, ok := v.GetKind().(*structpb.Value_ListValue)
+		if !ok {
+			return "", kindError(typ, v)
+		}
+		fields := typ.GetStructType().GetFields()
+		values := l.ListValue.GetValues()
+		if len(fields) != len(values) {
+			return "", fmt.Errorf("%s has %d fields but value has %d", TypeString(typ), len(fields), len(values))
+		}
+		parts := make([]string, len(values))
+		for i, e := range values {
+			s, err := c.Format(fields[i].GetType(), e)
+			if err != nil {
+				return "", err
+			}
+			if name := fields[i].GetName(); name != "" {
+				s += " AS " + name
+			}
+			parts[i] = s
+		}
+		return "(" + strings.Join(parts, ", ") + ")", nil
+	default:
+		// INT64, NUMERIC, STRING, JSON, DATE, TIMESTAMP, UUID, INTERVAL and ENUM are strings on the wire.
+		s, ok := v.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return "", kindError(typ, v)
+		}
+		return s.StringValue, nil
+	}
+}
+
+// FormatRow renders each value of a row against the fields of its row type.
+func (c Config) FormatRow(fields []*sppb.StructType_Field, values []*structpb.Value) ([]string, error) {
+	if len(fields) != len(values) {
+		return nil, fmt.Errorf("row has %d values but row type has %d fields", len(values), len(fields))
+	}
+	out := make([]string, len(values))
+	for i, v := range values {
+		s, err := c.Format(fields[i].GetType(), v)
+		if err != nil {
+			return nil, fmt.Errorf("column %d (%s): %w", i, fields[i].GetName(), err)
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+func kindError(typ *sppb.Type, v *structpb.Value) error {
+	return fmt.Errorf("unexpected %T for %s", v.GetKind(), TypeString(typ))
+}
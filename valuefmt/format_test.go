@@ -0,0 +1,92 @@
+package valuefmt
+
+import (
+	"math"
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestSimpleFormat(t *testing.T) {
+	t.Parallel()
+
+	structType := &sppb.Type{
+		Code: sppb.TypeCode_STRUCT,
+		StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+			{Name: "i", Type: &sppb.Type{Code: sppb.TypeCode_INT64}},
+			{Type: &sppb.Type{Code: sppb.TypeCode_STRING}},
+		}},
+	}
+	tests := []struct {
+		name  string
+		typ   *sppb.Type
+		value *structpb.Value
+		want  string
+	}{
+		{"null", &sppb.Type{Code: sppb.TypeCode_STRING}, structpb.NewNullValue(), "<null>"},
+		{"bool", &sppb.Type{Code: sppb.TypeCode_BOOL}, structpb.NewBoolValue(true), "true"},
+		{"int64", &sppb.Type{Code: sppb.TypeCode_INT64}, structpb.NewStringValue("42"), "42"},
+		{"float64", &sppb.Type{Code: sppb.TypeCode_FLOAT64}, structpb.NewNumberValue(3.5), "3.5"},
+		{"float32", &sppb.Type{Code: sppb.TypeCode_FLOAT32}, structpb.NewNumberValue(float64(float32(0.1))), "0.1"},
+		{"float64_nan", &sppb.Type{Code: sppb.TypeCode_FLOAT64}, structpb.NewStringValue("NaN"), "NaN"},
+		{"float64_large", &sppb.Type{Code: sppb.TypeCode_FLOAT64}, structpb.NewNumberValue(math.MaxFloat64), "1.7976931348623157e+308"},
+		{"bytes", &sppb.Type{Code: sppb.TypeCode_BYTES}, structpb.NewStringValue("YWJj"), "abc"},
+		{"array", &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: sppb.TypeCode_STRING}},
+			structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{
+				structpb.NewStringValue("foo"), structpb.NewNullValue(),
+			}}), "[foo, <null>]"},
+		{"struct", structType,
+			structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{
+				structpb.NewStringValue("7"), structpb.NewStringValue("x"),
+			}}), "(7 AS i, x)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Simple.Format(tc.typ, tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("Format() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Simple.Format(&sppb.Type{Code: sppb.TypeCode_BOOL}, structpb.NewStringValue("true")); err == nil {
+		t.Fatal("expected error for mismatched kind")
+	}
+	if _, err := Simple.Format(&sppb.Type{Code: sppb.TypeCode_BYTES}, structpb.NewStringValue("!")); err == nil {
+		t.Fatal("expected error for invalid base64")
+	}
+	if _, err := Simple.FormatRow(nil, []*structpb.Value{structpb.NewNullValue()}); err == nil {
+		t.Fatal("expected error for field count mismatch")
+	}
+}
+
+func TestTypeString(t *testing.T) {
+	t.Parallel()
+
+	typ := &sppb.Type{
+		Code: sppb.TypeCode_ARRAY,
+		ArrayElementType: &sppb.Type{
+			Code: sppb.TypeCode_STRUCT,
+			StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+				{Name: "id", Type: &sppb.Type{Code: sppb.TypeCode_INT64}},
+				{Type: &sppb.Type{Code: sppb.TypeCode_STRING}},
+			}},
+		},
+	}
+	if got, want := TypeString(typ), "ARRAY<STRUCT<id INT64, STRING>>"; got != want {
+		t.Fatalf("TypeString() = %q, want %q", got, want)
+	}
+	enum := &sppb.Type{Code: sppb.TypeCode_ENUM, ProtoTypeFqn: "examples.Genre"}
+	if got, want := TypeString(enum), "examples.Genre"; got != want {
+		t.Fatalf("TypeString() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+package valuefmt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/cloudspannerecosystem/memefish/token"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Literal renders v as a GoogleSQL literal expression of type typ, e.g.
+// NUMERIC "1.5", b"\x00", ARRAY<INT64>[1, NULL] or STRUCT<a INT64>(1).
+// A top-level NULL is CAST(NULL AS T) so that the type survives on its own,
+// as in a --param value; NULLs inside ARRAY and STRUCT literals are bare.
+func Literal(typ *sppb.Type, v *structpb.Value) (string, error) {
+	if _, ok := v.GetKind().(*structpb.Value_NullValue); ok {
+		return "CAST(NULL AS " + LiteralTypeString(typ) + ")", nil
+	}
+	return literal(typ, v)
+}
+
+// LiteralTypeString is [TypeString] with STRUCT field names and PROTO/ENUM
+// names quoted where GoogleSQL requires it, for use inside SQL text.
+func LiteralTypeString(typ *sppb.Type) string {
+	switch typ.GetCode() {
+	case sppb.TypeCode_ARRAY:
+		return "ARRAY<" + LiteralTypeString(typ.GetArrayElementType()) + ">"
+	case sppb.TypeCode_STRUCT:
+		fields := typ.GetStructType().GetFields()
+		parts := make([]string, len(fields))
+		for i, f := range fields {
+			if f.GetName() == "" {
+				parts[i] = LiteralTypeString(f.GetType())
+				continue
+			}
+			parts[i] = token.QuoteSQLIdent(f.GetName()) + " " + LiteralTypeString(f.GetType())
+		}
+		return "STRUCT<" + strings.Join(parts, ", ") + ">"
+	case sppb.TypeCode_PROTO, sppb.TypeCode_ENUM:
+		if fqn := typ.GetProtoTypeFqn(); fqn != "" {
+			return token.QuoteSQLIdent(fqn)
+		}
+		return typ.GetCode().String()
+	default:
+		return typ.GetCode().String()
+	}
+}
+
+func literal(typ *sppb.Type, v *structpb.Value) (string, error) {
+	if v == nil {
+		return "", fmt.Errorf("nil value for type %s", TypeString(typ))
+	}
+	if _, ok := v.GetKind().(*structpb.Value_NullValue); ok {
+		return "NULL", nil
+	}
+
+	switch typ.GetCode() {
+	case sppb.TypeCode_BOOL:
+		b, ok := v.GetKind().(*structpb.Value_BoolValue)
+		if !ok {
+			return "", kindError(typ, v)
+		}
+		return strings.ToUpper(strconv.FormatBool(b.BoolValue)), nil
+	case sppb.TypeCode_FLOAT64, sppb.TypeCode_FLOAT32:
+		s, err := floatLiteral(typ, v)
+		if err != nil {
+			return "", err
+		}
+		if typ.GetCode() == sppb.TypeCode_FLOAT32 {
+			return "CAST(" + s + " AS FLOAT32)", nil
+		}
+		return s, nil
+	case sppb.TypeCode_ARRAY:
+		l, ok := v.GetKind().(*structpb.Value_ListValue)
+		if !ok {
+			return "", kindError(typ, v)
+		}
+		elems := make([]string, len(l.ListValue.GetValues()))
+		for i, e := range l.ListValue.GetValues() {
+			s, err := literal(typ.GetArrayElementType(), e)
+			if err != nil {
+				return "", err
+			}
+			elems[i] = s
+		}
+		return LiteralTypeString(typ) + "[" + strings.Join(elems, ", ") + "]", nil
+	case sppb.TypeCode_STRUCT:
+		l, ok := v.GetKind().(*structpb.Value_ListValue)
+		if !ok {
+			return "", kindError(typ, v)
+		}
+		fields := typ.GetStructType().GetFields()
+		values := l.ListValue.GetValues()
+		if len(fields) != len(values) {
+			return "", fmt.Errorf("%s has %d fields but value has %d", TypeString(typ), len(fields), len(values))
+		}
+		parts := make([]string, len(values))
+		for i, e := range values {
+			s, err := literal(fields[i].GetType(), e)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return LiteralTypeString(typ) + "(" + strings.Join(parts, ", ") + ")", nil
+	}
+
+	s, ok := v.GetKind().(*structpb.Value_StringValue)
+	if !ok {
+		return "", kindError(typ, v)
+	}
+	switch typ.GetCode() {
+	case sppb.TypeCode_INT64:
+		return s.StringValue, nil
+	case sppb.TypeCode_STRING:
+		return token.QuoteSQLString(s.StringValue), nil
+	case sppb.TypeCode_BYTES, sppb.TypeCode_PROTO:
+		b, err := base64.StdEncoding.DecodeString(s.StringValue)
+		if err != nil {
+			return "", fmt.Errorf("decode %s: %w", TypeString(typ), err)
+		}
+		if typ.GetCode() == sppb.TypeCode_PROTO {
+			return "CAST(" + token.QuoteSQLBytes(b) + " AS " + LiteralTypeString(typ) + ")", nil
+		}
+		return token.QuoteSQLBytes(b), nil
+	case sppb.TypeCode_NUMERIC, sppb.TypeCode_JSON, sppb.TypeCode_DATE, sppb.TypeCode_TIMESTAMP:
+		return typ.GetCode().String() + " " + token.QuoteSQLString(s.StringValue), nil
+	case sppb.TypeCode_ENUM:
+		return "CAST(" + s.StringValue + " AS " + LiteralTypeString(typ) + ")", nil
+	default:
+		// UUID and INTERVAL have no literal prefix; both cast from their canonical string form.
+		return "CAST(" + token.QuoteSQLString(s.StringValue) + " AS " + LiteralTypeString(typ) + ")", nil
+	}
+}
+
+// floatLiteral renders a float so that it reads back as FLOAT64 rather than INT64.
+func floatLiteral(typ *sppb.Type, v *structpb.Value) (string, error) {
+	switch k := v.GetKind().(type) {
+	case *structpb.Value_NumberValue:
+		f := k.NumberValue
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nonFiniteLiteral(strconv.FormatFloat(f, 'g', -1, 64)), nil
+		}
+		bitSize := 64
+		if typ.GetCode() == sppb.TypeCode_FLOAT32 {
+			bitSize = 32
+		}
+		s := strconv.FormatFloat(f, 'g', -1, bitSize)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s, nil
+	case *structpb.Value_StringValue:
+		// NaN and infinities are encoded as strings.
+		return nonFiniteLiteral(k.StringValue), nil
+	default:
+		return "", kindError(typ, v)
+	}
+}
+
+func nonFiniteLiteral(s string) string {
+	switch s {
+	case "NaN":
+		s = "nan"
+	case "Infinity", "+Inf":
+		s = "inf"
+	case "-Infinity", "-Inf":
+		s = "-inf"
+	}
+	return `CAST("` + s + `" AS FLOAT64)`
+}
@@ -0,0 +1,82 @@
+package valuefmt
+
+import (
+	"testing"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestLiteral(t *testing.T) {
+	t.Parallel()
+
+	typ := func(code sppb.TypeCode) *sppb.Type { return &sppb.Type{Code: code} }
+	list := func(values ...*structpb.Value) *structpb.Value {
+		return structpb.NewListValue(&structpb.ListValue{Values: values})
+	}
+	structType := &sppb.Type{
+		Code: sppb.TypeCode_STRUCT,
+		StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+			{Name: "i", Type: typ(sppb.TypeCode_INT64)},
+			{Name: "select", Type: typ(sppb.TypeCode_STRING)},
+			{Type: typ(sppb.TypeCode_BOOL)},
+		}},
+	}
+	tests := []struct {
+		name  string
+		typ   *sppb.Type
+		value *structpb.Value
+		want  string
+	}{
+		{"null", typ(sppb.TypeCode_INT64), structpb.NewNullValue(), "CAST(NULL AS INT64)"},
+		{"bool", typ(sppb.TypeCode_BOOL), structpb.NewBoolValue(true), "TRUE"},
+		{"int64", typ(sppb.TypeCode_INT64), structpb.NewStringValue("-42"), "-42"},
+		{"float64", typ(sppb.TypeCode_FLOAT64), structpb.NewNumberValue(3.5), "3.5"},
+		{"float64_integral", typ(sppb.TypeCode_FLOAT64), structpb.NewNumberValue(1), "1.0"},
+		{"float64_exponent", typ(sppb.TypeCode_FLOAT64), structpb.NewNumberValue(1e21), "1e+21"},
+		{"float64_nan", typ(sppb.TypeCode_FLOAT64), structpb.NewStringValue("NaN"), `CAST("nan" AS FLOAT64)`},
+		{"float64_neg_inf", typ(sppb.TypeCode_FLOAT64), structpb.NewStringValue("-Infinity"), `CAST("-inf" AS FLOAT64)`},
+		{"float32", typ(sppb.TypeCode_FLOAT32), structpb.NewNumberValue(1), "CAST(1.0 AS FLOAT32)"},
+		{"string", typ(sppb.TypeCode_STRING), structpb.NewStringValue("it's \"x\"\n"), `"it's \"x\"\n"`},
+		{"bytes", typ(sppb.TypeCode_BYTES), structpb.NewStringValue("AGFi"), `b"\x00ab"`},
+		{"numeric", typ(sppb.TypeCode_NUMERIC), structpb.NewStringValue("99.5"), `NUMERIC "99.5"`},
+		{"json", typ(sppb.TypeCode_JSON), structpb.NewStringValue(`{"k":1}`), `JSON '{"k":1}'`},
+		{"date", typ(sppb.TypeCode_DATE), structpb.NewStringValue("2024-06-01"), `DATE "2024-06-01"`},
+		{"timestamp", typ(sppb.TypeCode_TIMESTAMP), structpb.NewStringValue("2024-06-01T12:34:56Z"), `TIMESTAMP "2024-06-01T12:34:56Z"`},
+		{"uuid", typ(sppb.TypeCode_UUID), structpb.NewStringValue("550e8400-e29b-41d4-a716-446655440000"), `CAST("550e8400-e29b-41d4-a716-446655440000" AS UUID)`},
+		{"interval", typ(sppb.TypeCode_INTERVAL), structpb.NewStringValue("P1Y2M"), `CAST("P1Y2M" AS INTERVAL)`},
+		{"enum", &sppb.Type{Code: sppb.TypeCode_ENUM, ProtoTypeFqn: "examples.Genre"}, structpb.NewStringValue("1"), "CAST(1 AS `examples.Genre`)"},
+		{"proto", &sppb.Type{Code: sppb.TypeCode_PROTO, ProtoTypeFqn: "examples.Book"}, structpb.NewStringValue("CAE="), "CAST(b\"\\x08\\x01\" AS `examples.Book`)"},
+		{"array", &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: typ(sppb.TypeCode_INT64)},
+			list(structpb.NewStringValue("1"), structpb.NewNullValue()), "ARRAY<INT64>[1, NULL]"},
+		{"array_empty", &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: typ(sppb.TypeCode_STRING)},
+			list(), "ARRAY<STRING>[]"},
+		{"struct", structType,
+			list(structpb.NewStringValue("7"), structpb.NewStringValue("x"), structpb.NewNullValue()),
+			"STRUCT<i INT64, `select` STRING, BOOL>(7, \"x\", NULL)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Literal(tc.typ, tc.value)
+			if err != nil {
+				t.Fatalf("Literal() error = %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("Literal() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLiteralErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Literal(&sppb.Type{Code: sppb.TypeCode_INT64}, structpb.NewBoolValue(true)); err == nil {
+		t.Error("expected error for INT64 encoded as bool")
+	}
+	if _, err := Literal(&sppb.Type{Code: sppb.TypeCode_BYTES}, structpb.NewStringValue("!")); err == nil {
+		t.Error("expected error for invalid base64")
+	}
+}
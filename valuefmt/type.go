@@ -0,0 +1,33 @@
+package valuefmt
+
+import (
+	"strings"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+// TypeString renders typ in GoogleSQL type syntax, e.g. ARRAY<STRUCT<id INT64, name STRING>>.
+func TypeString(typ *sppb.Type) string {
+	switch typ.GetCode() {
+	case sppb.TypeCode_ARRAY:
+		return "ARRAY<" + TypeString(typ.GetArrayElementType()) + ">"
+	case sppb.TypeCode_STRUCT:
+		fields := typ.GetStructType().GetFields()
+		parts := make([]string, len(fields))
+		for i, f := range fields {
+			if f.GetName() == "" {
+				parts[i] = TypeString(f.GetType())
+				continue
+			}
+			parts[i] = f.GetName() + " " + TypeString(f.GetType())
+		}
+		return "STRUCT<" + strings.Join(parts, ", ") + ">"
+	case sppb.TypeCode_PROTO, sppb.TypeCode_ENUM:
+		if fqn := typ.GetProtoTypeFqn(); fqn != "" {
+			return fqn
+		}
+		return typ.GetCode().String()
+	default:
+		return typ.GetCode().String()
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
 	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/apstndb/execspansql/jqresult"
 )
 
 // yamlGoldenCases maps fixture name to jq filter and ResultSet input.
@@ -35,6 +37,20 @@ func yamlGoldenCases() map[string]yamlGoldenCase {
 
 	allTypes := csvFixtures()["bytes_date_timestamp_numeric"]
 	multiScalar := csvFixtures()["null_bool_float64"]
+	rendered := resultSet(
+		[]string{"f", "j", "i"},
+		[]*sppb.Type{
+			{Code: sppb.TypeCode_FLOAT64},
+			{Code: sppb.TypeCode_JSON},
+			{Code: sppb.TypeCode_INTERVAL},
+		},
+		[][]*structpb.Value{
+			{
+				structpb.NewNumberValue(1.5), structpb.NewStringValue(`{"n":2.25}`),
+				structpb.NewStringValue("P1Y2M3DT4H"),
+			},
+		},
+	)
 
 	return map[string]yamlGoldenCase{
 		"dca_albums_rowtype_rows": {
@@ -49,10 +65,16 @@ func yamlGoldenCases() map[string]yamlGoldenCase {
 			filter: `.rows`,
 			rs:     multiScalar,
 		},
+		"render_float_json_interval_rows": {
+			filter: `.rows`,
+			rs:     rendered,
+			render: jqresult.RenderOptions{ExpandJSON: true, Interval: jqresult.IntervalObject},
+		},
 	}
 }
 
 type yamlGoldenCase struct {
 	filter string
 	rs     *sppb.ResultSet
+	render jqresult.RenderOptions
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -11,19 +12,19 @@ import (
 	"github.com/apstndb/execspansql/jqresult"
 )
 
-func encodeResultSetYAML(filter string, rs *sppb.ResultSet) ([]byte, error) {
+func encodeResultSetYAML(filter string, rs *sppb.ResultSet, render jqresult.RenderOptions) ([]byte, error) {
 	code, err := jqresult.Compile(filter, jqresult.InputEager)
 	if err != nil {
 		return nil, err
 	}
-	iter, cleanup, err := jqresult.Execute(code, jqresult.InputEager, nil, rs, false)
+	iter, cleanup, err := jqresult.Execute(code, jqresult.InputEager, nil, rs, false, jqresult.WithRender(render))
 	if err != nil {
 		return nil, err
 	}
 	defer cleanup()
 
 	var buf bytes.Buffer
-	enc, err := newEncoder(&buf, "yaml", false, false)
+	enc, err := newEncoder(&buf, "yaml", false, false, render.RendersRows(jqresult.RowShapeArray))
 	if err != nil {
 		return nil, err
 	}
@@ -44,7 +45,7 @@ func TestYamlOutputGolden(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			goldenPath := filepath.Join("testdata", "yaml_output", name+".golden")
 
-			got, err := encodeResultSetYAML(tc.filter, tc.rs)
+			got, err := encodeResultSetYAML(tc.filter, tc.rs, tc.render)
 			if err != nil {
 				t.Fatalf("encodeResultSetYAML() error = %v", err)
 			}
@@ -74,7 +75,24 @@ func TestYamlOutputGolden(t *testing.T) {
 func TestNewEncoderUnknownFormat(t *testing.T) {
 	t.Parallel()
 
-	if _, err := newEncoder(&bytes.Buffer{}, "nope", false, false); err == nil {
+	if _, err := newEncoder(&bytes.Buffer{}, "nope", false, false, false); err == nil {
 		t.Fatal("expected error for unknown format")
 	}
 }
+
+func TestNewEncoderYAMLPlainNumbers(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	enc, err := newEncoder(&buf, "yaml", false, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode([]any{json.Number("9223372036854775807"), json.Number("12345678901234567890.123456789")}); err != nil {
+		t.Fatal(err)
+	}
+	want := "- 9223372036854775807\n- 12345678901234567890.123456789\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("Encode() = %q, want %q", got, want)
+	}
+}